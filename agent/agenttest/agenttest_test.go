@@ -0,0 +1,210 @@
+package agenttest_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sohaha/zlsgo"
+	"github.com/sohaha/zlsgo/zjson"
+	"github.com/zlsgo/zllm/agent"
+	"github.com/zlsgo/zllm/agent/agenttest"
+	"github.com/zlsgo/zllm/message"
+)
+
+func collect(tt *zlsgo.TestUtil, llm agent.LLM, prompt string) (string, []string) {
+	var (
+		mu     sync.Mutex
+		chunks []string
+	)
+	done, err := llm.Stream(context.Background(), []byte(prompt), func(s string, _ []byte) {
+		mu.Lock()
+		chunks = append(chunks, s)
+		mu.Unlock()
+	})
+	tt.NoError(err, true)
+
+	select {
+	case res := <-done:
+		tt.EqualTrue(res != nil)
+		resp, err := llm.ParseResponse(res)
+		tt.NoError(err, true)
+		return string(resp.Content), chunks
+	case <-time.After(5 * time.Second):
+		tt.Fatal("stream timeout")
+	}
+	return "", nil
+}
+
+func TestMockLLM(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	boom := errors.New("boom")
+	llm := agenttest.NewMock(
+		agenttest.Text("hello"),
+		agenttest.ToolCall("search", `{"q":"go"}`),
+		agenttest.Error(boom),
+		agenttest.Chunks(time.Millisecond, "a", "b", "c"),
+	)
+
+	msg := message.NewMessages()
+	_ = msg.AppendUser("hi")
+	body, err := llm.PrepareRequest(msg)
+	tt.NoError(err, true)
+
+	res, err := llm.Generate(context.Background(), body)
+	tt.NoError(err, true)
+	resp, err := llm.ParseResponse(res)
+	tt.NoError(err, true)
+	tt.Equal("hello", string(resp.Content))
+	tt.Equal([][]string{{message.RoleUser, "hi"}}, llm.LastMessages())
+
+	res, err = llm.Generate(context.Background(), body)
+	tt.NoError(err, true)
+	resp, _ = llm.ParseResponse(res)
+	tt.Equal([]agent.Tool{{ID: "call_0", Name: "search", Args: `{"q":"go"}`}}, resp.Tools)
+
+	_, err = llm.Generate(context.Background(), body)
+	tt.EqualTrue(errors.Is(err, boom))
+
+	content, chunks := collect(tt, llm, "stream")
+	tt.Equal("abc", content)
+	tt.Equal([]string{"a", "b", "c"}, chunks)
+
+	_, err = llm.Generate(context.Background(), body)
+	tt.EqualTrue(errors.Is(err, agenttest.ErrNoReply))
+	tt.Equal(5, llm.Calls())
+
+	llm.Push(agenttest.Text("slow").WithDelay(time.Second)).Repeat()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = llm.Generate(ctx, body)
+	tt.EqualTrue(errors.Is(err, context.DeadlineExceeded))
+
+	done, err := llm.Stream(ctx, body, func(string, []byte) {})
+	tt.NoError(err, true)
+	tt.EqualTrue(<-done == nil)
+	tt.EqualTrue(errors.Is(ctx.Err(), context.DeadlineExceeded))
+}
+
+func TestServer(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	tt.Run("OpenAI", func(tt *zlsgo.TestUtil) {
+		srv := agenttest.NewServer(agenttest.OpenAI,
+			agenttest.Text("hello"),
+			agenttest.ToolCall("search", `{"q":"go"}`),
+			agenttest.Chunks(time.Millisecond, "Hel", "lo"),
+			agenttest.HTTPError(401, "bad key"),
+		)
+		defer srv.Close()
+
+		llm := agent.NewOpenAI(func(o *agent.OpenAIOptions) {
+			o.BaseURL = srv.URL
+			o.APIURL = "/chat/completions"
+			o.APIKey = "sk-test"
+			o.Model = "gpt-test"
+		})
+
+		res, err := llm.Generate(context.Background(), []byte("hi"))
+		tt.NoError(err, true)
+		resp, err := llm.ParseResponse(res)
+		tt.NoError(err, true)
+		tt.Equal("hello", string(resp.Content))
+
+		res, err = llm.Generate(context.Background(), []byte("hi"))
+		tt.NoError(err, true)
+		resp, err = llm.ParseResponse(res)
+		tt.NoError(err, true)
+		tt.Equal("search", resp.Tools[0].Name)
+
+		content, chunks := collect(tt, llm, "hi")
+		tt.Equal("Hello", content)
+		tt.Equal([]string{"Hel", "lo"}, chunks)
+
+		_, err = llm.Generate(context.Background(), []byte("hi"))
+		tt.EqualTrue(err != nil)
+
+		reqs := srv.Requests()
+		tt.Equal(4, len(reqs))
+		tt.Equal("/chat/completions", reqs[0].Path)
+		tt.Equal("Bearer sk-test", reqs[0].Header.Get("Authorization"))
+		tt.Equal("gpt-test", zjson.GetBytes(reqs[0].Body, "model").String())
+		tt.EqualTrue(reqs[2].Stream())
+	})
+
+	tt.Run("Anthropic", func(tt *zlsgo.TestUtil) {
+		srv := agenttest.NewServer(agenttest.Anthropic,
+			agenttest.Text("bonjour"),
+			agenttest.Chunks(0, "bon", "jour"),
+		)
+		defer srv.Close()
+
+		llm := agent.NewAnthropic(func(o *agent.AnthropicOptions) {
+			o.BaseURL = srv.URL
+			o.APIKey = "ak-test"
+		})
+
+		res, err := llm.Generate(context.Background(), []byte("hi"))
+		tt.NoError(err, true)
+		resp, err := llm.ParseResponse(res)
+		tt.NoError(err, true)
+		tt.Equal("bonjour", string(resp.Content))
+
+		content, chunks := collect(tt, llm, "hi")
+		tt.Equal("bonjour", content)
+		tt.Equal([]string{"bon", "jour"}, chunks)
+		tt.Equal("ak-test", srv.Requests()[0].Header.Get("x-api-key"))
+	})
+
+	tt.Run("Gemini", func(tt *zlsgo.TestUtil) {
+		srv := agenttest.NewServer(agenttest.Gemini,
+			agenttest.Text("hola"),
+			agenttest.Chunks(0, "ho", "la"),
+		)
+		defer srv.Close()
+
+		llm := agent.NewGemini(func(o *agent.GeminiOptions) {
+			o.BaseURL = srv.URL
+			o.APIKey = "gk-test"
+		})
+
+		res, err := llm.Generate(context.Background(), []byte("hi"))
+		tt.NoError(err, true)
+		resp, err := llm.ParseResponse(res)
+		tt.NoError(err, true)
+		tt.Equal("hola", string(resp.Content))
+
+		content, chunks := collect(tt, llm, "hi")
+		tt.Equal("hola", content)
+		tt.Equal([]string{"ho", "la"}, chunks)
+		tt.EqualTrue(strings.HasSuffix(srv.Requests()[0].Path, ":generateContent"))
+		tt.Equal("gk-test", srv.Requests()[0].Header.Get("x-goog-api-key"))
+	})
+
+	tt.Run("Ollama", func(tt *zlsgo.TestUtil) {
+		srv := agenttest.NewServer(agenttest.Ollama, agenttest.Text("hallo"))
+		defer srv.Close()
+
+		llm := agent.NewOllama(func(o *agent.OllamaOptions) {
+			o.BaseURL = srv.URL
+		})
+
+		res, err := llm.Generate(context.Background(), []byte("hi"))
+		tt.NoError(err, true)
+		tt.Equal("hallo", res.Get("message.content").String())
+		tt.EqualTrue(res.Get("done").Bool())
+		tt.Equal("/api/chat", srv.Requests()[0].Path)
+		resp, err := llm.ParseResponse(res)
+		tt.NoError(err, true)
+		tt.Equal("hallo", string(resp.Content))
+
+		srv.Push(agenttest.Chunks(0, "hal", "lo"))
+		content, chunks := collect(tt, llm, "hi")
+		tt.Equal("hallo", content)
+		tt.Equal([]string{"hal", "lo"}, chunks)
+	})
+}
@@ -0,0 +1,330 @@
+package agenttest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/sohaha/zlsgo/zjson"
+)
+
+// batchStore 模拟服务中的批处理任务与文件
+type batchStore struct {
+	files   map[string][]byte
+	batches map[string]*mockBatch
+	mu      sync.Mutex
+	seq     int
+}
+
+// mockBatch 模拟的批处理任务，每次查询推进一个状态，第二次查询时结束
+type mockBatch struct {
+	fields  map[string]any
+	id      string
+	results []byte
+	errors  []byte
+	total   int
+	failed  int
+	polls   int
+	cancel  bool
+}
+
+// anthropicErrorTypes HTTP 状态码对应的 Anthropic 错误类型
+var anthropicErrorTypes = map[int]string{
+	400: "invalid_request_error",
+	401: "authentication_error",
+	403: "permission_error",
+	404: "not_found_error",
+	429: "rate_limit_error",
+	529: "overloaded_error",
+}
+
+// handleBatch 处理批处理相关接口，非批处理请求返回 false
+func (s *Server) handleBatch(w http.ResponseWriter, r *http.Request, req Request) bool {
+	path := req.Path
+	switch {
+	case s.protocol == Anthropic && strings.Contains(path, "/messages/batches"):
+		id := strings.Trim(path[strings.Index(path, "/messages/batches")+len("/messages/batches"):], "/")
+		s.anthropicBatch(w, r, req, id)
+	case s.protocol == OpenAI && strings.HasSuffix(path, "/files") && req.Method == http.MethodPost:
+		s.uploadFile(w, r, req)
+	case s.protocol == OpenAI && strings.Contains(path, "/files/") && strings.HasSuffix(path, "/content"):
+		id := strings.TrimSuffix(path[strings.Index(path, "/files/")+len("/files/"):], "/content")
+		s.batch.mu.Lock()
+		data, ok := s.batch.files[id]
+		s.batch.mu.Unlock()
+		if !ok {
+			writeError(w, http.StatusNotFound, "file not found")
+			return true
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		_, _ = w.Write(data)
+	case s.protocol == OpenAI && strings.Contains(path, "/batches"):
+		id := strings.Trim(path[strings.Index(path, "/batches")+len("/batches"):], "/")
+		s.openAIBatch(w, req, id)
+	default:
+		return false
+	}
+	return true
+}
+
+// uploadFile 保存上传的 JSONL 文件
+func (s *Server) uploadFile(w http.ResponseWriter, r *http.Request, req Request) {
+	r.Body = io.NopCloser(bytes.NewReader(req.Body))
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	data, _ := io.ReadAll(file)
+
+	id := s.batch.save("file", data)
+	writeJSON(w, map[string]any{"id": id, "object": "file", "purpose": r.FormValue("purpose"), "bytes": len(data)})
+}
+
+// openAIBatch 处理 OpenAI Batch 接口
+func (s *Server) openAIBatch(w http.ResponseWriter, req Request, id string) {
+	if id == "" {
+		fileID := zjson.GetBytes(req.Body, "input_file_id").String()
+		s.batch.mu.Lock()
+		input, ok := s.batch.files[fileID]
+		s.batch.mu.Unlock()
+		if !ok {
+			writeError(w, http.StatusBadRequest, "input file not found")
+			return
+		}
+
+		b := &mockBatch{fields: map[string]any{"object": "batch", "endpoint": zjson.GetBytes(req.Body, "endpoint").String()}}
+		for _, line := range bytes.Split(input, []byte("\n")) {
+			if len(bytes.TrimSpace(line)) == 0 {
+				continue
+			}
+			item := zjson.ParseBytes(line)
+			customID := item.Get("custom_id").String()
+			b.total++
+			reply, ok := s.script.next()
+			if !ok {
+				reply = HTTPError(http.StatusInternalServerError, ErrNoReply.Error())
+			}
+			status, body := http.StatusOK, json.RawMessage(OpenAIResponse(reply, item.Get("body.model").String()))
+			if reply.Status >= 400 {
+				b.failed++
+				status = reply.Status
+				body = mustJSON(map[string]any{"error": map[string]any{"message": reply.Message, "code": reply.Status}})
+			}
+			out := mustJSON(map[string]any{
+				"id":        fmt.Sprintf("batch_req_%d", b.total),
+				"custom_id": customID,
+				"response":  map[string]any{"status_code": status, "request_id": fmt.Sprintf("req_%d", b.total), "body": body},
+				"error":     nil,
+			})
+			if status >= 400 {
+				b.errors = append(append(b.errors, out...), '\n')
+			} else {
+				b.results = append(append(b.results, out...), '\n')
+			}
+		}
+		b.id = s.batch.add(b)
+		writeJSON(w, s.openAIBatchObject(b, "validating"))
+		return
+	}
+
+	cancel := strings.HasSuffix(id, "/cancel")
+	b, ok := s.batch.get(strings.TrimSuffix(id, "/cancel"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "batch not found")
+		return
+	}
+
+	s.batch.mu.Lock()
+	defer s.batch.mu.Unlock()
+	if cancel {
+		b.cancel = true
+		writeJSON(w, s.openAIBatchObject(b, "cancelling"))
+		return
+	}
+
+	b.polls++
+	switch {
+	case b.cancel:
+		writeJSON(w, s.openAIBatchObject(b, "cancelled"))
+	case b.polls < 2:
+		writeJSON(w, s.openAIBatchObject(b, "in_progress"))
+	default:
+		if _, ok := b.fields["output_file_id"]; !ok {
+			if len(b.results) > 0 {
+				b.fields["output_file_id"] = s.batch.saveLocked("file", b.results)
+			}
+			if len(b.errors) > 0 {
+				b.fields["error_file_id"] = s.batch.saveLocked("file", b.errors)
+			}
+		}
+		writeJSON(w, s.openAIBatchObject(b, "completed"))
+	}
+}
+
+// openAIBatchObject 生成 OpenAI Batch 对象
+func (s *Server) openAIBatchObject(b *mockBatch, status string) map[string]any {
+	counts := map[string]any{"total": b.total, "completed": 0, "failed": 0}
+	if status == "completed" {
+		counts["completed"], counts["failed"] = b.total-b.failed, b.failed
+	}
+	obj := map[string]any{"id": b.id, "status": status, "request_counts": counts}
+	for k, v := range b.fields {
+		obj[k] = v
+	}
+	return obj
+}
+
+// anthropicBatch 处理 Anthropic Message Batches 接口
+func (s *Server) anthropicBatch(w http.ResponseWriter, r *http.Request, req Request, id string) {
+	if id == "" {
+		b := &mockBatch{}
+		zjson.ParseBytes(req.Body).Get("requests").ForEach(func(_, item *zjson.Res) bool {
+			b.total++
+			reply, ok := s.script.next()
+			if !ok {
+				reply = HTTPError(http.StatusInternalServerError, ErrNoReply.Error())
+			}
+			result := map[string]any{
+				"type":    "succeeded",
+				"message": json.RawMessage(AnthropicResponse(reply, item.Get("params.model").String())),
+			}
+			if reply.Status >= 400 {
+				b.failed++
+				errType, ok := anthropicErrorTypes[reply.Status]
+				if !ok {
+					errType = "api_error"
+				}
+				result = map[string]any{
+					"type":  "errored",
+					"error": map[string]any{"type": "error", "error": map[string]any{"type": errType, "message": reply.Message}},
+				}
+			}
+			b.results = append(append(b.results, mustJSON(map[string]any{
+				"custom_id": item.Get("custom_id").String(),
+				"result":    result,
+			})...), '\n')
+			return true
+		})
+		b.id = s.batch.add(b)
+		writeJSON(w, s.anthropicBatchObject(b, "in_progress", r))
+		return
+	}
+
+	action := ""
+	if i := strings.Index(id, "/"); i > 0 {
+		id, action = id[:i], id[i+1:]
+	}
+	b, ok := s.batch.get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "batch not found")
+		return
+	}
+
+	s.batch.mu.Lock()
+	defer s.batch.mu.Unlock()
+	switch action {
+	case "cancel":
+		b.cancel = true
+		writeJSON(w, s.anthropicBatchObject(b, "canceling", r))
+	case "results":
+		w.Header().Set("Content-Type", "application/binary")
+		if b.cancel {
+			for _, line := range bytes.Split(bytes.TrimSpace(b.results), []byte("\n")) {
+				_, _ = w.Write(mustJSON(map[string]any{
+					"custom_id": zjson.GetBytes(line, "custom_id").String(),
+					"result":    map[string]any{"type": "canceled"},
+				}))
+				_, _ = w.Write([]byte("\n"))
+			}
+			return
+		}
+		_, _ = w.Write(b.results)
+	default:
+		b.polls++
+		status := "in_progress"
+		if b.cancel || b.polls >= 2 {
+			status = "ended"
+		}
+		writeJSON(w, s.anthropicBatchObject(b, status, r))
+	}
+}
+
+// anthropicBatchObject 生成 Anthropic Message Batch 对象
+func (s *Server) anthropicBatchObject(b *mockBatch, status string, r *http.Request) map[string]any {
+	counts := map[string]any{"processing": b.total, "succeeded": 0, "errored": 0, "canceled": 0, "expired": 0}
+	obj := map[string]any{
+		"id":                  b.id,
+		"type":                "message_batch",
+		"processing_status":   status,
+		"request_counts":      counts,
+		"results_url":         nil,
+		"cancel_initiated_at": nil,
+	}
+	if b.cancel {
+		obj["cancel_initiated_at"] = "2025-01-01T00:00:00Z"
+	}
+	if status == "ended" {
+		counts["processing"] = 0
+		if b.cancel {
+			counts["canceled"] = b.total
+		} else {
+			counts["succeeded"], counts["errored"] = b.total-b.failed, b.failed
+		}
+		path := r.URL.Path
+		obj["results_url"] = s.URL + path[:strings.Index(path, "/messages/batches")] + "/messages/batches/" + b.id + "/results"
+	}
+	return obj
+}
+
+// save 保存文件并返回 ID
+func (b *batchStore) save(prefix string, data []byte) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.saveLocked(prefix, data)
+}
+
+// saveLocked 保存文件并返回 ID，调用方需持有锁
+func (b *batchStore) saveLocked(prefix string, data []byte) string {
+	b.seq++
+	id := fmt.Sprintf("%s-%d", prefix, b.seq)
+	if b.files == nil {
+		b.files = map[string][]byte{}
+	}
+	b.files[id] = data
+	return id
+}
+
+// add 保存批处理任务并返回 ID
+func (b *batchStore) add(batch *mockBatch) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	batch.id = fmt.Sprintf("batch_%d", b.seq)
+	if b.batches == nil {
+		b.batches = map[string]*mockBatch{}
+	}
+	b.batches[batch.id] = batch
+	return batch.id
+}
+
+// get 查询批处理任务
+func (b *batchStore) get(id string) (*mockBatch, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	batch, ok := b.batches[id]
+	return batch, ok
+}
+
+// writeJSON 输出 JSON 响应
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(mustJSON(v))
+}
@@ -0,0 +1,301 @@
+// Package agenttest 提供离线测试工具：按脚本返回结果的模拟 LLM，
+// 以及模拟 OpenAI、Anthropic、Gemini、Ollama 协议（含 SSE）的 httptest 服务
+package agenttest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sohaha/zlsgo/zarray"
+	"github.com/sohaha/zlsgo/zjson"
+	"github.com/sohaha/zlsgo/ztype"
+	"github.com/zlsgo/zllm/agent"
+	"github.com/zlsgo/zllm/message"
+)
+
+// ErrNoReply 脚本中的回复已用完
+var ErrNoReply = errors.New("agenttest: no scripted reply left")
+
+// Reply 一次脚本化的回复
+type Reply struct {
+	Err       error         // 返回的错误，模拟 LLM 时直接返回
+	Content   string        // 文本内容
+	Reasoning string        // 推理（思考）内容，模拟服务按各协议格式输出
+	Message   string        // 模拟服务返回错误时的错误信息
+	Tools     []agent.Tool  // 工具调用
+	Usage     Usage         // token 用量
+	Chunks    []string      // 流式分片，为空时整段内容作为一个分片
+	Delay     time.Duration // 响应前及每个分片之间的延迟
+	Status    int           // 模拟服务返回的 HTTP 状态码，默认 200
+}
+
+// Usage 回复中的 token 用量
+type Usage struct {
+	Input  int // 输入 token 数
+	Output int // 输出 token 数
+	Cached int // 命中提示词缓存的输入 token 数
+}
+
+// Text 文本回复
+func Text(content string) Reply {
+	return Reply{Content: content}
+}
+
+// ToolCall 工具调用回复
+func ToolCall(name, args string) Reply {
+	return Reply{Tools: []agent.Tool{{Name: name, Args: args}}}
+}
+
+// Error 错误回复
+func Error(err error) Reply {
+	return Reply{Err: err}
+}
+
+// HTTPError 模拟服务返回的 HTTP 错误
+func HTTPError(status int, message string) Reply {
+	return Reply{Status: status, Message: message}
+}
+
+// Chunks 流式回复，每个分片之间间隔 delay
+func Chunks(delay time.Duration, chunks ...string) Reply {
+	content := ""
+	for _, c := range chunks {
+		content += c
+	}
+	return Reply{Content: content, Chunks: chunks, Delay: delay}
+}
+
+// WithDelay 设置回复延迟
+func (r Reply) WithDelay(d time.Duration) Reply {
+	r.Delay = d
+	return r
+}
+
+// WithReasoning 设置推理内容
+func (r Reply) WithReasoning(reasoning string) Reply {
+	r.Reasoning = reasoning
+	return r
+}
+
+// WithUsage 设置 token 用量
+func (r Reply) WithUsage(input, output, cached int) Reply {
+	r.Usage = Usage{Input: input, Output: output, Cached: cached}
+	return r
+}
+
+// chunks 返回流式分片
+func (r Reply) chunks() []string {
+	if len(r.Chunks) > 0 {
+		return r.Chunks
+	}
+	if r.Content == "" {
+		return nil
+	}
+	return []string{r.Content}
+}
+
+// script 按顺序取出回复的脚本
+type script struct {
+	replies []Reply
+	index   int
+	repeat  bool
+	mu      sync.Mutex
+}
+
+// next 取出下一条回复，开启 repeat 时脚本用完后重复最后一条
+func (s *script) next() (Reply, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.index < len(s.replies) {
+		r := s.replies[s.index]
+		s.index++
+		return r, true
+	}
+	if s.repeat && len(s.replies) > 0 {
+		return s.replies[len(s.replies)-1], true
+	}
+	return Reply{}, false
+}
+
+// push 追加回复
+func (s *script) push(replies ...Reply) {
+	s.mu.Lock()
+	s.replies = append(s.replies, replies...)
+	s.mu.Unlock()
+}
+
+// MockLLM 按脚本返回结果的 LLM，实现 agent.LLM
+type MockLLM struct {
+	script   *script
+	requests [][]byte
+	mu       sync.Mutex
+}
+
+var _ agent.LLM = &MockLLM{}
+
+// NewMock 创建模拟 LLM，按顺序返回 replies
+func NewMock(replies ...Reply) *MockLLM {
+	return &MockLLM{script: &script{replies: replies}}
+}
+
+// Push 追加回复
+func (m *MockLLM) Push(replies ...Reply) *MockLLM {
+	m.script.push(replies...)
+	return m
+}
+
+// Repeat 脚本用完后重复最后一条回复
+func (m *MockLLM) Repeat() *MockLLM {
+	m.script.mu.Lock()
+	m.script.repeat = true
+	m.script.mu.Unlock()
+	return m
+}
+
+// Requests 返回收到的全部请求体
+func (m *MockLLM) Requests() [][]byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([][]byte(nil), m.requests...)
+}
+
+// Calls 返回调用次数
+func (m *MockLLM) Calls() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.requests)
+}
+
+// LastMessages 返回最后一次请求中的消息列表
+func (m *MockLLM) LastMessages() [][]string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.requests) == 0 {
+		return nil
+	}
+
+	var list [][]string
+	zjson.GetBytes(m.requests[len(m.requests)-1], "messages").ForEach(func(_, v *zjson.Res) bool {
+		list = append(list, []string{v.Get("role").String(), v.Get("content").String()})
+		return true
+	})
+	return list
+}
+
+// record 记录请求
+func (m *MockLLM) record(data []byte) {
+	m.mu.Lock()
+	m.requests = append(m.requests, append([]byte(nil), data...))
+	m.mu.Unlock()
+}
+
+// Generate 返回下一条脚本回复
+func (m *MockLLM) Generate(ctx context.Context, data []byte) (*zjson.Res, error) {
+	m.record(data)
+
+	reply, ok := m.script.next()
+	if !ok {
+		return nil, ErrNoReply
+	}
+	if err := sleep(ctx, reply.Delay); err != nil {
+		return nil, err
+	}
+	if reply.Err != nil {
+		return nil, reply.Err
+	}
+
+	return zjson.ParseBytes(OpenAIResponse(reply, "mock")), nil
+}
+
+// Stream 按分片回调下一条脚本回复，
+// 与真实提供商一致，ctx 取消时 done 直接关闭且不返回结果，调用方通过 ctx.Err() 获取原因
+func (m *MockLLM) Stream(ctx context.Context, data []byte, callback func(string, []byte)) (<-chan *zjson.Res, error) {
+	m.record(data)
+
+	reply, ok := m.script.next()
+	if !ok {
+		return nil, ErrNoReply
+	}
+	if reply.Err != nil {
+		return nil, reply.Err
+	}
+
+	done := make(chan *zjson.Res, 1)
+	go func() {
+		defer close(done)
+
+		for _, chunk := range reply.chunks() {
+			if err := sleep(ctx, reply.Delay); err != nil {
+				return
+			}
+			if callback != nil {
+				callback(chunk, openAIChunk(chunk))
+			}
+		}
+		done <- zjson.ParseBytes(OpenAIResponse(reply, "mock"))
+	}()
+
+	return done, nil
+}
+
+// PrepareRequest 生成 OpenAI 格式的请求体
+func (m *MockLLM) PrepareRequest(messages *message.Messages, options ...func(ztype.Map) ztype.Map) ([]byte, error) {
+	body := ztype.Map{
+		"model": "mock",
+		"messages": zarray.Map(messages.History(true), func(_ int, v []string) map[string]string {
+			return map[string]string{"role": v[0], "content": v[1]}
+		}),
+	}
+	for _, v := range options {
+		body = v(body)
+	}
+
+	return json.Marshal(body)
+}
+
+// ParseResponse 解析 OpenAI 格式的响应
+func (m *MockLLM) ParseResponse(body *zjson.Res) (*agent.Response, error) {
+	msg := body.Get("choices.0.message")
+	if !msg.Exists() {
+		return nil, errors.New("agenttest: invalid response: " + body.String())
+	}
+
+	resp := &agent.Response{}
+	msg.Get("tool_calls").ForEach(func(_, v *zjson.Res) bool {
+		resp.Tools = append(resp.Tools, agent.Tool{
+			ID:   v.Get("id").String(),
+			Name: v.Get("function.name").String(),
+			Args: v.Get("function.arguments").String(),
+		})
+		return true
+	})
+	if len(resp.Tools) == 0 {
+		resp.Content = []byte(msg.Get("content").String())
+	}
+
+	return resp, nil
+}
+
+// sleep 等待指定时间，上下文取消时提前返回
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
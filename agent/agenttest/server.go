@@ -0,0 +1,495 @@
+package agenttest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/sohaha/zlsgo/zjson"
+)
+
+// Protocol 模拟服务使用的协议
+type Protocol string
+
+const (
+	OpenAI          Protocol = "openai"
+	OpenAIResponses Protocol = "openai_responses"
+	Anthropic       Protocol = "anthropic"
+	Gemini          Protocol = "gemini"
+	Ollama          Protocol = "ollama"
+)
+
+// MockSignature 模拟服务为 Anthropic thinking 块生成的签名
+const MockSignature = "sig_mock"
+
+// Request 模拟服务收到的请求
+type Request struct {
+	Header http.Header
+	Method string
+	Path   string
+	Query  string
+	Body   []byte
+}
+
+// Stream 请求是否为流式请求
+func (r Request) Stream() bool {
+	return zjson.GetBytes(r.Body, "stream").Bool() ||
+		strings.Contains(r.Path, ":streamGenerateContent")
+}
+
+// Server 模拟各协议的 LLM 服务
+type Server struct {
+	*httptest.Server
+	script   *script
+	protocol Protocol
+	requests []Request
+	batch    batchStore
+	mu       sync.Mutex
+}
+
+// NewServer 创建并启动模拟服务，按顺序返回 replies
+func NewServer(protocol Protocol, replies ...Reply) *Server {
+	s := &Server{protocol: protocol, script: &script{replies: replies}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Push 追加回复
+func (s *Server) Push(replies ...Reply) *Server {
+	s.script.push(replies...)
+	return s
+}
+
+// Repeat 脚本用完后重复最后一条回复
+func (s *Server) Repeat() *Server {
+	s.script.mu.Lock()
+	s.script.repeat = true
+	s.script.mu.Unlock()
+	return s
+}
+
+// Requests 返回收到的全部请求
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Request(nil), s.requests...)
+}
+
+// handle 处理请求
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	req := Request{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Query:  r.URL.RawQuery,
+		Header: r.Header.Clone(),
+		Body:   body,
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	s.mu.Unlock()
+
+	if s.handleBatch(w, r, req) {
+		return
+	}
+
+	reply, ok := s.script.next()
+	if !ok {
+		writeError(w, http.StatusInternalServerError, ErrNoReply.Error())
+		return
+	}
+	if err := sleep(r.Context(), reply.Delay); err != nil {
+		return
+	}
+	if reply.Status >= 400 {
+		writeError(w, reply.Status, reply.Message)
+		return
+	}
+	if reply.Err != nil {
+		writeError(w, http.StatusInternalServerError, reply.Err.Error())
+		return
+	}
+
+	model := zjson.GetBytes(body, "model").String()
+	if !req.Stream() {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(s.response(reply, model))
+		return
+	}
+
+	s.stream(w, r, reply, model)
+}
+
+// response 生成非流式响应
+func (s *Server) response(reply Reply, model string) []byte {
+	switch s.protocol {
+	case Anthropic:
+		return AnthropicResponse(reply, model)
+	case Gemini:
+		return GeminiResponse(reply)
+	case Ollama:
+		return OllamaResponse(reply, model)
+	case OpenAIResponses:
+		return ResponsesResponse(reply, model)
+	default:
+		return OpenAIResponse(reply, model)
+	}
+}
+
+// stream 以对应协议的流式格式输出分片
+func (s *Server) stream(w http.ResponseWriter, r *http.Request, reply Reply, model string) {
+	flusher, _ := w.(http.Flusher)
+	if s.protocol == Ollama {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	} else {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+
+	write := func(event string, data []byte) {
+		switch {
+		case s.protocol == Ollama:
+			_, _ = w.Write(append(data, '\n'))
+		case event != "":
+			_, _ = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+		default:
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", data)
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	textIndex := 0
+	if s.protocol == Anthropic {
+		write("message_start", mustJSON(map[string]any{
+			"type":    "message_start",
+			"message": map[string]any{"id": "msg_mock", "type": "message", "role": "assistant", "model": model, "content": []any{}},
+		}))
+		if reply.Reasoning != "" {
+			write("content_block_start", mustJSON(map[string]any{
+				"type": "content_block_start", "index": 0, "content_block": map[string]any{"type": "thinking", "thinking": ""},
+			}))
+			write("content_block_delta", mustJSON(map[string]any{
+				"type": "content_block_delta", "index": 0, "delta": map[string]any{"type": "thinking_delta", "thinking": reply.Reasoning},
+			}))
+			write("content_block_delta", mustJSON(map[string]any{
+				"type": "content_block_delta", "index": 0, "delta": map[string]any{"type": "signature_delta", "signature": MockSignature},
+			}))
+			write("content_block_stop", mustJSON(map[string]any{"type": "content_block_stop", "index": 0}))
+			textIndex = 1
+		}
+		write("content_block_start", mustJSON(map[string]any{
+			"type": "content_block_start", "index": textIndex, "content_block": map[string]any{"type": "text", "text": ""},
+		}))
+	}
+
+	if reply.Reasoning != "" {
+		switch s.protocol {
+		case Gemini:
+			write("", mustJSON(map[string]any{
+				"candidates": []any{map[string]any{
+					"content": map[string]any{"parts": []any{map[string]any{"text": reply.Reasoning, "thought": true}}, "role": "model"},
+					"index":   0,
+				}},
+			}))
+		case OpenAIResponses:
+			write("response.reasoning_summary_text.delta", mustJSON(map[string]any{
+				"type": "response.reasoning_summary_text.delta", "item_id": "rs_mock", "output_index": 0, "summary_index": 0, "delta": reply.Reasoning,
+			}))
+		case Ollama:
+			write("", mustJSON(map[string]any{
+				"model":   model,
+				"message": map[string]any{"role": "assistant", "content": "", "thinking": reply.Reasoning},
+				"done":    false,
+			}))
+		case OpenAI:
+			write("", mustJSON(map[string]any{
+				"id":      "chatcmpl-mock",
+				"object":  "chat.completion.chunk",
+				"choices": []any{map[string]any{"index": 0, "delta": map[string]any{"reasoning_content": reply.Reasoning}}},
+			}))
+		}
+	}
+
+	if s.protocol == OpenAIResponses {
+		write("response.created", mustJSON(map[string]any{
+			"type":     "response.created",
+			"response": map[string]any{"id": "resp_mock", "object": "response", "status": "in_progress", "model": model, "output": []any{}},
+		}))
+	}
+
+	for i, chunk := range reply.chunks() {
+		if i > 0 {
+			if err := sleep(r.Context(), reply.Delay); err != nil {
+				return
+			}
+		}
+
+		switch s.protocol {
+		case Anthropic:
+			write("content_block_delta", mustJSON(map[string]any{
+				"type": "content_block_delta", "index": textIndex, "delta": map[string]any{"type": "text_delta", "text": chunk},
+			}))
+		case Gemini:
+			write("", mustJSON(map[string]any{
+				"candidates": []any{map[string]any{
+					"content": map[string]any{"parts": []any{map[string]any{"text": chunk}}, "role": "model"},
+					"index":   0,
+				}},
+			}))
+		case OpenAIResponses:
+			write("response.output_text.delta", mustJSON(map[string]any{
+				"type": "response.output_text.delta", "item_id": "msg_mock", "output_index": 0, "content_index": 0, "delta": chunk,
+			}))
+		case Ollama:
+			write("", mustJSON(map[string]any{
+				"model":   model,
+				"message": map[string]any{"role": "assistant", "content": chunk},
+				"done":    false,
+			}))
+		default:
+			write("", openAIChunk(chunk))
+		}
+	}
+
+	switch s.protocol {
+	case Anthropic:
+		write("content_block_stop", mustJSON(map[string]any{"type": "content_block_stop", "index": textIndex}))
+		write("message_delta", mustJSON(map[string]any{
+			"type": "message_delta", "delta": map[string]any{"stop_reason": "end_turn"},
+		}))
+		write("message_stop", mustJSON(map[string]any{"type": "message_stop"}))
+	case Gemini:
+		write("", mustJSON(map[string]any{
+			"candidates": []any{map[string]any{
+				"content":      map[string]any{"parts": []any{}, "role": "model"},
+				"finishReason": "STOP",
+				"index":        0,
+			}},
+		}))
+	case Ollama:
+		write("", mustJSON(map[string]any{
+			"model":       model,
+			"message":     map[string]any{"role": "assistant", "content": ""},
+			"done":        true,
+			"done_reason": "stop",
+		}))
+	case OpenAIResponses:
+		write("response.completed", mustJSON(map[string]any{
+			"type":     "response.completed",
+			"response": json.RawMessage(ResponsesResponse(reply, model)),
+		}))
+	default:
+		write("", []byte("[DONE]"))
+	}
+}
+
+// OpenAIResponse 生成 OpenAI Chat Completions 格式的响应
+func OpenAIResponse(reply Reply, model string) []byte {
+	msg := map[string]any{"role": "assistant", "content": reply.Content}
+	if reply.Reasoning != "" {
+		msg["reasoning_content"] = reply.Reasoning
+	}
+	finish := "stop"
+	if len(reply.Tools) > 0 {
+		calls := make([]any, 0, len(reply.Tools))
+		for i, t := range reply.Tools {
+			calls = append(calls, map[string]any{
+				"id":       fmt.Sprintf("call_%d", i),
+				"type":     "function",
+				"function": map[string]any{"name": t.Name, "arguments": t.Args},
+			})
+		}
+		msg["tool_calls"] = calls
+		finish = "tool_calls"
+	}
+
+	return mustJSON(map[string]any{
+		"id":      "chatcmpl-mock",
+		"object":  "chat.completion",
+		"model":   model,
+		"choices": []any{map[string]any{"index": 0, "message": msg, "finish_reason": finish}},
+		"usage": map[string]any{
+			"prompt_tokens":         reply.Usage.Input,
+			"completion_tokens":     reply.Usage.Output,
+			"total_tokens":          reply.Usage.Input + reply.Usage.Output,
+			"prompt_tokens_details": map[string]any{"cached_tokens": reply.Usage.Cached},
+		},
+	})
+}
+
+// ResponsesResponse 生成 OpenAI Responses API 格式的响应
+func ResponsesResponse(reply Reply, model string) []byte {
+	output := []any{}
+	if reply.Reasoning != "" {
+		output = append(output, map[string]any{
+			"type":    "reasoning",
+			"id":      "rs_mock",
+			"summary": []any{map[string]any{"type": "summary_text", "text": reply.Reasoning}},
+		})
+	}
+	if reply.Content != "" {
+		output = append(output, map[string]any{
+			"type":    "message",
+			"id":      "msg_mock",
+			"role":    "assistant",
+			"status":  "completed",
+			"content": []any{map[string]any{"type": "output_text", "text": reply.Content, "annotations": []any{}}},
+		})
+	}
+	for i, t := range reply.Tools {
+		output = append(output, map[string]any{
+			"type":      "function_call",
+			"id":        fmt.Sprintf("fc_%d", i),
+			"call_id":   fmt.Sprintf("call_%d", i),
+			"name":      t.Name,
+			"arguments": t.Args,
+			"status":    "completed",
+		})
+	}
+
+	return mustJSON(map[string]any{
+		"id":     "resp_mock",
+		"object": "response",
+		"status": "completed",
+		"model":  model,
+		"output": output,
+		"usage": map[string]any{
+			"input_tokens":         reply.Usage.Input,
+			"output_tokens":        reply.Usage.Output,
+			"total_tokens":         reply.Usage.Input + reply.Usage.Output,
+			"input_tokens_details": map[string]any{"cached_tokens": reply.Usage.Cached},
+		},
+	})
+}
+
+// AnthropicResponse 生成 Anthropic Messages 格式的响应
+func AnthropicResponse(reply Reply, model string) []byte {
+	content := []any{}
+	if reply.Reasoning != "" {
+		content = append(content, map[string]any{"type": "thinking", "thinking": reply.Reasoning, "signature": MockSignature})
+	}
+	if reply.Content != "" {
+		content = append(content, map[string]any{"type": "text", "text": reply.Content})
+	}
+	stop := "end_turn"
+	for i, t := range reply.Tools {
+		content = append(content, map[string]any{
+			"type":  "tool_use",
+			"id":    fmt.Sprintf("toolu_%d", i),
+			"name":  t.Name,
+			"input": json.RawMessage(argsJSON(t.Args)),
+		})
+		stop = "tool_use"
+	}
+
+	return mustJSON(map[string]any{
+		"id":          "msg_mock",
+		"type":        "message",
+		"role":        "assistant",
+		"model":       model,
+		"content":     content,
+		"stop_reason": stop,
+		"usage": map[string]any{
+			"input_tokens":            reply.Usage.Input,
+			"output_tokens":           reply.Usage.Output,
+			"cache_read_input_tokens": reply.Usage.Cached,
+		},
+	})
+}
+
+// GeminiResponse 生成 Gemini generateContent 格式的响应
+func GeminiResponse(reply Reply) []byte {
+	parts := []any{}
+	if reply.Reasoning != "" {
+		parts = append(parts, map[string]any{"text": reply.Reasoning, "thought": true})
+	}
+	if reply.Content != "" {
+		parts = append(parts, map[string]any{"text": reply.Content})
+	}
+	for _, t := range reply.Tools {
+		parts = append(parts, map[string]any{
+			"functionCall": map[string]any{"name": t.Name, "args": json.RawMessage(argsJSON(t.Args))},
+		})
+	}
+
+	return mustJSON(map[string]any{
+		"candidates": []any{map[string]any{
+			"content":      map[string]any{"parts": parts, "role": "model"},
+			"finishReason": "STOP",
+			"index":        0,
+		}},
+		"usageMetadata": map[string]any{
+			"promptTokenCount":        reply.Usage.Input,
+			"candidatesTokenCount":    reply.Usage.Output,
+			"totalTokenCount":         reply.Usage.Input + reply.Usage.Output,
+			"cachedContentTokenCount": reply.Usage.Cached,
+		},
+	})
+}
+
+// OllamaResponse 生成 Ollama /api/chat 格式的响应
+func OllamaResponse(reply Reply, model string) []byte {
+	msg := map[string]any{"role": "assistant", "content": reply.Content}
+	if reply.Reasoning != "" {
+		msg["thinking"] = reply.Reasoning
+	}
+	if len(reply.Tools) > 0 {
+		calls := make([]any, 0, len(reply.Tools))
+		for _, t := range reply.Tools {
+			calls = append(calls, map[string]any{
+				"function": map[string]any{"name": t.Name, "arguments": json.RawMessage(argsJSON(t.Args))},
+			})
+		}
+		msg["tool_calls"] = calls
+	}
+
+	return mustJSON(map[string]any{
+		"model":             model,
+		"message":           msg,
+		"done":              true,
+		"done_reason":       "stop",
+		"prompt_eval_count": reply.Usage.Input,
+		"eval_count":        reply.Usage.Output,
+	})
+}
+
+// openAIChunk 生成 OpenAI 流式分片
+func openAIChunk(content string) []byte {
+	return mustJSON(map[string]any{
+		"id":      "chatcmpl-mock",
+		"object":  "chat.completion.chunk",
+		"choices": []any{map[string]any{"index": 0, "delta": map[string]any{"content": content}}},
+	})
+}
+
+// writeError 输出错误响应
+func writeError(w http.ResponseWriter, status int, message string) {
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(mustJSON(map[string]any{"error": map[string]any{"message": message, "code": status}}))
+}
+
+// argsJSON 将工具参数转换为 JSON 对象
+func argsJSON(args string) string {
+	if zjson.Valid(args) && strings.HasPrefix(strings.TrimSpace(args), "{") {
+		return args
+	}
+	return "{}"
+}
+
+// mustJSON 序列化为 JSON
+func mustJSON(v any) []byte {
+	b, _ := json.Marshal(v)
+	return b
+}
@@ -27,6 +27,43 @@ type AnthropicOptions struct {
 	MaxRetries  uint                 // 失败请求的最大重试次数
 	MaxTokens   int                  // 响应中的最大 token 数（默认 4096）
 	OnMessage   func(string, []byte) // 流式消息回调函数
+
+	ThinkingBudget int       // 扩展思考 token 预算（可选，启用后不发送 temperature）
+	Sampling       Sampling  // 通用生成参数，支持 max_tokens、top_p、top_k、stop
+	RateLimit      RateLimit // 客户端限流（每分钟请求数与 token 数）
+}
+
+// 实现 providerConfig 接口
+func (o *AnthropicOptions) getAPIKey() []string {
+	return parseValue(o.APIKey)
+}
+
+func (o *AnthropicOptions) getEndpoints() []string {
+	return parseValue(o.BaseURL)
+}
+
+func (o *AnthropicOptions) getAPIPath() string {
+	return o.APIURL
+}
+
+func (o *AnthropicOptions) buildHeaders(apiKey string) zhttp.Header {
+	return zhttp.Header{
+		"Content-Type":      "application/json",
+		"x-api-key":         apiKey,
+		"anthropic-version": o.Version,
+	}
+}
+
+func (o *AnthropicOptions) getStreamProcessor() string {
+	return "anthropic"
+}
+
+func (o *AnthropicOptions) getMaxRetries() uint {
+	return o.MaxRetries
+}
+
+func (o *AnthropicOptions) getOnMessage() func(string, []byte) {
+	return o.OnMessage
 }
 
 // Anthropic Claude 模型的 LLM 代理实现
@@ -37,7 +74,18 @@ type AnthropicProvider struct {
 	keys     []string // 负载均衡的 API 密钥
 }
 
-var _ LLM = &AnthropicProvider{}
+var (
+	_ LLM            = &AnthropicProvider{}
+	_ CallOptionsLLM = &AnthropicProvider{}
+)
+
+// WithCallOptions 返回应用单次调用参数后的副本
+func (p *AnthropicProvider) WithCallOptions(co CallOptions) LLM {
+	c := *p
+	c.baseProvider = p.withCallOptions(co)
+	c.options.Model, c.options.Temperature = c.config.Model, c.config.Temperature
+	return &c
+}
 
 //		o.MaxTokens = 4096
 //		o.MaxRetries = 3
@@ -62,6 +110,8 @@ func NewAnthropic(opt ...func(*AnthropicOptions)) LLM {
 		Temperature: o.Temperature,
 		MaxRetries:  o.MaxRetries,
 		Stream:      o.Stream,
+		Sampling:    o.Sampling,
+		RateLimit:   o.RateLimit,
 	})
 
 	return &AnthropicProvider{
@@ -100,8 +150,6 @@ func (p *AnthropicProvider) Generate(ctx context.Context, body []byte) (*zjson.R
 		body, _ = zjson.SetBytes(body, "stream", false)
 	}
 
-	logRequestBody(body)
-
 	keys := newRand(p.keys)
 	endpoints := newRand(p.endpoint)
 
@@ -111,16 +159,17 @@ func (p *AnthropicProvider) Generate(ctx context.Context, body []byte) (*zjson.R
 		"anthropic-version": p.options.Version,
 	}
 
-	json, status, err := p.baseProvider.DoRequest(ctx, endpoints()+p.options.APIURL, headers, body)
+	url := endpoints() + p.options.APIURL
+	ctx, call := beginChat(ctx, "anthropic", p.config.Model, url, headers, body, false)
+	json, status, err := p.baseProvider.DoRequest(ctx, url, headers, body)
+	if err == nil && status >= 400 {
+		err = handleHTTPError("anthropic", status, "")
+	}
+	finishChat(ctx, call, json, status, err)
 	if err != nil {
 		return nil, err
 	}
 
-	if status >= 400 {
-		return nil, handleHTTPError("anthropic", status, "")
-	}
-
-	runtime.Log(json)
 	return json, nil
 }
 
@@ -141,8 +190,6 @@ func (p *AnthropicProvider) Stream(ctx context.Context, body []byte, callback fu
 		body, _ = zjson.SetBytes(body, "stream", true)
 	}
 
-	logRequestBody(body)
-
 	done := make(chan *zjson.Res, 1)
 
 	keys := newRand(p.keys)
@@ -158,13 +205,14 @@ func (p *AnthropicProvider) Stream(ctx context.Context, body []byte, callback fu
 		// 非流模式，直接请求
 		go func() {
 			defer close(done)
-			json, status, err := p.baseProvider.DoRequest(ctx, endpoints()+p.options.APIURL, headers, body)
-			if err != nil {
-				runtime.Log("Anthropic request error:", err)
-				return
+			url := endpoints() + p.options.APIURL
+			ctx, call := beginChat(ctx, "anthropic", p.config.Model, url, headers, body, false)
+			json, status, err := p.baseProvider.DoRequest(ctx, url, headers, body)
+			if err == nil && status >= 400 {
+				err = handleHTTPError("anthropic", status, "")
 			}
-			if status >= 400 {
-				runtime.Log("Anthropic http error:", status)
+			finishChat(ctx, call, json, status, err)
+			if err != nil {
 				return
 			}
 			done <- json
@@ -177,17 +225,24 @@ func (p *AnthropicProvider) Stream(ctx context.Context, body []byte, callback fu
 		defer close(done)
 		defer func() {
 			if r := recover(); r != nil {
-				runtime.Log("Anthropic stream goroutine panic:", r)
+				runtime.Error(ctx, "Anthropic stream goroutine panic", runtime.Attr("panic", r))
 			}
 		}()
 
-		sse, err := p.baseProvider.DoSSE(ctx, endpoints()+p.options.APIURL, headers, body)
+		var (
+			json *zjson.Res
+			err  error
+		)
+		url := endpoints() + p.options.APIURL
+		ctx, call := beginChat(ctx, "anthropic", p.config.Model, url, headers, body, true)
+		defer func() { finishChat(ctx, call, json, 0, err) }()
+
+		sse, err := p.baseProvider.DoSSE(ctx, url, headers, body)
 		if err != nil {
-			runtime.Log("Anthropic SSE error:", err)
 			return
 		}
 
-		json, err := processAnthropicStream(ctx, sse, newStreamConfig(func(chunk string, data []byte) {
+		json, err = processAnthropicStream(ctx, sse, newStreamConfig(func(chunk string, data []byte) {
 			if p.options.OnMessage != nil {
 				p.options.OnMessage(chunk, data)
 			}
@@ -196,9 +251,6 @@ func (p *AnthropicProvider) Stream(ctx context.Context, body []byte, callback fu
 			}
 		}), p.baseProvider.config.StreamTimeout)
 		if err != nil || json == nil {
-			if err != nil {
-				runtime.Log("Anthropic stream processing error:", err)
-			}
 			return
 		}
 
@@ -206,9 +258,9 @@ func (p *AnthropicProvider) Stream(ctx context.Context, body []byte, callback fu
 		select {
 		case done <- json:
 		case <-ctx.Done():
-			runtime.Log("Anthropic stream context cancelled")
+			runtime.Debug(ctx, "Anthropic stream context canceled")
 		case <-time.After(30 * time.Second):
-			runtime.Log("Anthropic stream response timeout after 30 seconds")
+			runtime.Warn(ctx, "Anthropic stream response timeout", runtime.Attr("timeout", "30s"))
 		}
 	}()
 
@@ -217,6 +269,11 @@ func (p *AnthropicProvider) Stream(ctx context.Context, body []byte, callback fu
 
 // PrepareRequest 将消息转换为 Anthropic 消息格式
 func (p *AnthropicProvider) PrepareRequest(messages *message.Messages, options ...func(ztype.Map) ztype.Map) ([]byte, error) {
+	sampling, err := p.samplingOption("anthropic", anthropicSampling)
+	if err != nil {
+		return nil, err
+	}
+
 	req := ztype.Map{
 		"model":       p.GetConfig().Model,
 		"stream":      p.GetConfig().Stream,
@@ -225,6 +282,7 @@ func (p *AnthropicProvider) PrepareRequest(messages *message.Messages, options .
 
 	// 收集 system 消息并转换对话
 	history := messages.History(true)
+	raw := messages.HistoryMessages(true)
 	var sys []string
 	arr := make([]ztype.Map, 0, len(history))
 	for i := range history {
@@ -240,10 +298,8 @@ func (p *AnthropicProvider) PrepareRequest(messages *message.Messages, options .
 				role = message.RoleUser
 			}
 			arr = append(arr, ztype.Map{
-				"role": role,
-				"content": []ztype.Map{
-					{"type": "text", "text": content},
-				},
+				"role":    role,
+				"content": anthropicContentBlocks(role, content, raw[i]),
 			})
 		}
 	}
@@ -259,6 +315,16 @@ func (p *AnthropicProvider) PrepareRequest(messages *message.Messages, options .
 	} else {
 		req["max_tokens"] = 1024
 	}
+	req = sampling(req)
+
+	if budget := p.options.ThinkingBudget; budget > 0 {
+		req["thinking"] = ztype.Map{"type": "enabled", "budget_tokens": budget}
+		// 扩展思考不支持自定义温度，且 max_tokens 必须大于预算
+		delete(req, "temperature")
+		if maxTokens := ztype.ToInt(req["max_tokens"]); maxTokens <= budget {
+			req["max_tokens"] = budget + maxTokens
+		}
+	}
 
 	for _, v := range options {
 		req = v(req)
@@ -271,15 +337,82 @@ func (p *AnthropicProvider) PrepareRequest(messages *message.Messages, options .
 	return json.Marshal(req)
 }
 
-// ParseResponse 解析 Anthropic 返回
+// anthropicContentBlocks 转换单条消息的内容块，工具调用与工具结果使用原生的 tool_use、tool_result 块
+func anthropicContentBlocks(role, content string, raw message.Message) []ztype.Map {
+	blocks := []ztype.Map{}
+	switch {
+	case role == message.RoleAssistant && len(raw.ToolCalls) > 0:
+		// 带签名的思考块需原样置于助手消息开头
+		blocks = append(blocks, anthropicReasoningBlocks(raw.Reasoning)...)
+		if raw.Content != "" {
+			blocks = append(blocks, ztype.Map{"type": "text", "text": raw.Content})
+		}
+		for _, c := range raw.ToolCalls {
+			args := []byte(c.Args)
+			if !json.Valid(args) {
+				// input 必须为 JSON 对象
+				args = []byte("{}")
+			}
+			blocks = append(blocks, ztype.Map{"type": "tool_use", "id": c.ID, "name": c.Name, "input": json.RawMessage(args)})
+		}
+		return blocks
+	case role == message.RoleUser && len(raw.ToolOutputs) > 0:
+		// tool_result 块需位于用户消息开头
+		for _, o := range raw.ToolOutputs {
+			blocks = append(blocks, ztype.Map{"type": "tool_result", "tool_use_id": o.ID, "content": o.Content, "is_error": o.IsError})
+		}
+		if content != raw.Content {
+			// 附加了输出格式要求时保留文本
+			blocks = append(blocks, ztype.Map{"type": "text", "text": content})
+		}
+		return blocks
+	case role == message.RoleAssistant:
+		blocks = append(blocks, anthropicReasoningBlocks(raw.Reasoning)...)
+	}
+	return append(blocks, ztype.Map{"type": "text", "text": content})
+}
+
+// ParseResponse 解析 Anthropic 返回，thinking 块作为推理内容保留签名
 func (p *AnthropicProvider) ParseResponse(body *zjson.Res) (*Response, error) {
-	text := body.Get("content.0.text").String()
-	if text == "" {
+	var (
+		text, thinking strings.Builder
+		resp           = &Response{}
+	)
+	body.Get("content").ForEach(func(_, block *zjson.Res) bool {
+		switch t := block.Get("type").String(); t {
+		case "text":
+			text.WriteString(block.Get("text").String())
+		case "thinking":
+			thinking.WriteString(block.Get("thinking").String())
+			resp.ReasoningBlocks = append(resp.ReasoningBlocks, message.ReasoningBlock{
+				Type:      t,
+				Thinking:  block.Get("thinking").String(),
+				Signature: block.Get("signature").String(),
+			})
+		case "redacted_thinking":
+			resp.ReasoningBlocks = append(resp.ReasoningBlocks, message.ReasoningBlock{
+				Type: t,
+				Data: block.Get("data").String(),
+			})
+		case "tool_use":
+			resp.Tools = append(resp.Tools, Tool{
+				ID:   block.Get("id").String(),
+				Name: block.Get("name").String(),
+				Args: block.Get("input").Raw(),
+			})
+		}
+		return true
+	})
+	if thinking.Len() > 0 {
+		resp.Reasoning = []byte(thinking.String())
+	}
+
+	if text.Len() == 0 && len(resp.Tools) == 0 {
 		// 兜底：有些情况 content 为空
 		if body.Get("error").Exists() {
 			return nil, errors.New(body.Get("error.message").String())
 		}
-		return &Response{Content: []byte{}}, nil
 	}
-	return &Response{Content: []byte(text)}, nil
+	resp.Content = []byte(text.String())
+	return resp, nil
 }
@@ -0,0 +1,140 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	neturl "net/url"
+
+	"github.com/sohaha/zlsgo/zjson"
+	runtime_errors "github.com/zlsgo/zllm/runtime/errors"
+)
+
+var _ BatchLLM = &AnthropicProvider{}
+
+// anthropicErrorStatus Anthropic 错误类型对应的 HTTP 状态码
+var anthropicErrorStatus = map[string]int{
+	"invalid_request_error": 400,
+	"authentication_error":  401,
+	"permission_error":      403,
+	"not_found_error":       404,
+	"request_too_large":     413,
+	"rate_limit_error":      429,
+	"api_error":             500,
+	"overloaded_error":      529,
+}
+
+// SubmitBatch 创建 Anthropic Message Batches 任务
+func (p *AnthropicProvider) SubmitBatch(ctx context.Context, requests []BatchRequest) (*Batch, error) {
+	items := make([]map[string]any, 0, len(requests))
+	for i := range requests {
+		body, err := completeMessage(p, requests[i].Body)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, map[string]any{
+			"custom_id": requests[i].CustomID,
+			"params":    json.RawMessage(batchBody(body)),
+		})
+	}
+
+	body, err := json.Marshal(map[string]any{"requests": items})
+	if err != nil {
+		return nil, err
+	}
+	res, err := p.doBatch(ctx, &p.options, "POST", p.batchPath(""), body)
+	if err != nil {
+		return nil, err
+	}
+	return anthropicBatch(res.JSONs()), nil
+}
+
+// GetBatch 查询 Anthropic Message Batches 任务
+func (p *AnthropicProvider) GetBatch(ctx context.Context, id string) (*Batch, error) {
+	res, err := p.doBatch(ctx, &p.options, "GET", p.batchPath(id))
+	if err != nil {
+		return nil, err
+	}
+	return anthropicBatch(res.JSONs()), nil
+}
+
+// CancelBatch 取消 Anthropic Message Batches 任务
+func (p *AnthropicProvider) CancelBatch(ctx context.Context, id string) (*Batch, error) {
+	res, err := p.doBatch(ctx, &p.options, "POST", p.batchPath(id)+"/cancel")
+	if err != nil {
+		return nil, err
+	}
+	return anthropicBatch(res.JSONs()), nil
+}
+
+// BatchResults 下载任务结果，并通过 ParseResponse 解析每个成功的消息
+func (p *AnthropicProvider) BatchResults(ctx context.Context, id string) ([]BatchResult, error) {
+	res, err := p.doBatch(ctx, &p.options, "GET", p.batchPath(id)+"/results")
+	if err != nil {
+		return nil, err
+	}
+
+	var results []BatchResult
+	for _, line := range bytes.Split(res.Bytes(), []byte("\n")) {
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			results = append(results, p.batchResult(zjson.ParseBytes(line)))
+		}
+	}
+	return results, nil
+}
+
+// batchResult 解析结果文件中的一行
+func (p *AnthropicProvider) batchResult(line *zjson.Res) BatchResult {
+	r := BatchResult{CustomID: line.Get("custom_id").String()}
+	result := line.Get("result")
+	switch result.Get("type").String() {
+	case "succeeded":
+		r.Response, r.Err = p.ParseResponse(result.Get("message"))
+	case "errored":
+		e := result.Get("error.error")
+		r.Err = batchError("anthropic", anthropicErrorStatus[e.Get("type").String()], e.Get("message").String())
+	case "canceled":
+		r.Err = runtime_errors.NewLLMError(runtime_errors.ErrContextCanceled, "anthropic batch request canceled")
+	case "expired":
+		r.Err = runtime_errors.NewLLMError(runtime_errors.ErrTimeout, "anthropic batch request expired")
+	default:
+		r.Err = runtime_errors.NewLLMError(runtime_errors.ErrInvalidResponse, "anthropic batch: unknown result "+result.String())
+	}
+	return r
+}
+
+// batchPath 批处理接口路径，基于消息接口路径拼接
+func (p *AnthropicProvider) batchPath(id string) string {
+	path := p.options.getAPIPath() + "/batches"
+	if id != "" {
+		path += "/" + neturl.PathEscape(id)
+	}
+	return path
+}
+
+// anthropicBatch 转换 Anthropic Message Batch 对象
+func anthropicBatch(res *zjson.Res) *Batch {
+	counts := res.Get("request_counts")
+	succeeded := counts.Get("succeeded").Int()
+	failed := counts.Get("errored").Int() + counts.Get("canceled").Int() + counts.Get("expired").Int()
+
+	status := BatchInProgress
+	switch res.Get("processing_status").String() {
+	case "canceling":
+		status = BatchCanceling
+	case "ended":
+		status = BatchCompleted
+		if res.Get("cancel_initiated_at").String() != "" {
+			status = BatchCanceled
+		}
+	}
+
+	return &Batch{
+		Raw:       res,
+		ID:        res.Get("id").String(),
+		Status:    status,
+		Total:     succeeded + failed + counts.Get("processing").Int(),
+		Succeeded: succeeded,
+		Failed:    failed,
+	}
+}
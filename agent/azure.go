@@ -0,0 +1,198 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/sohaha/zlsgo/zhttp"
+	"github.com/sohaha/zlsgo/zjson"
+	"github.com/sohaha/zlsgo/ztype"
+	"github.com/sohaha/zlsgo/zutil"
+	"github.com/zlsgo/zllm/message"
+	"github.com/zlsgo/zllm/runtime"
+)
+
+// AzureOptions Azure OpenAI 配置选项
+type AzureOptions struct {
+	APIKey      string                 // api-key 请求头使用的密钥，支持逗号分隔的多密钥负载均衡
+	Endpoint    string                 // 资源端点，如 https://{resource}.openai.azure.com，支持逗号分隔
+	Model       string                 // 模型名称
+	Deployment  string                 // 默认部署名称，为空时使用模型名称
+	Deployments map[string]string      // 模型名称到部署名称的映射
+	APIVersion  string                 // api-version 查询参数
+	TokenSource func() (string, error) // Entra ID 令牌获取函数，设置后使用 Bearer 认证，获取失败时请求直接返回错误
+	Temperature float64                // 采样温度
+	Stream      bool                   // 启用流式响应
+	MaxRetries  uint                   // 失败请求的最大重试次数
+	OnMessage   func(string, []byte)   // 流式消息回调函数
+	Sampling    Sampling               // 通用生成参数（max_tokens、top_p、stop 等）
+	RateLimit   RateLimit              // 客户端限流（每分钟请求数与 token 数）
+	token       string                 // 本次请求通过 TokenSource 获取的令牌
+}
+
+func (o *AzureOptions) getAPIKey() []string {
+	return parseValue(o.APIKey)
+}
+
+func (o *AzureOptions) getEndpoints() []string {
+	return parseValue(o.Endpoint)
+}
+
+func (o *AzureOptions) getAPIPath() string {
+	return "/openai/deployments/" + url.PathEscape(o.deployment(o.Model)) +
+		"/chat/completions?api-version=" + url.QueryEscape(o.APIVersion)
+}
+
+func (o *AzureOptions) buildHeaders(apiKey string) zhttp.Header {
+	h := buildJSONHeaders()
+	if o.token != "" {
+		h["Authorization"] = "Bearer " + o.token
+		return h
+	}
+	if apiKey != "" {
+		h["api-key"] = apiKey
+	}
+	return h
+}
+
+func (o *AzureOptions) getStreamProcessor() string {
+	return "openai"
+}
+
+func (o *AzureOptions) getMaxRetries() uint {
+	return o.MaxRetries
+}
+
+func (o *AzureOptions) getOnMessage() func(string, []byte) {
+	return o.OnMessage
+}
+
+// deployment 返回模型对应的部署名称
+func (o *AzureOptions) deployment(model string) string {
+	if d, ok := o.Deployments[model]; ok && d != "" {
+		return d
+	}
+	if o.Deployment != "" {
+		return o.Deployment
+	}
+	return model
+}
+
+// AzureProvider Azure OpenAI 的 LLM 代理实现
+type AzureProvider struct {
+	*baseProvider
+	options AzureOptions
+}
+
+var (
+	_ LLM            = &AzureProvider{}
+	_ CallOptionsLLM = &AzureProvider{}
+)
+
+// WithCallOptions 返回应用单次调用参数后的副本
+func (p *AzureProvider) WithCallOptions(co CallOptions) LLM {
+	c := *p
+	c.baseProvider = p.withCallOptions(co)
+	c.options.Model, c.options.Temperature = c.config.Model, c.config.Temperature
+	return &c
+}
+
+// NewAzure 创建新的 Azure OpenAI LLM 代理
+//
+//	agent.NewAzure(func(o *agent.AzureOptions) {
+//		o.Endpoint = "https://my-resource.openai.azure.com"
+//		o.APIKey = "your-api-key"
+//		o.Model = "gpt-4o"
+//		o.Deployments = map[string]string{"gpt-4o": "gpt4o-prod"}
+//	})
+func NewAzure(opt ...func(*AzureOptions)) LLM {
+	o := zutil.Optional(AzureOptions{
+		APIKey:      zutil.Getenv("AZURE_OPENAI_API_KEY", ""),
+		Endpoint:    zutil.Getenv("AZURE_OPENAI_ENDPOINT", ""),
+		Model:       zutil.Getenv("AZURE_OPENAI_MODEL", "gpt-4o"),
+		Deployment:  zutil.Getenv("AZURE_OPENAI_DEPLOYMENT", ""),
+		APIVersion:  zutil.Getenv("AZURE_OPENAI_API_VERSION", "2024-10-21"),
+		Temperature: 0.5,
+		MaxRetries:  3,
+	}, opt...)
+
+	if o.APIKey == "" && o.TokenSource == nil {
+		runtime.Warn(context.Background(), "AZURE_OPENAI_API_KEY not set, provider will be non-functional", runtime.Attr(runtime.KeyProvider, "azure"))
+	}
+
+	config := DefaultConfig().
+		WithAPIKey(o.APIKey).
+		WithModel(o.Model).
+		WithTemperature(o.Temperature).
+		WithRetries(o.MaxRetries).
+		WithSampling(o.Sampling).
+		WithRateLimit(o.RateLimit).
+		WithTimeout(30*time.Second, 60*time.Second)
+	config.BaseURL = o.Endpoint
+	config.Stream = o.Stream
+
+	return &AzureProvider{
+		baseProvider: newBaseProvider(config),
+		options:      o,
+	}
+}
+
+// requestOptions 按请求体中的模型选择部署，设置 TokenSource 时获取本次请求的令牌
+func (p *AzureProvider) requestOptions(body []byte) (*AzureOptions, error) {
+	o := p.options
+	if model := zjson.GetBytes(body, "model").String(); model != "" {
+		o.Model = model
+	}
+	if o.TokenSource != nil {
+		token, err := o.TokenSource()
+		if err != nil {
+			return nil, fmt.Errorf("azure token source: %w", err)
+		}
+		if token == "" {
+			return nil, errors.New("azure token source returned an empty token")
+		}
+		o.token = token
+	}
+	return &o, nil
+}
+
+func (p *AzureProvider) Generate(ctx context.Context, body []byte) (*zjson.Res, error) {
+	var err error
+	body, err = completeMessage(p, body)
+	if err != nil {
+		return nil, err
+	}
+	o, err := p.requestOptions(body)
+	if err != nil {
+		return nil, err
+	}
+	return p.baseProvider.generateWithConfig(ctx, o, body)
+}
+
+func (p *AzureProvider) Stream(ctx context.Context, body []byte, callback func(string, []byte)) (<-chan *zjson.Res, error) {
+	var err error
+	body, err = completeMessage(p, body)
+	if err != nil {
+		return nil, err
+	}
+	o, err := p.requestOptions(body)
+	if err != nil {
+		return nil, err
+	}
+	return p.baseProvider.streamWithConfig(ctx, o, body, callback)
+}
+
+func (p *AzureProvider) PrepareRequest(messages *message.Messages, options ...func(ztype.Map) ztype.Map) ([]byte, error) {
+	sampling, err := p.samplingOption("azure", openAISampling)
+	if err != nil {
+		return nil, err
+	}
+	return p.PrepareMessagesRequest(messages, append([]func(ztype.Map) ztype.Map{sampling}, options...)...)
+}
+
+func (p *AzureProvider) ParseResponse(body *zjson.Res) (*Response, error) {
+	return p.baseProvider.parseDefaultResponse(body)
+}
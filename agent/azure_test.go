@@ -0,0 +1,92 @@
+package agent_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sohaha/zlsgo"
+	"github.com/sohaha/zlsgo/ztype"
+	"github.com/zlsgo/zllm/agent"
+	"github.com/zlsgo/zllm/agent/agenttest"
+	"github.com/zlsgo/zllm/message"
+)
+
+func TestAzure(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	srv := agenttest.NewServer(agenttest.OpenAI).Push(agenttest.Text("hello")).Repeat()
+	defer srv.Close()
+
+	llm := agent.NewAzure(func(o *agent.AzureOptions) {
+		o.Endpoint = srv.URL
+		o.APIKey = "azure-key"
+		o.Model = "gpt-4o"
+		o.APIVersion = "2024-10-21"
+		o.Deployments = map[string]string{"gpt-4o": "gpt4o-prod", "gpt-4o-mini": "mini-prod"}
+	})
+
+	res, err := llm.Generate(context.Background(), []byte("hi"))
+	tt.NoError(err, true)
+	resp, err := llm.ParseResponse(res)
+	tt.NoError(err, true)
+	tt.Equal("hello", string(resp.Content))
+
+	req := srv.Requests()[0]
+	tt.Equal("/openai/deployments/gpt4o-prod/chat/completions", req.Path)
+	tt.Equal("api-version=2024-10-21", req.Query)
+	tt.Equal("azure-key", req.Header.Get("api-key"))
+	tt.Equal("", req.Header.Get("Authorization"))
+
+	msg := message.NewMessages()
+	_ = msg.AppendUser("hi")
+	body, err := llm.PrepareRequest(msg, func(m ztype.Map) ztype.Map {
+		m["model"] = "gpt-4o-mini"
+		return m
+	})
+	tt.NoError(err, true)
+
+	done, err := llm.Stream(context.Background(), body, func(string, []byte) {})
+	tt.NoError(err, true)
+	res = <-done
+	tt.EqualTrue(res != nil)
+	resp, err = llm.ParseResponse(res)
+	tt.NoError(err, true)
+	tt.Equal("hello", string(resp.Content))
+	tt.Equal("/openai/deployments/mini-prod/chat/completions", srv.Requests()[1].Path)
+	tt.EqualTrue(srv.Requests()[1].Stream())
+
+	entra := agent.NewAzure(func(o *agent.AzureOptions) {
+		o.Endpoint = srv.URL
+		o.APIKey = ""
+		o.Deployment = "default-deploy"
+		o.Model = "unmapped"
+		o.TokenSource = func() (string, error) { return "entra-token", nil }
+	})
+	_, err = entra.Generate(context.Background(), []byte("hi"))
+	tt.NoError(err, true)
+	req = srv.Requests()[2]
+	tt.Equal("/openai/deployments/default-deploy/chat/completions", req.Path)
+	tt.Equal("Bearer entra-token", req.Header.Get("Authorization"))
+	tt.Equal("", req.Header.Get("api-key"))
+}
+
+func TestAzureTokenSourceError(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	srv := agenttest.NewServer(agenttest.OpenAI, agenttest.Text("hello")).Repeat()
+	defer srv.Close()
+
+	tokenErr := errors.New("token expired")
+	llm := agent.NewAzure(func(o *agent.AzureOptions) {
+		o.Endpoint = srv.URL
+		o.APIKey = "azure-key"
+		o.TokenSource = func() (string, error) { return "", tokenErr }
+	})
+
+	_, err := llm.Generate(context.Background(), []byte("hi"))
+	tt.EqualTrue(errors.Is(err, tokenErr))
+	_, err = llm.Stream(context.Background(), []byte("hi"), func(string, []byte) {})
+	tt.EqualTrue(errors.Is(err, tokenErr))
+	tt.Equal(0, len(srv.Requests()))
+}
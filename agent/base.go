@@ -3,14 +3,16 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 
-	"github.com/sohaha/zlsgo/zarray"
 	"github.com/sohaha/zlsgo/zhttp"
 	"github.com/sohaha/zlsgo/zjson"
 	"github.com/sohaha/zlsgo/ztype"
 	"github.com/zlsgo/zllm/message"
 	"github.com/zlsgo/zllm/runtime"
+	"github.com/zlsgo/zllm/tokenizer"
 )
 
 // AuthProvider 认证配置接口
@@ -57,6 +59,8 @@ type Config struct {
 	Stream         bool
 	RequestTimeout time.Duration
 	StreamTimeout  time.Duration
+	Sampling       Sampling  // 通用生成参数
+	RateLimit      RateLimit // 客户端限流
 
 	// 调试配置
 	DebugMode bool
@@ -117,13 +121,19 @@ type baseProvider struct {
 
 func newBaseProvider(config Config) *baseProvider {
 	if config.Temperature < 0 || config.Temperature > 2 {
-		runtime.Log("Warning: Temperature should be between 0 and 2, clamping to valid range")
+		runtime.Warn(context.Background(), "Temperature should be between 0 and 2, clamping to valid range", runtime.Attr("temperature", config.Temperature))
 		if config.Temperature < 0 {
 			config.Temperature = 0
 		} else {
 			config.Temperature = 2
 		}
 	}
+	if config.RequestTimeout <= 0 {
+		config.RequestTimeout = DefaultConfig().RequestTimeout
+	}
+	if config.StreamTimeout <= 0 {
+		config.StreamTimeout = DefaultConfig().StreamTimeout
+	}
 	return &baseProvider{
 		config: config,
 	}
@@ -133,6 +143,16 @@ func (bp *baseProvider) GetConfig() Config {
 	return bp.config
 }
 
+// Tokenizer 返回当前模型对应的 token 计数器
+func (bp *baseProvider) Tokenizer() tokenizer.Tokenizer {
+	return tokenizer.ForModel(bp.config.Model)
+}
+
+// CountTokens 估算文本的 token 数
+func (bp *baseProvider) CountTokens(text string) int {
+	return bp.Tokenizer().Count(text)
+}
+
 func (bp *baseProvider) PrepareMessagesRequest(messages *message.Messages, options ...func(ztype.Map) ztype.Map) ([]byte, error) {
 	requestBody := ztype.Map{
 		"model":  bp.config.Model,
@@ -141,12 +161,7 @@ func (bp *baseProvider) PrepareMessagesRequest(messages *message.Messages, optio
 
 	requestBody["temperature"] = bp.config.Temperature
 
-	requestBody["messages"] = zarray.Map(messages.History(true), func(i int, v []string) map[string]string {
-		return map[string]string{
-			"role":    v[0],
-			"content": v[1],
-		}
-	})
+	requestBody["messages"] = chatMessages(messages)
 
 	for _, v := range options {
 		requestBody = v(requestBody)
@@ -155,17 +170,129 @@ func (bp *baseProvider) PrepareMessagesRequest(messages *message.Messages, optio
 	return json.Marshal(requestBody)
 }
 
+// chatMessages 转换为 Chat Completions 消息，工具调用与工具结果使用原生的 tool_calls 与 tool 消息
+func chatMessages(messages *message.Messages) []ztype.Map {
+	history := messages.History(true)
+	raw := messages.HistoryMessages(true)
+	result := make([]ztype.Map, 0, len(history))
+	for i := range history {
+		role, content := history[i][0], history[i][1]
+		switch {
+		case role == message.RoleAssistant && len(raw[i].ToolCalls) > 0:
+			calls := make([]ztype.Map, 0, len(raw[i].ToolCalls))
+			for _, c := range raw[i].ToolCalls {
+				calls = append(calls, ztype.Map{
+					"id":       c.ID,
+					"type":     "function",
+					"function": ztype.Map{"name": c.Name, "arguments": c.Args},
+				})
+			}
+			m := ztype.Map{"role": role, "content": nil, "tool_calls": calls}
+			if raw[i].Content != "" {
+				m["content"] = raw[i].Content
+			}
+			result = append(result, m)
+		case role == message.RoleUser && len(raw[i].ToolOutputs) > 0:
+			for _, o := range raw[i].ToolOutputs {
+				result = append(result, ztype.Map{"role": "tool", "tool_call_id": o.ID, "content": o.Content})
+			}
+			if content != raw[i].Content {
+				// 附加了输出格式要求时保留文本
+				result = append(result, ztype.Map{"role": role, "content": content})
+			}
+		default:
+			result = append(result, ztype.Map{"role": role, "content": content})
+		}
+	}
+	return result
+}
+
 func (bp *baseProvider) DoRequest(ctx context.Context, url string, headers zhttp.Header, body []byte) (*zjson.Res, int, error) {
+	limiter, err := bp.waitRateLimit(ctx, url, headers, body)
+	if err != nil {
+		return nil, 0, err
+	}
+
 	resp, err := runtime.GetClient().Post(url, headers, body, ctx)
 	if err != nil {
 		return nil, 0, err
 	}
 
+	limiter.update(resp.Response().Header, resp.StatusCode())
 	return resp.JSONs(), resp.StatusCode(), nil
 }
 
 func (bp *baseProvider) DoSSE(ctx context.Context, url string, headers zhttp.Header, body []byte) (*zhttp.SSEEngine, error) {
-	return runtime.GetClient().SSE(url, nil, headers, body, ctx)
+	limiter, err := bp.waitRateLimit(ctx, url, headers, body)
+	if err != nil {
+		return nil, err
+	}
+	c := runtime.GetClient()
+	return c.SSE(url, nil, headers, body, ctx, limiter.client(c.Client()))
+}
+
+// doRaw 按提供商配置发送请求并返回未读取的响应，状态码异常时返回错误
+// body 可以是 []byte 请求体，也可以是 zhttp 支持的其它参数（如表单、文件上传）
+func (bp *baseProvider) doRaw(ctx context.Context, config providerConfig, method, path string, body ...any) (*zhttp.Res, error) {
+	endpoint, apiKey := newRand(config.getEndpoints())(), newRand(config.getAPIKey())()
+	return bp.doWith(ctx, config, endpoint, apiKey, method, path, body...)
+}
+
+// doBatch 发送批处理请求，固定使用第一个服务地址与 API Key
+// 批处理任务只能在创建它的账号下查询，多地址或多 Key 轮换会导致任务不可见
+func (bp *baseProvider) doBatch(ctx context.Context, config providerConfig, method, path string, body ...any) (*zhttp.Res, error) {
+	return bp.doWith(ctx, config, firstValue(config.getEndpoints()), firstValue(config.getAPIKey()), method, path, body...)
+}
+
+// firstValue 返回列表中的第一个值，列表为空时返回空字符串
+func firstValue(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// doWith 使用指定的服务地址与 API Key 发送请求
+func (bp *baseProvider) doWith(ctx context.Context, config providerConfig, endpoint, apiKey, method, path string, body ...any) (*zhttp.Res, error) {
+	url := endpoint + path
+	headers := config.buildHeaders(apiKey)
+
+	var raw []byte
+	args := []any{headers, ctx}
+	for _, b := range body {
+		if v, ok := b.([]byte); ok {
+			raw = v
+		}
+		args = append(args, b)
+	}
+
+	limiter, err := bp.waitRateLimit(ctx, url, headers, raw)
+	if err != nil {
+		return nil, err
+	}
+	res, err := runtime.GetClient().Do(method, url, args...)
+	if err != nil {
+		return nil, err
+	}
+	limiter.update(res.Response().Header, res.Response().StatusCode)
+
+	// StatusCode 会读取整个响应体，流式响应需直接读取状态码
+	if status := res.Response().StatusCode; status >= 400 {
+		message := errorMessage(res.JSON("error"))
+		if message == "" {
+			message = strings.TrimSpace(res.String())
+		}
+		return nil, handleHTTPError(config.getStreamProcessor(), status, message)
+	}
+	return res, nil
+}
+
+// errorMessage 提取响应中的错误信息，兼容字符串与对象两种格式
+func errorMessage(e *zjson.Res) string {
+	if e.IsObject() {
+		return e.Get("message").String()
+	}
+	return e.String()
 }
 
 // generateWithConfig 通用生成方法
@@ -176,24 +303,22 @@ func (bp *baseProvider) generateWithConfig(ctx context.Context, config providerC
 		body, _ = zjson.SetBytes(body, "stream", false)
 	}
 
-	logRequestBody(body)
-
 	keys := newRand(config.getAPIKey())
 	endpoints := newRand(config.getEndpoints())
 
 	url := endpoints() + config.getAPIPath()
 	headers := config.buildHeaders(keys())
 
+	ctx, call := beginChat(ctx, traceSystem(config), bp.config.Model, url, headers, body, false)
 	json, status, err := bp.DoRequest(ctx, url, headers, body)
+	if err == nil && status >= 400 {
+		err = handleHTTPError("provider", status, "")
+	}
+	finishChat(ctx, call, json, status, err)
 	if err != nil {
 		return nil, err
 	}
 
-	if status >= 400 {
-		return nil, handleHTTPError("provider", status, "")
-	}
-
-	runtime.Log(json)
 	return json, nil
 }
 
@@ -208,8 +333,6 @@ func (bp *baseProvider) streamWithConfig(ctx context.Context, config providerCon
 		body, _ = zjson.SetBytes(body, "stream", true)
 	}
 
-	logRequestBody(body)
-
 	done := make(chan *zjson.Res, 1)
 
 	if !stream {
@@ -217,23 +340,22 @@ func (bp *baseProvider) streamWithConfig(ctx context.Context, config providerCon
 			defer close(done)
 			defer func() {
 				if r := recover(); r != nil {
-					runtime.Log("Generate goroutine panic:", r)
+					runtime.Error(ctx, "Generate goroutine panic", runtime.Attr("panic", r))
 				}
 			}()
 
 			json, err := bp.generateWithConfig(ctx, config, body)
 			if err != nil {
-				runtime.Log("Generate error:", err)
 				return
 			}
 
 			select {
 			case done <- json:
 			case <-ctx.Done():
-				runtime.Log("Generate context cancelled")
+				runtime.Debug(ctx, "Generate context canceled")
 				return
 			case <-time.After(bp.config.RequestTimeout):
-				runtime.Log("Generate response timeout after", bp.config.RequestTimeout)
+				runtime.Warn(ctx, "Generate response timeout", runtime.Attr("timeout", bp.config.RequestTimeout.String()))
 				return
 			}
 		}()
@@ -244,7 +366,7 @@ func (bp *baseProvider) streamWithConfig(ctx context.Context, config providerCon
 		defer close(done)
 		defer func() {
 			if r := recover(); r != nil {
-				runtime.Log("Stream goroutine panic:", r)
+				runtime.Error(ctx, "Stream goroutine panic", runtime.Attr("panic", r))
 			}
 		}()
 
@@ -254,9 +376,15 @@ func (bp *baseProvider) streamWithConfig(ctx context.Context, config providerCon
 		url := endpoints() + config.getAPIPath()
 		headers := config.buildHeaders(keys())
 
+		var (
+			json *zjson.Res
+			err  error
+		)
+		ctx, call := beginChat(ctx, traceSystem(config), bp.config.Model, url, headers, body, true)
+		defer func() { finishChat(ctx, call, json, 0, err) }()
+
 		sse, err := bp.DoSSE(ctx, url, headers, body)
 		if err != nil {
-			runtime.Log("SSE error:", err)
 			return
 		}
 
@@ -269,37 +397,31 @@ func (bp *baseProvider) streamWithConfig(ctx context.Context, config providerCon
 			}
 		})
 
-		var json *zjson.Res
 		switch config.getStreamProcessor() {
 		case "openai":
 			json, err = processOpenAIStream(ctx, sse, streamConfig, bp.config.StreamTimeout)
 		case "anthropic":
 			json, err = processAnthropicStream(ctx, sse, streamConfig, bp.config.StreamTimeout)
-		case "ollama":
-			json, err = processOllamaStream(ctx, sse, streamConfig, bp.config.StreamTimeout)
 		case "gemini":
 			json, err = processGeminiStream(ctx, sse, streamConfig, bp.config.StreamTimeout)
+		case "openai_responses":
+			json, err = processOpenAIResponsesStream(ctx, sse, streamConfig, bp.config.StreamTimeout)
 		default:
-			runtime.Log("Unknown stream processor:", config.getStreamProcessor())
+			err = fmt.Errorf("unknown stream processor: %s", config.getStreamProcessor())
 			return
 		}
 
 		if err != nil || json == nil {
-			if err != nil {
-				runtime.Log("Stream processing error:", err)
-			}
 			return
 		}
 
-		runtime.Log(json)
-
 		select {
 		case done <- json:
 		case <-ctx.Done():
-			runtime.Log("Stream context cancelled")
+			runtime.Debug(ctx, "Stream context canceled")
 			return
 		case <-time.After(bp.config.StreamTimeout):
-			runtime.Log("Stream response timeout after", bp.config.StreamTimeout)
+			runtime.Warn(ctx, "Stream response timeout", runtime.Attr("timeout", bp.config.StreamTimeout.String()))
 			return
 		}
 	}()
@@ -309,21 +431,30 @@ func (bp *baseProvider) streamWithConfig(ctx context.Context, config providerCon
 
 // parseDefaultResponse 通用响应解析
 func (bp *baseProvider) parseDefaultResponse(body *zjson.Res) (*Response, error) {
+	var reasoning []byte
+	if r := openAIReasoning(body.Get("choices.0.message")); r != "" {
+		reasoning = []byte(r)
+	}
+
 	tools, _, hasTools := preferToolCallsInResponse(body)
 	if hasTools {
 		// 转换私有 tool 为公开 Tool
 		publicTools := make([]Tool, len(tools))
 		for i, t := range tools {
 			publicTools[i] = Tool{
+				ID:   t.ID,
 				Name: t.Name,
 				Args: t.Args,
 			}
 		}
-		return &Response{Tools: publicTools}, nil
+		return &Response{Tools: publicTools, Reasoning: reasoning}, nil
 	}
 	content, err := extractContentOrError(body)
 	if err != nil {
 		return nil, err
 	}
-	return &Response{Content: content}, nil
+	if reasoning == nil {
+		reasoning, content = splitReasoning(content)
+	}
+	return &Response{Content: content, Reasoning: reasoning}, nil
 }
@@ -0,0 +1,126 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sohaha/zlsgo/zjson"
+	"github.com/sohaha/zlsgo/ztype"
+	"github.com/zlsgo/zllm/message"
+	"github.com/zlsgo/zllm/runtime"
+	runtime_errors "github.com/zlsgo/zllm/runtime/errors"
+)
+
+// BatchStatus 批处理任务状态
+type BatchStatus string
+
+const (
+	BatchInProgress BatchStatus = "in_progress" // 校验、排队或处理中
+	BatchCompleted  BatchStatus = "completed"   // 处理完成，可以下载结果
+	BatchFailed     BatchStatus = "failed"      // 任务失败（如输入校验未通过）
+	BatchExpired    BatchStatus = "expired"     // 未在时间窗口内完成
+	BatchCanceling  BatchStatus = "canceling"   // 取消中
+	BatchCanceled   BatchStatus = "canceled"    // 已取消，已完成的部分仍可下载
+)
+
+// Done 任务是否已结束
+func (s BatchStatus) Done() bool {
+	switch s {
+	case BatchCompleted, BatchFailed, BatchExpired, BatchCanceled:
+		return true
+	}
+	return false
+}
+
+// BatchRequest 批处理中的单个请求
+type BatchRequest struct {
+	CustomID string // 自定义 ID，用于将结果对应回请求
+	Body     []byte // PrepareRequest 生成的请求体
+}
+
+// Batch 批处理任务
+type Batch struct {
+	Raw       *zjson.Res  // 服务端原始响应
+	ID        string      // 任务 ID
+	Status    BatchStatus // 统一后的状态
+	Total     int         // 请求总数
+	Succeeded int         // 成功数
+	Failed    int         // 失败数（含取消、过期）
+}
+
+// BatchResult 批处理中单个请求的结果
+type BatchResult struct {
+	Response *Response // 经 ParseResponse 解析后的响应
+	Err      error     // 请求失败时的错误
+	CustomID string    // 对应请求的自定义 ID
+}
+
+// BatchLLM 支持离线批处理接口的提供商
+type BatchLLM interface {
+	LLM
+	// SubmitBatch 提交批处理任务
+	SubmitBatch(ctx context.Context, requests []BatchRequest) (*Batch, error)
+	// GetBatch 查询任务状态
+	GetBatch(ctx context.Context, id string) (*Batch, error)
+	// CancelBatch 取消任务
+	CancelBatch(ctx context.Context, id string) (*Batch, error)
+	// BatchResults 下载任务结果，顺序不保证与提交顺序一致，需按 CustomID 对应
+	BatchResults(ctx context.Context, id string) ([]BatchResult, error)
+}
+
+// NewBatchRequests 使用提供商的 PrepareRequest 将消息集合转换为批处理请求，CustomID 为 request-<下标>
+func NewBatchRequests(llm LLM, messages []*message.Messages, options ...func(ztype.Map) ztype.Map) ([]BatchRequest, error) {
+	requests := make([]BatchRequest, 0, len(messages))
+	for i := range messages {
+		body, err := llm.PrepareRequest(messages[i], options...)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, BatchRequest{CustomID: fmt.Sprintf("request-%d", i), Body: body})
+	}
+	return requests, nil
+}
+
+// WaitBatch 按间隔轮询任务状态直到结束或上下文取消
+func WaitBatch(ctx context.Context, llm BatchLLM, id string, interval time.Duration) (*Batch, error) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	for {
+		batch, err := llm.GetBatch(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if batch.Status.Done() {
+			return batch, nil
+		}
+		runtime.Debug(ctx, "Batch in progress", runtime.Attr("batch_id", id), runtime.Attr(runtime.KeyStatus, string(batch.Status)),
+			runtime.Attr("done", batch.Succeeded+batch.Failed), runtime.Attr("total", batch.Total))
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return batch, ctx.Err()
+		}
+	}
+}
+
+// batchBody 去掉批处理不支持的流式参数
+func batchBody(body []byte) []byte {
+	if zjson.GetBytes(body, "stream").Exists() {
+		body, _ = zjson.DeleteBytes(body, "stream")
+	}
+	return body
+}
+
+// batchError 将批处理结果中的错误转换为 LLMError
+func batchError(provider string, status int, message string) error {
+	if status <= 0 {
+		return runtime_errors.NewLLMError(runtime_errors.ErrServer, message)
+	}
+	return handleHTTPError(provider, status, message)
+}
@@ -0,0 +1,132 @@
+package agent_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sohaha/zlsgo"
+	"github.com/sohaha/zlsgo/zjson"
+	"github.com/zlsgo/zllm/agent"
+	"github.com/zlsgo/zllm/agent/agenttest"
+	"github.com/zlsgo/zllm/message"
+	runtime_errors "github.com/zlsgo/zllm/runtime/errors"
+)
+
+func TestBatch(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	messages := make([]*message.Messages, 3)
+	for i := range messages {
+		messages[i] = message.NewMessages()
+		_ = messages[i].AppendUser("hi")
+	}
+
+	run := func(tt *zlsgo.TestUtil, llm agent.BatchLLM) []agent.BatchResult {
+		requests, err := agent.NewBatchRequests(llm, messages)
+		tt.NoError(err, true)
+		tt.Equal("request-2", requests[2].CustomID)
+
+		batch, err := llm.SubmitBatch(context.Background(), requests)
+		tt.NoError(err, true)
+		tt.Equal(agent.BatchInProgress, batch.Status)
+
+		batch, err = agent.WaitBatch(context.Background(), llm, batch.ID, time.Millisecond)
+		tt.NoError(err, true)
+		tt.Equal(agent.BatchCompleted, batch.Status)
+		tt.Equal(3, batch.Total)
+		tt.Equal(2, batch.Succeeded)
+		tt.Equal(1, batch.Failed)
+
+		results, err := llm.BatchResults(context.Background(), batch.ID)
+		tt.NoError(err, true)
+		tt.Equal(3, len(results))
+
+		byID := map[string]agent.BatchResult{}
+		for _, r := range results {
+			byID[r.CustomID] = r
+		}
+		tt.Equal("one", string(byID["request-0"].Response.Content))
+		tt.Equal("weather", byID["request-2"].Response.Tools[0].Name)
+		llmErr, ok := byID["request-1"].Err.(runtime_errors.LLMError)
+		tt.EqualTrue(ok)
+		tt.Equal("bad request", llmErr.Message)
+		return results
+	}
+	replies := []agenttest.Reply{
+		agenttest.Text("one"),
+		agenttest.HTTPError(400, "bad request"),
+		agenttest.ToolCall("weather", `{"city":"北京"}`),
+	}
+
+	tt.Run("OpenAI", func(tt *zlsgo.TestUtil) {
+		srv := agenttest.NewServer(agenttest.OpenAI, replies...)
+		defer srv.Close()
+
+		llm := agent.NewOpenAI(func(o *agent.OpenAIOptions) {
+			o.BaseURL = srv.URL + "/v1"
+			o.APIKey = "sk-test"
+			o.Stream = true
+		}).(agent.BatchLLM)
+		run(tt, llm)
+
+		reqs := srv.Requests()
+		tt.Equal("/v1/files", reqs[0].Path)
+		tt.Equal("/v1/chat/completions", zjson.GetBytes(reqs[1].Body, "endpoint").String())
+	})
+
+	tt.Run("Anthropic", func(tt *zlsgo.TestUtil) {
+		srv := agenttest.NewServer(agenttest.Anthropic, replies...)
+		defer srv.Close()
+
+		llm := agent.NewAnthropic(func(o *agent.AnthropicOptions) {
+			o.BaseURL = srv.URL
+			o.APIKey = "sk-ant-test"
+		}).(agent.BatchLLM)
+		run(tt, llm)
+
+		reqs := srv.Requests()
+		tt.Equal("/v1/messages/batches", reqs[0].Path)
+		tt.Equal("sk-ant-test", reqs[0].Header.Get("x-api-key"))
+		tt.Equal("", zjson.GetBytes(reqs[0].Body, "requests.0.params.stream").String())
+	})
+
+	tt.Run("MultiKey", func(tt *zlsgo.TestUtil) {
+		srv := agenttest.NewServer(agenttest.OpenAI, replies...)
+		defer srv.Close()
+
+		llm := agent.NewOpenAI(func(o *agent.OpenAIOptions) {
+			o.BaseURL = srv.URL + "/v1"
+			o.APIKey = "k1,k2,k3"
+		}).(agent.BatchLLM)
+		run(tt, llm)
+
+		for _, req := range srv.Requests() {
+			tt.Equal("Bearer k1", req.Header.Get("Authorization"))
+		}
+	})
+
+	tt.Run("Cancel", func(tt *zlsgo.TestUtil) {
+		srv := agenttest.NewServer(agenttest.Anthropic, agenttest.Text("one")).Repeat()
+		defer srv.Close()
+
+		llm := agent.NewAnthropic(func(o *agent.AnthropicOptions) { o.BaseURL = srv.URL }).(agent.BatchLLM)
+		requests, err := agent.NewBatchRequests(llm, messages[:1])
+		tt.NoError(err, true)
+		batch, err := llm.SubmitBatch(context.Background(), requests)
+		tt.NoError(err, true)
+
+		batch, err = llm.CancelBatch(context.Background(), batch.ID)
+		tt.NoError(err, true)
+		tt.Equal(agent.BatchCanceling, batch.Status)
+
+		batch, err = llm.GetBatch(context.Background(), batch.ID)
+		tt.NoError(err, true)
+		tt.Equal(agent.BatchCanceled, batch.Status)
+
+		results, err := llm.BatchResults(context.Background(), batch.ID)
+		tt.NoError(err, true)
+		llmErr, _ := results[0].Err.(runtime_errors.LLMError)
+		tt.Equal(runtime_errors.ErrContextCanceled, llmErr.Code)
+	})
+}
@@ -0,0 +1,89 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/zlsgo/zllm/runtime"
+)
+
+// CallOptions 单次调用的参数覆盖，零值项沿用提供商构造时的配置
+type CallOptions struct {
+	Model       string   // 模型名称
+	Temperature *float64 // 采样温度，可通过 Ptr 设置
+	Sampling    Sampling // 通用生成参数，非零项覆盖提供商配置
+}
+
+// CallOptionsLLM 支持单次调用参数覆盖的 LLM 代理
+type CallOptionsLLM interface {
+	// WithCallOptions 返回应用覆盖参数后的副本，不修改原实例
+	WithCallOptions(co CallOptions) LLM
+}
+
+// ApplyCallOptions 对 LLM 应用单次调用参数，不支持覆盖时记录警告并原样返回
+func ApplyCallOptions(llm LLM, co CallOptions) LLM {
+	if co.IsZero() {
+		return llm
+	}
+	if c, ok := llm.(CallOptionsLLM); ok {
+		return c.WithCallOptions(co)
+	}
+	runtime.Warn(context.Background(), "LLM does not support call options, ignored",
+		runtime.Attr("llm", fmt.Sprintf("%T", llm)))
+	return llm
+}
+
+// IsZero 是否未设置任何覆盖参数
+func (co CallOptions) IsZero() bool {
+	return co.Model == "" && co.Temperature == nil && co.Sampling.IsZero()
+}
+
+// Ptr 返回值的指针，便于设置可选参数
+func Ptr[T any](v T) *T {
+	return &v
+}
+
+// merge 合并通用生成参数，o 中的非零项优先
+func (s Sampling) merge(o Sampling) Sampling {
+	if o.MaxTokens > 0 {
+		s.MaxTokens = o.MaxTokens
+	}
+	if o.TopP > 0 {
+		s.TopP = o.TopP
+	}
+	if o.TopK > 0 {
+		s.TopK = o.TopK
+	}
+	if len(o.Stop) > 0 {
+		s.Stop = o.Stop
+	}
+	if o.Seed != nil {
+		s.Seed = o.Seed
+	}
+	if o.PresencePenalty != 0 {
+		s.PresencePenalty = o.PresencePenalty
+	}
+	if o.FrequencyPenalty != 0 {
+		s.FrequencyPenalty = o.FrequencyPenalty
+	}
+	if len(o.LogitBias) > 0 {
+		s.LogitBias = o.LogitBias
+	}
+	if o.N > 0 {
+		s.N = o.N
+	}
+	return s
+}
+
+// withCallOptions 返回应用覆盖参数后的基础配置副本
+func (bp *baseProvider) withCallOptions(co CallOptions) *baseProvider {
+	config := bp.config
+	if co.Model != "" {
+		config.Model = co.Model
+	}
+	if co.Temperature != nil {
+		config.Temperature = *co.Temperature
+	}
+	config.Sampling = config.Sampling.merge(co.Sampling)
+	return newBaseProvider(config)
+}
@@ -0,0 +1,245 @@
+package agent
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sohaha/zlsgo/zjson"
+	"github.com/zlsgo/zllm/runtime"
+	runtime_errors "github.com/zlsgo/zllm/runtime/errors"
+)
+
+//go:embed models.json
+var catalogData []byte
+
+// Capabilities 模型能力
+type Capabilities struct {
+	Tools           bool `json:"tools"`             // 支持工具调用
+	Vision          bool `json:"vision"`            // 支持图片输入
+	Streaming       bool `json:"streaming"`         // 支持流式输出
+	StreamingTools  bool `json:"streaming_tools"`   // 支持在流式输出中返回工具调用
+	JSONSchema      bool `json:"json_schema"`       // 支持 JSON Schema 结构化输出
+	Reasoning       bool `json:"reasoning"`         // 支持推理（思考）输出
+	ContextWindow   int  `json:"context_window"`    // 上下文窗口大小（token），0 表示未知
+	MaxOutputTokens int  `json:"max_output_tokens"` // 最大输出 token 数，0 表示未知
+}
+
+// Missing 返回 need 中要求但当前模型不具备的能力名称
+func (c Capabilities) Missing(need Capabilities) []string {
+	var missing []string
+	check := func(name string, required, ok bool) {
+		if required && !ok {
+			missing = append(missing, name)
+		}
+	}
+	check("tools", need.Tools, c.Tools)
+	check("vision", need.Vision, c.Vision)
+	check("streaming", need.Streaming, c.Streaming)
+	check("streaming_tools", need.StreamingTools, c.StreamingTools)
+	check("json_schema", need.JSONSchema, c.JSONSchema)
+	check("reasoning", need.Reasoning, c.Reasoning)
+	check("context_window", need.ContextWindow > 0 && c.ContextWindow > 0, c.ContextWindow >= need.ContextWindow)
+	check("max_output_tokens", need.MaxOutputTokens > 0 && c.MaxOutputTokens > 0, c.MaxOutputTokens >= need.MaxOutputTokens)
+	return missing
+}
+
+// Satisfies 是否具备 need 中要求的全部能力，未知的窗口大小视为满足
+func (c Capabilities) Satisfies(need Capabilities) bool {
+	return len(c.Missing(need)) == 0
+}
+
+// ModelInfo 模型信息
+type ModelInfo struct {
+	ID           string       `json:"id"`
+	Provider     string       `json:"provider"`
+	Capabilities Capabilities `json:"capabilities"`
+	Known        bool         `json:"known"` // 能力是否来自模型目录或服务端，为 false 时 Capabilities 为零值
+}
+
+// ModelLister 支持列出服务端可用模型的 LLM 代理
+type ModelLister interface {
+	Models(ctx context.Context) ([]ModelInfo, error)
+}
+
+// CapabilitiesLLM 可直接声明模型能力的 LLM 代理，优先于模型目录
+type CapabilitiesLLM interface {
+	Capabilities() (Capabilities, bool)
+}
+
+var (
+	catalog   = loadCatalog()
+	catalogMu sync.RWMutex
+)
+
+// loadCatalog 加载内置模型目录
+func loadCatalog() map[string]map[string]Capabilities {
+	m := map[string]map[string]Capabilities{}
+	if err := json.Unmarshal(catalogData, &m); err != nil {
+		runtime.Error(context.Background(), "Invalid model catalog", runtime.ErrAttr(err))
+	}
+	return m
+}
+
+// RegisterModel 注册或覆盖模型能力，model 同时作为前缀匹配带版本后缀的模型名
+func RegisterModel(provider, model string, caps Capabilities) {
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+
+	provider, model = strings.ToLower(provider), strings.ToLower(model)
+	if catalog[provider] == nil {
+		catalog[provider] = map[string]Capabilities{}
+	}
+	catalog[provider][model] = caps
+}
+
+// LookupModel 查询模型能力，按最长前缀匹配（如 gpt-4o-2024-08-06 匹配 gpt-4o），
+// 只在指定提供商的目录中查找，未收录时返回 false
+func LookupModel(provider, model string) (Capabilities, bool) {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+
+	provider, model = strings.ToLower(provider), strings.ToLower(model)
+	for _, name := range catalogNames(model) {
+		if caps, ok := lookupCatalog(catalog[provider], name); ok {
+			return caps, true
+		}
+	}
+	return Capabilities{}, false
+}
+
+// guessModel 查询模型能力，指定提供商未收录时在全部提供商中查找，
+// 同名模型在不同提供商下能力可能不同，结果仅供展示参考，不能用于校验请求
+func guessModel(provider, model string) (Capabilities, bool) {
+	if caps, ok := LookupModel(provider, model); ok {
+		return caps, true
+	}
+
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+
+	provider, model = strings.ToLower(provider), strings.ToLower(model)
+	providers := make([]string, 0, len(catalog))
+	for p := range catalog {
+		if p != provider {
+			providers = append(providers, p)
+		}
+	}
+	sort.Strings(providers)
+	for _, name := range catalogNames(model) {
+		for _, p := range providers {
+			if caps, ok := lookupCatalog(catalog[p], name); ok {
+				return caps, true
+			}
+		}
+	}
+	return Capabilities{}, false
+}
+
+// catalogNames 模型在目录中的候选名称，带命名空间时（如 deepseek/deepseek-r1）同时尝试去掉命名空间的名称
+func catalogNames(model string) []string {
+	names := []string{model}
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		names = append(names, model[i+1:])
+	}
+	return names
+}
+
+// lookupCatalog 在单个提供商的目录中按最长前缀匹配模型
+func lookupCatalog(models map[string]Capabilities, model string) (Capabilities, bool) {
+	if caps, ok := models[model]; ok {
+		return caps, true
+	}
+
+	var (
+		best string
+		caps Capabilities
+	)
+	for name, c := range models {
+		if len(name) <= len(best) || !strings.HasPrefix(model, name) {
+			continue
+		}
+		if strings.IndexByte("-:@", model[len(name)]) < 0 {
+			continue
+		}
+		best, caps = name, c
+	}
+	return caps, best != ""
+}
+
+// ModelCapabilities 获取 LLM 当前模型的能力
+func ModelCapabilities(llm LLM) (Capabilities, bool) {
+	if c, ok := llm.(CapabilitiesLLM); ok {
+		return c.Capabilities()
+	}
+	provider, model := DescribeModel(llm)
+	if model == "" {
+		return Capabilities{}, false
+	}
+	return LookupModel(provider, model)
+}
+
+// DescribeModel 返回 LLM 的提供商名称与模型名称，无法识别时返回空字符串
+func DescribeModel(llm LLM) (provider, model string) {
+	switch p := llm.(type) {
+	case *InterceptProvider:
+		return DescribeModel(p.llm)
+	case *OpenAIProvider, *AzureProvider, *OpenAIResponsesProvider:
+		provider = "openai"
+	case *DeepseekProvider:
+		provider = "deepseek"
+	case *AnthropicProvider:
+		provider = "anthropic"
+	case *GeminiProvider:
+		provider = "gemini"
+	case *OllamaProvider:
+		provider = "ollama"
+	case *CompatibleProvider:
+		provider = p.options.providerName()
+	}
+	if c, ok := llm.(interface{ GetConfig() Config }); ok {
+		model = c.GetConfig().Model
+	}
+	return provider, model
+}
+
+// RequiredCapabilities 根据请求体推断所需的模型能力
+func RequiredCapabilities(body []byte) Capabilities {
+	req := zjson.ParseBytes(body)
+	format := req.Get("format")
+	return Capabilities{
+		Tools: len(req.Get("tools").Array()) > 0,
+		JSONSchema: req.Get("response_format.type").String() == "json_schema" ||
+			req.Get("text.format.type").String() == "json_schema" ||
+			req.Get("generationConfig.responseSchema").Exists() ||
+			req.Get("generationConfig.responseJsonSchema").Exists() ||
+			format.IsObject(),
+	}
+}
+
+// CheckCapabilities 校验模型是否具备请求所需的能力，模型未收录时不做限制
+func CheckCapabilities(llm LLM, body []byte) error {
+	caps, ok := ModelCapabilities(llm)
+	if !ok {
+		return nil
+	}
+	missing := caps.Missing(RequiredCapabilities(body))
+	if len(missing) == 0 {
+		return nil
+	}
+
+	_, model := DescribeModel(llm)
+	return runtime_errors.NewLLMErrorWithDetails(runtime_errors.ErrInvalidRequest,
+		fmt.Sprintf("model %s does not support: %s", model, strings.Join(missing, ", ")),
+		map[string]interface{}{"model": model, "missing": missing})
+}
+
+// modelInfo 根据模型目录生成模型信息
+func modelInfo(provider, id string) ModelInfo {
+	caps, ok := guessModel(provider, id)
+	return ModelInfo{ID: id, Provider: provider, Capabilities: caps, Known: ok}
+}
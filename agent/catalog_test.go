@@ -0,0 +1,113 @@
+package agent_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sohaha/zlsgo"
+	"github.com/zlsgo/zllm/agent"
+	runtime_errors "github.com/zlsgo/zllm/runtime/errors"
+)
+
+func TestCatalog(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	caps, ok := agent.LookupModel("openai", "gpt-4o-2024-08-06")
+	tt.EqualTrue(ok)
+	tt.EqualTrue(caps.Tools && caps.Vision && caps.JSONSchema)
+	tt.Equal(128000, caps.ContextWindow)
+
+	caps, ok = agent.LookupModel("anthropic", "claude-3-5-sonnet-20241022")
+	tt.EqualTrue(ok)
+	tt.Equal(8192, caps.MaxOutputTokens)
+
+	caps, ok = agent.LookupModel("gemini", "models/gemini-2.0-flash-lite-001")
+	tt.EqualTrue(ok)
+	tt.Equal(1048576, caps.ContextWindow)
+
+	_, ok = agent.LookupModel("vllm", "qwen2.5:7b")
+	tt.EqualTrue(!ok)
+
+	_, ok = agent.LookupModel("openai", "gpt-4oxyz")
+	tt.EqualTrue(!ok)
+
+	agent.RegisterModel("custom", "my-model", agent.Capabilities{Tools: true, ContextWindow: 8192})
+	caps, ok = agent.LookupModel("custom", "my-model-v2")
+	tt.EqualTrue(ok)
+	tt.EqualTrue(caps.Satisfies(agent.Capabilities{Tools: true, ContextWindow: 4096}))
+	tt.Equal([]string{"vision", "context_window"}, caps.Missing(agent.Capabilities{Vision: true, ContextWindow: 16384}))
+
+	llm := agent.NewOllama(func(o *agent.OllamaOptions) { o.Model = "deepseek-r1:7b" })
+	caps, ok = agent.ModelCapabilities(llm)
+	tt.EqualTrue(ok)
+	tt.EqualTrue(caps.Reasoning && !caps.Tools)
+
+	tools := []byte(`{"model":"deepseek-r1:7b","tools":[{"type":"function","function":{"name":"weather"}}]}`)
+	err := agent.CheckCapabilities(llm, tools)
+	llmErr, ok := err.(runtime_errors.LLMError)
+	tt.EqualTrue(ok)
+	tt.Equal(runtime_errors.ErrInvalidRequest, llmErr.Code)
+	tt.Equal("model deepseek-r1:7b does not support: tools", llmErr.Error())
+
+	tt.NoError(agent.CheckCapabilities(llm, []byte(`{"format":"json"}`)))
+	tt.NoError(agent.CheckCapabilities(agent.NewOpenAI(func(o *agent.OpenAIOptions) { o.Model = "gpt-4o" }), tools))
+	tt.NoError(agent.CheckCapabilities(agent.NewOpenAI(func(o *agent.OpenAIOptions) { o.Model = "unknown" }), tools))
+	tt.NoError(agent.CheckCapabilities(agent.NewCompatible(func(o *agent.CompatibleOptions) {
+		o.Profile = "openrouter"
+		o.Model = "deepseek/deepseek-r1"
+	}), tools))
+	tt.EqualTrue(agent.RequiredCapabilities([]byte(`{"response_format":{"type":"json_schema"}}`)).JSONSchema)
+
+	provider, model := agent.DescribeModel(agent.Intercept(agent.NewCompatible(func(o *agent.CompatibleOptions) { o.Model = "local" })))
+	tt.Equal("openai_compatible", provider)
+	tt.Equal("local", model)
+}
+
+func TestModels(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/models":
+			_, _ = w.Write([]byte(`{"object":"list","data":[{"id":"gpt-4o-mini"},{"id":"ft:custom"}]}`))
+		case "/v1beta/models":
+			_, _ = w.Write([]byte(`{"models":[
+				{"name":"models/gemini-2.0-flash","inputTokenLimit":1000000,"outputTokenLimit":8192,"supportedGenerationMethods":["generateContent","streamGenerateContent"]},
+				{"name":"models/text-embedding-004","supportedGenerationMethods":["embedContent"]}]}`))
+		case "/api/tags":
+			_, _ = w.Write([]byte(`{"models":[{"name":"qwen3:8b"}]}`))
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+
+	openai := agent.NewOpenAI(func(o *agent.OpenAIOptions) {
+		o.BaseURL = srv.URL + "/v1"
+		o.APIKey = "sk-test"
+	}).(agent.ModelLister)
+	models, err := openai.Models(ctx)
+	tt.NoError(err, true)
+	tt.Equal(2, len(models))
+	tt.EqualTrue(models[0].Known && models[0].Capabilities.Tools)
+	tt.EqualTrue(!models[1].Known)
+
+	gemini := agent.NewGemini(func(o *agent.GeminiOptions) {
+		o.BaseURL = srv.URL
+		o.APIKey = "gk-test"
+	}).(agent.ModelLister)
+	models, err = gemini.Models(ctx)
+	tt.NoError(err, true)
+	tt.Equal(1, len(models))
+	tt.Equal("gemini-2.0-flash", models[0].ID)
+	tt.Equal(1000000, models[0].Capabilities.ContextWindow)
+	tt.EqualTrue(models[0].Capabilities.Streaming && models[0].Capabilities.Tools)
+
+	ollama := agent.NewOllama(func(o *agent.OllamaOptions) { o.BaseURL = srv.URL }).(agent.ModelLister)
+	models, err = ollama.Models(ctx)
+	tt.NoError(err, true)
+	tt.Equal("ollama", models[0].Provider)
+	tt.EqualTrue(models[0].Capabilities.Reasoning)
+}
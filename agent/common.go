@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"regexp"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -17,17 +16,18 @@ import (
 	"github.com/sohaha/zlsgo/ztype"
 	"github.com/sohaha/zlsgo/zutil"
 	"github.com/zlsgo/zllm/message"
-	"github.com/zlsgo/zllm/runtime"
 	runtime_errors "github.com/zlsgo/zllm/runtime/errors"
 )
 
 // Tool 工具调用信息
 type Tool struct {
+	ID   string `json:"id,omitempty"` // 调用 ID，回传工具结果时使用
 	Name string `json:"name"`
 	Args string `json:"args"`
 }
 
 type tool struct {
+	ID   string `json:"id,omitempty"`
 	Name string `json:"name"`
 	Args string `json:"args"`
 }
@@ -123,63 +123,6 @@ func handleHTTPError(providerName string, status int, message string) error {
 	return runtime_errors.NewLLMError(runtime_errors.MapHTTPToCode(status), message)
 }
 
-// logRequestBody 记录请求体
-func logRequestBody(body []byte) {
-	if runtime.IsDebug() {
-		sanitized := sanitizeSensitiveData(zstring.Bytes2String(body))
-		runtime.Log("Request Body:", sanitized)
-	}
-}
-
-// 用于检测日志中敏感数据的正则表达式模式
-var (
-	apiKeyPattern   = regexp.MustCompile(`(?i)(api[_-]?key["\s]*[:=]["\s]*)[a-zA-Z0-9_-]{10,}`)
-	bearerPattern   = regexp.MustCompile(`(?i)(authorization["\s]*[:=]["\s]*bearer\s+)[a-zA-Z0-9._-]+`)
-	tokenPattern    = regexp.MustCompile(`(?i)(token["\s]*[:=]["\s]*)[a-zA-Z0-9._-]{10,}`)
-	passwordPattern = regexp.MustCompile(`(?i)(password["\s]*[:=]["\s]*)[^\s"']{4,}`)
-)
-
-// sanitizeSensitiveData 清理敏感数据
-func sanitizeSensitiveData(input string) string {
-	result := apiKeyPattern.ReplaceAllStringFunc(input, func(match string) string {
-		parts := apiKeyPattern.FindStringSubmatch(match)
-		if len(parts) >= 2 {
-			key := parts[1]
-			return key + "***REDACTED***"
-		}
-		return match
-	})
-
-	result = bearerPattern.ReplaceAllStringFunc(result, func(match string) string {
-		parts := bearerPattern.FindStringSubmatch(match)
-		if len(parts) >= 2 {
-			prefix := parts[1]
-			return prefix + "***REDACTED***"
-		}
-		return match
-	})
-
-	result = tokenPattern.ReplaceAllStringFunc(result, func(match string) string {
-		parts := tokenPattern.FindStringSubmatch(match)
-		if len(parts) >= 2 {
-			key := parts[1]
-			return key + "***REDACTED***"
-		}
-		return match
-	})
-
-	result = passwordPattern.ReplaceAllStringFunc(result, func(match string) string {
-		parts := passwordPattern.FindStringSubmatch(match)
-		if len(parts) >= 2 {
-			key := parts[1]
-			return key + "***REDACTED***"
-		}
-		return match
-	})
-
-	return result
-}
-
 // WithToolCallHint 添加工具调用选项
 func WithToolCallHint(tools any) func(ztype.Map) ztype.Map {
 	return func(m ztype.Map) ztype.Map {
@@ -283,6 +226,7 @@ func preferToolCallsInResponse(body *zjson.Res) ([]tool, []byte, bool) {
 		var tools []tool
 		for _, v := range toolCalls.Array() {
 			tools = append(tools, tool{
+				ID:   v.Get("id").String(),
 				Name: v.Get("function.name").String(),
 				Args: v.Get("function.arguments").String(),
 			})
@@ -0,0 +1,349 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/sohaha/zlsgo/zhttp"
+	"github.com/sohaha/zlsgo/zjson"
+	"github.com/sohaha/zlsgo/ztype"
+	"github.com/sohaha/zlsgo/zutil"
+	"github.com/zlsgo/zllm/message"
+	"github.com/zlsgo/zllm/runtime"
+)
+
+// Quirks OpenAI 兼容服务的差异配置
+type Quirks struct {
+	BaseURL        string            // 默认 API 基础 URL
+	APIURL         string            // API 路径，默认 /chat/completions
+	Headers        map[string]string // 额外请求头
+	DropParams     []string          // 需要移除的请求参数，如 tool_choice
+	RenameParams   map[string]string // 请求参数重命名，如 max_tokens -> max_completion_tokens
+	DefaultParams  map[string]any    // 请求中缺失时补充的参数
+	MinTemperature float64           // 温度下限
+	MaxTemperature float64           // 温度上限，为 0 时不限制
+	ResponseFields map[string]string // 响应字段映射，目标路径 -> 源路径，目标不存在时从源路径复制
+}
+
+// merge 合并差异配置，other 中的非空项覆盖当前配置
+func (q Quirks) merge(other Quirks) Quirks {
+	if other.BaseURL != "" {
+		q.BaseURL = other.BaseURL
+	}
+	if other.APIURL != "" {
+		q.APIURL = other.APIURL
+	}
+	if other.MaxTemperature > 0 {
+		q.MinTemperature, q.MaxTemperature = other.MinTemperature, other.MaxTemperature
+	}
+	q.Headers = mergeMap(q.Headers, other.Headers)
+	q.RenameParams = mergeMap(q.RenameParams, other.RenameParams)
+	q.DefaultParams = mergeMap(q.DefaultParams, other.DefaultParams)
+	q.ResponseFields = mergeMap(q.ResponseFields, other.ResponseFields)
+	q.DropParams = append(append([]string(nil), q.DropParams...), other.DropParams...)
+	return q
+}
+
+// mergeMap 合并两个映射，返回新映射
+func mergeMap[V any](a, b map[string]V) map[string]V {
+	if len(a) == 0 && len(b) == 0 {
+		return nil
+	}
+	m := make(map[string]V, len(a)+len(b))
+	for k, v := range a {
+		m[k] = v
+	}
+	for k, v := range b {
+		m[k] = v
+	}
+	return m
+}
+
+var (
+	quirkProfiles = map[string]Quirks{
+		"qwen": {
+			BaseURL:        "https://dashscope.aliyuncs.com/compatible-mode/v1",
+			MaxTemperature: 1.99,
+		},
+		"moonshot": {
+			BaseURL:        "https://api.moonshot.cn/v1",
+			MaxTemperature: 1,
+		},
+		"zhipu": {
+			BaseURL:        "https://open.bigmodel.cn/api/paas/v4",
+			MinTemperature: 0.01,
+			MaxTemperature: 0.99,
+		},
+		"vllm": {
+			BaseURL: "http://localhost:8000/v1",
+		},
+		"lmstudio": {
+			BaseURL:    "http://localhost:1234/v1",
+			DropParams: []string{"tool_choice"},
+		},
+		"openrouter": {
+			BaseURL: "https://openrouter.ai/api/v1",
+			ResponseFields: map[string]string{
+				"choices.0.message.reasoning_content": "choices.0.message.reasoning",
+			},
+		},
+	}
+	quirkMu sync.RWMutex
+)
+
+// RegisterQuirks 注册或覆盖命名的差异配置
+func RegisterQuirks(name string, q Quirks) {
+	quirkMu.Lock()
+	quirkProfiles[name] = q
+	quirkMu.Unlock()
+}
+
+// GetQuirks 获取命名的差异配置
+func GetQuirks(name string) (Quirks, bool) {
+	quirkMu.RLock()
+	defer quirkMu.RUnlock()
+
+	q, ok := quirkProfiles[name]
+	return q, ok
+}
+
+// CompatibleOptions OpenAI 兼容服务配置选项
+type CompatibleOptions struct {
+	Profile     string               // 差异配置名称，如 qwen、moonshot、zhipu、vllm、lmstudio、openrouter
+	Quirks      Quirks               // 自定义差异配置，与 Profile 合并并优先生效
+	APIKey      string               // API 密钥，支持逗号分隔的多密钥负载均衡
+	Model       string               // 模型名称
+	BaseURL     string               // API 基础 URL，为空时使用差异配置中的地址
+	Temperature float64              // 采样温度
+	Stream      bool                 // 启用流式响应
+	MaxRetries  uint                 // 失败请求的最大重试次数
+	OnMessage   func(string, []byte) // 流式消息回调函数
+	Sampling    Sampling             // 通用生成参数，不支持的参数可通过 Quirks.DropParams 移除
+	RateLimit   RateLimit            // 客户端限流（每分钟请求数与 token 数）
+	quirks      Quirks
+}
+
+// providerName 提供商名称，未设置 Profile 时为 openai_compatible，日志、追踪与指标共用
+func (o *CompatibleOptions) providerName() string {
+	if o.Profile != "" {
+		return o.Profile
+	}
+	return "openai_compatible"
+}
+
+func (o *CompatibleOptions) getAPIKey() []string {
+	return parseValue(o.APIKey)
+}
+
+func (o *CompatibleOptions) getEndpoints() []string {
+	return parseValue(o.BaseURL)
+}
+
+func (o *CompatibleOptions) getAPIPath() string {
+	if o.quirks.APIURL != "" {
+		return o.quirks.APIURL
+	}
+	return "/chat/completions"
+}
+
+func (o *CompatibleOptions) buildHeaders(apiKey string) zhttp.Header {
+	h := buildAuthHeaders(apiKey)
+	for k, v := range o.quirks.Headers {
+		h[k] = v
+	}
+	return h
+}
+
+func (o *CompatibleOptions) getStreamProcessor() string {
+	return "openai"
+}
+
+func (o *CompatibleOptions) getMaxRetries() uint {
+	return o.MaxRetries
+}
+
+func (o *CompatibleOptions) getOnMessage() func(string, []byte) {
+	return o.OnMessage
+}
+
+// CompatibleProvider OpenAI 兼容服务的 LLM 代理实现
+type CompatibleProvider struct {
+	*baseProvider
+	options CompatibleOptions
+}
+
+var (
+	_ LLM            = &CompatibleProvider{}
+	_ CallOptionsLLM = &CompatibleProvider{}
+)
+
+// WithCallOptions 返回应用单次调用参数后的副本
+func (p *CompatibleProvider) WithCallOptions(co CallOptions) LLM {
+	c := *p
+	c.baseProvider = p.withCallOptions(co)
+	c.options.Model, c.options.Temperature = c.config.Model, c.config.Temperature
+	return &c
+}
+
+// NewCompatible 创建 OpenAI 兼容服务的 LLM 代理
+//
+//	agent.NewCompatible(func(o *agent.CompatibleOptions) {
+//		o.Profile = "moonshot"
+//		o.APIKey = "sk-..."
+//		o.Model = "moonshot-v1-8k"
+//	})
+func NewCompatible(opt ...func(*CompatibleOptions)) LLM {
+	o := zutil.Optional(CompatibleOptions{
+		Temperature: 0.5,
+		MaxRetries:  3,
+	}, opt...)
+
+	if o.Profile != "" {
+		q, ok := GetQuirks(o.Profile)
+		if !ok {
+			runtime.Warn(context.Background(), "Unknown quirk profile", runtime.Attr("profile", o.Profile))
+		}
+		o.quirks = q
+	}
+	o.quirks = o.quirks.merge(o.Quirks)
+	if o.BaseURL == "" {
+		o.BaseURL = o.quirks.BaseURL
+	}
+
+	config := DefaultConfig().
+		WithAPIKey(o.APIKey).
+		WithModel(o.Model).
+		WithTemperature(o.Temperature).
+		WithRetries(o.MaxRetries).
+		WithSampling(o.Sampling).
+		WithRateLimit(o.RateLimit).
+		WithTimeout(30*time.Second, 60*time.Second)
+	config.BaseURL = o.BaseURL
+	config.Stream = o.Stream
+
+	return &CompatibleProvider{
+		baseProvider: newBaseProvider(config),
+		options:      o,
+	}
+}
+
+func (p *CompatibleProvider) Generate(ctx context.Context, body []byte) (*zjson.Res, error) {
+	var err error
+	body, err = completeMessage(p, body)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := p.baseProvider.generateWithConfig(ctx, &p.options, p.applyRequestQuirks(body))
+	if err != nil {
+		return nil, err
+	}
+	return p.applyResponseQuirks(res), nil
+}
+
+func (p *CompatibleProvider) Stream(ctx context.Context, body []byte, callback func(string, []byte)) (<-chan *zjson.Res, error) {
+	var err error
+	body, err = completeMessage(p, body)
+	if err != nil {
+		return nil, err
+	}
+
+	done, err := p.baseProvider.streamWithConfig(ctx, &p.options, p.applyRequestQuirks(body), callback)
+	if err != nil || len(p.options.quirks.ResponseFields) == 0 {
+		return done, err
+	}
+
+	mapped := make(chan *zjson.Res, 1)
+	go func() {
+		defer close(mapped)
+		if res, ok := <-done; ok && res != nil {
+			mapped <- p.applyResponseQuirks(res)
+		}
+	}()
+	return mapped, nil
+}
+
+func (p *CompatibleProvider) PrepareRequest(messages *message.Messages, options ...func(ztype.Map) ztype.Map) ([]byte, error) {
+	sampling, err := p.samplingOption("compatible", openAISampling)
+	if err != nil {
+		return nil, err
+	}
+	return p.PrepareMessagesRequest(messages, append([]func(ztype.Map) ztype.Map{sampling}, options...)...)
+}
+
+func (p *CompatibleProvider) ParseResponse(body *zjson.Res) (*Response, error) {
+	return p.baseProvider.parseDefaultResponse(body)
+}
+
+// applyRequestQuirks 按差异配置调整请求参数
+func (p *CompatibleProvider) applyRequestQuirks(body []byte) []byte {
+	q := p.options.quirks
+	if len(q.DropParams) == 0 && len(q.RenameParams) == 0 && len(q.DefaultParams) == 0 && q.MaxTemperature <= 0 {
+		return body
+	}
+
+	var req map[string]any
+	if err := json.Unmarshal(body, &req); err != nil {
+		return body
+	}
+
+	for from, to := range q.RenameParams {
+		if v, ok := req[from]; ok {
+			delete(req, from)
+			req[to] = v
+		}
+	}
+	for _, k := range q.DropParams {
+		delete(req, k)
+	}
+	for k, v := range q.DefaultParams {
+		if _, ok := req[k]; !ok {
+			req[k] = v
+		}
+	}
+	if t, ok := req["temperature"]; ok && q.MaxTemperature > 0 {
+		temp := ztype.ToFloat64(t)
+		if temp < q.MinTemperature {
+			temp = q.MinTemperature
+		} else if temp > q.MaxTemperature {
+			temp = q.MaxTemperature
+		}
+		req["temperature"] = temp
+	}
+
+	b, err := json.Marshal(req)
+	if err != nil {
+		return body
+	}
+	return b
+}
+
+// applyResponseQuirks 按差异配置映射响应字段
+func (p *CompatibleProvider) applyResponseQuirks(res *zjson.Res) *zjson.Res {
+	fields := p.options.quirks.ResponseFields
+	if len(fields) == 0 || res == nil {
+		return res
+	}
+
+	raw := res.Bytes()
+	changed := false
+	for target, source := range fields {
+		if zjson.GetBytes(raw, target).Exists() {
+			continue
+		}
+		v := zjson.GetBytes(raw, source)
+		if !v.Exists() {
+			continue
+		}
+		if b, err := zjson.SetRawBytes(raw, target, []byte(v.Raw())); err == nil {
+			raw, changed = b, true
+		}
+	}
+
+	if !changed {
+		return res
+	}
+	return zjson.ParseBytes(raw)
+}
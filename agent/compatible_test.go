@@ -0,0 +1,79 @@
+package agent_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sohaha/zlsgo"
+	"github.com/sohaha/zlsgo/zjson"
+	"github.com/sohaha/zlsgo/ztype"
+	"github.com/zlsgo/zllm/agent"
+	"github.com/zlsgo/zllm/agent/agenttest"
+	"github.com/zlsgo/zllm/message"
+)
+
+func TestCompatible(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	srv := agenttest.NewServer(agenttest.OpenAI).Push(agenttest.Text("hello")).Repeat()
+	defer srv.Close()
+
+	agent.RegisterQuirks("test-vendor", agent.Quirks{
+		Headers:        map[string]string{"X-Vendor": "zllm"},
+		DropParams:     []string{"tool_choice"},
+		RenameParams:   map[string]string{"max_tokens": "max_completion_tokens"},
+		DefaultParams:  map[string]any{"top_p": 0.9},
+		MaxTemperature: 1,
+		ResponseFields: map[string]string{
+			"choices.0.message.reasoning_content": "choices.0.message.content",
+		},
+	})
+	q, ok := agent.GetQuirks("test-vendor")
+	tt.EqualTrue(ok)
+	tt.Equal("zllm", q.Headers["X-Vendor"])
+
+	llm := agent.NewCompatible(func(o *agent.CompatibleOptions) {
+		o.Profile = "test-vendor"
+		o.Quirks = agent.Quirks{Headers: map[string]string{"X-Extra": "1"}}
+		o.BaseURL = srv.URL
+		o.APIKey = "key"
+		o.Model = "vendor-model"
+		o.Temperature = 1.8
+	})
+
+	msg := message.NewMessages()
+	_ = msg.AppendUser("hi")
+	body, err := llm.PrepareRequest(msg, func(m ztype.Map) ztype.Map {
+		m["tool_choice"] = "auto"
+		m["max_tokens"] = 128
+		return m
+	})
+	tt.NoError(err, true)
+
+	res, err := llm.Generate(context.Background(), body)
+	tt.NoError(err, true)
+	tt.Equal("hello", res.Get("choices.0.message.reasoning_content").String())
+	resp, err := llm.ParseResponse(res)
+	tt.NoError(err, true)
+	tt.Equal("hello", string(resp.Content))
+
+	req := srv.Requests()[0]
+	tt.Equal("/chat/completions", req.Path)
+	tt.Equal("Bearer key", req.Header.Get("Authorization"))
+	tt.Equal("zllm", req.Header.Get("X-Vendor"))
+	tt.Equal("1", req.Header.Get("X-Extra"))
+	sent := zjson.ParseBytes(req.Body)
+	tt.EqualTrue(!sent.Get("tool_choice").Exists())
+	tt.EqualTrue(!sent.Get("max_tokens").Exists())
+	tt.Equal(128, sent.Get("max_completion_tokens").Int())
+	tt.Equal(0.9, sent.Get("top_p").Float())
+	tt.Equal(1.0, sent.Get("temperature").Float())
+
+	done, err := llm.Stream(context.Background(), body, func(string, []byte) {})
+	tt.NoError(err, true)
+	res = <-done
+	tt.EqualTrue(res != nil)
+	tt.Equal("hello", res.Get("choices.0.message.reasoning_content").String())
+	tt.EqualTrue(srv.Requests()[1].Stream())
+	tt.EqualTrue(!zjson.GetBytes(srv.Requests()[1].Body, "tool_choice").Exists())
+}
@@ -18,6 +18,8 @@ type DeepseekOptions struct {
 	Stream      bool
 	MaxRetries  uint
 	OnMessage   func(string, []byte)
+	Sampling    Sampling  // 通用生成参数（max_tokens、top_p、stop 等）
+	RateLimit   RateLimit // 客户端限流（每分钟请求数与 token 数）
 }
 
 func (o *DeepseekOptions) getAPIKey() []string {
@@ -55,7 +57,18 @@ type DeepseekProvider struct {
 	keys     []string
 }
 
-var _ LLM = &DeepseekProvider{}
+var (
+	_ LLM            = &DeepseekProvider{}
+	_ CallOptionsLLM = &DeepseekProvider{}
+)
+
+// WithCallOptions 返回应用单次调用参数后的副本
+func (p *DeepseekProvider) WithCallOptions(co CallOptions) LLM {
+	c := *p
+	c.baseProvider = p.withCallOptions(co)
+	c.options.Model, c.options.Temperature = c.config.Model, c.config.Temperature
+	return &c
+}
 
 func NewDeepseek(opt ...func(*DeepseekOptions)) LLM {
 	o := zutil.Optional(DeepseekOptions{
@@ -74,6 +87,8 @@ func NewDeepseek(opt ...func(*DeepseekOptions)) LLM {
 		Temperature: o.Temperature,
 		MaxRetries:  o.MaxRetries,
 		Stream:      o.Stream,
+		Sampling:    o.Sampling,
+		RateLimit:   o.RateLimit,
 	}
 
 	return &DeepseekProvider{
@@ -103,7 +118,11 @@ func (p *DeepseekProvider) Stream(ctx context.Context, body []byte, callback fun
 }
 
 func (p *DeepseekProvider) PrepareRequest(messages *message.Messages, options ...func(ztype.Map) ztype.Map) ([]byte, error) {
-	return p.PrepareMessagesRequest(messages, options...)
+	sampling, err := p.samplingOption("deepseek", deepseekSampling)
+	if err != nil {
+		return nil, err
+	}
+	return p.PrepareMessagesRequest(messages, append([]func(ztype.Map) ztype.Map{sampling}, options...)...)
 }
 
 func (p *DeepseekProvider) ParseResponse(body *zjson.Res) (*Response, error) {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"sort"
 	"strings"
 
 	"github.com/sohaha/zlsgo/zhttp"
@@ -12,6 +13,7 @@ import (
 	"github.com/sohaha/zlsgo/zutil"
 	"github.com/zlsgo/zllm/message"
 	"github.com/zlsgo/zllm/runtime"
+	runtime_errors "github.com/zlsgo/zllm/runtime/errors"
 )
 
 // Gemini 特定配置选项
@@ -27,6 +29,53 @@ type GeminiOptions struct {
 	TopP        float64              // 核采样参数（0.0-1.0，控制多样性）
 	TopK        int                  // 核采样参数，选择前 K 个候选词
 	OnMessage   func(string, []byte) // 流式消息回调函数
+
+	ThinkingBudget  int  // 思考 token 预算（可选，-1 为动态预算）
+	IncludeThoughts bool // 返回思考摘要
+
+	Sampling  Sampling  // 通用生成参数，优先于 MaxTokens/TopP/TopK
+	RateLimit RateLimit // 客户端限流（每分钟请求数与 token 数）
+
+	// SafetySettings 各安全类别的拦截阈值，未设置的默认类别使用 BLOCK_NONE
+	//
+	//	o.SafetySettings = map[string]string{agent.GeminiHarmHarassment: agent.GeminiBlockMediumAndAbove}
+	SafetySettings map[string]string
+}
+
+// Gemini 安全类别
+const (
+	GeminiHarmHarassment       = "HARM_CATEGORY_HARASSMENT"
+	GeminiHarmHateSpeech       = "HARM_CATEGORY_HATE_SPEECH"
+	GeminiHarmSexuallyExplicit = "HARM_CATEGORY_SEXUALLY_EXPLICIT"
+	GeminiHarmDangerousContent = "HARM_CATEGORY_DANGEROUS_CONTENT"
+	GeminiHarmCivicIntegrity   = "HARM_CATEGORY_CIVIC_INTEGRITY"
+)
+
+// Gemini 安全拦截阈值
+const (
+	GeminiBlockNone           = "BLOCK_NONE"
+	GeminiBlockOnlyHigh       = "BLOCK_ONLY_HIGH"
+	GeminiBlockMediumAndAbove = "BLOCK_MEDIUM_AND_ABOVE"
+	GeminiBlockLowAndAbove    = "BLOCK_LOW_AND_ABOVE"
+	GeminiBlockOff            = "OFF"
+)
+
+// geminiDefaultSafetyCategories 默认发送安全设置的类别
+var geminiDefaultSafetyCategories = []string{
+	GeminiHarmHarassment,
+	GeminiHarmHateSpeech,
+	GeminiHarmSexuallyExplicit,
+	GeminiHarmDangerousContent,
+}
+
+// geminiBlockedFinishReasons 因安全策略中止生成的结束原因
+var geminiBlockedFinishReasons = map[string]bool{
+	"SAFETY":             true,
+	"RECITATION":         true,
+	"BLOCKLIST":          true,
+	"PROHIBITED_CONTENT": true,
+	"SPII":               true,
+	"IMAGE_SAFETY":       true,
 }
 
 // 实现 providerConfig 接口
@@ -69,7 +118,21 @@ type GeminiProvider struct {
 	keys     []string // 负载均衡的 API 密钥
 }
 
-var _ LLM = &GeminiProvider{}
+var (
+	_ LLM            = &GeminiProvider{}
+	_ CallOptionsLLM = &GeminiProvider{}
+)
+
+// WithCallOptions 返回应用单次调用参数后的副本
+func (p *GeminiProvider) WithCallOptions(co CallOptions) LLM {
+	c := *p
+	c.baseProvider = p.withCallOptions(co)
+	c.options.Model, c.options.Temperature = c.config.Model, c.config.Temperature
+	if co.Model != "" && co.Model != p.config.Model {
+		c.options.APIURL = geminiModelPath(c.options.APIURL, co.Model)
+	}
+	return &c
+}
 
 // NewGemini 创建新的 Gemini LLM 代理
 //
@@ -93,11 +156,11 @@ func NewGemini(opt ...func(*GeminiOptions)) LLM {
 	}, opt...)
 
 	if o.APIKey == "" {
-		runtime.Log("Warning: GEMINI_API_KEY not set, provider will be non-functional")
+		runtime.Warn(context.Background(), "GEMINI_API_KEY not set, provider will be non-functional", runtime.Attr(runtime.KeyProvider, "gemini"))
 	}
 
 	if o.Temperature < 0 || o.Temperature > 2 {
-		runtime.Log("Warning: Temperature should be between 0 and 2, clamping to valid range")
+		runtime.Warn(context.Background(), "Temperature should be between 0 and 2, clamping to valid range", runtime.Attr("temperature", o.Temperature))
 		if o.Temperature < 0 {
 			o.Temperature = 0
 		} else {
@@ -117,6 +180,8 @@ func NewGemini(opt ...func(*GeminiOptions)) LLM {
 		Temperature: o.Temperature,
 		MaxRetries:  o.MaxRetries,
 		Stream:      o.Stream,
+		Sampling:    o.Sampling,
+		RateLimit:   o.RateLimit,
 	})
 
 	return &GeminiProvider{
@@ -147,6 +212,11 @@ func (p *GeminiProvider) Stream(ctx context.Context, body []byte, callback func(
 
 // PrepareRequest 将消息转换为 Gemini API 格式
 func (p *GeminiProvider) PrepareRequest(messages *message.Messages, options ...func(ztype.Map) ztype.Map) ([]byte, error) {
+	sampling, err := p.samplingOption("gemini", geminiSampling)
+	if err != nil {
+		return nil, err
+	}
+
 	generationConfig := ztype.Map{
 		"temperature": p.GetConfig().Temperature,
 	}
@@ -160,10 +230,21 @@ func (p *GeminiProvider) PrepareRequest(messages *message.Messages, options ...f
 	if p.options.TopK > 0 {
 		generationConfig["topK"] = p.options.TopK
 	}
+	if p.options.ThinkingBudget != 0 || p.options.IncludeThoughts {
+		thinkingConfig := ztype.Map{}
+		if p.options.ThinkingBudget != 0 {
+			thinkingConfig["thinkingBudget"] = p.options.ThinkingBudget
+		}
+		if p.options.IncludeThoughts {
+			thinkingConfig["includeThoughts"] = true
+		}
+		generationConfig["thinkingConfig"] = thinkingConfig
+	}
 
 	history := messages.History(true)
 	contents := make([]ztype.Map, 0, len(history))
 
+	var sys []string
 	for i := range history {
 		role := history[i][0]
 		content := history[i][1]
@@ -174,18 +255,7 @@ func (p *GeminiProvider) PrepareRequest(messages *message.Messages, options ...f
 		case message.RoleAssistant:
 			geminiRole = "model"
 		case message.RoleSystem:
-			if len(contents) == 0 {
-				contents = append(contents, ztype.Map{
-					"role":  "user",
-					"parts": []ztype.Map{{"text": "System: " + content}},
-				})
-				continue
-			}
-			if len(contents) > 0 && contents[0]["role"] == "user" {
-				if parts, ok := contents[0]["parts"].([]ztype.Map); ok {
-					contents[0]["parts"] = append(parts, ztype.Map{"text": "\n\nSystem: " + content})
-				}
-			}
+			sys = append(sys, content)
 			continue
 		default:
 			geminiRole = "user"
@@ -200,26 +270,21 @@ func (p *GeminiProvider) PrepareRequest(messages *message.Messages, options ...f
 	request := ztype.Map{
 		"contents":         contents,
 		"generationConfig": generationConfig,
-		"safetySettings": []ztype.Map{
-			{
-				"category":  "HARM_CATEGORY_HARASSMENT",
-				"threshold": "BLOCK_NONE",
-			},
-			{
-				"category":  "HARM_CATEGORY_HATE_SPEECH",
-				"threshold": "BLOCK_NONE",
-			},
-			{
-				"category":  "HARM_CATEGORY_SEXUALLY_EXPLICIT",
-				"threshold": "BLOCK_NONE",
-			},
-			{
-				"category":  "HARM_CATEGORY_DANGEROUS_CONTENT",
-				"threshold": "BLOCK_NONE",
-			},
-		},
+		"safetySettings":   p.safetySettings(),
+	}
+
+	if len(sys) > 0 {
+		system := strings.Join(sys, "\n\n")
+		if len(contents) == 0 {
+			// 仅有系统提示时 contents 不能为空，作为用户输入发送
+			request["contents"] = []ztype.Map{{"role": "user", "parts": []ztype.Map{{"text": system}}}}
+		} else {
+			request["systemInstruction"] = ztype.Map{"parts": []ztype.Map{{"text": system}}}
+		}
 	}
 
+	request = sampling(request)
+
 	for _, v := range options {
 		request = v(request)
 	}
@@ -241,15 +306,136 @@ func (p *GeminiProvider) ParseResponse(body *zjson.Res) (*Response, error) {
 		return nil, errors.New(msg)
 	}
 
+	if reason := body.Get("promptFeedback.blockReason").String(); reason != "" {
+		ratings := geminiSafetyRatings(body.Get("promptFeedback.safetyRatings"))
+		return nil, runtime_errors.NewLLMErrorWithDetails(runtime_errors.ErrContentFiltered,
+			"gemini prompt blocked: "+reason, map[string]interface{}{"block_reason": reason, "safety_ratings": ratings})
+	}
+
 	candidates := body.Get("candidates")
 	if !candidates.Exists() || len(candidates.Array()) == 0 {
 		return nil, errors.New("no candidates in response")
 	}
 
-	content := candidates.Get("0.content.parts.0.text")
-	if !content.Exists() {
-		return &Response{Content: []byte{}}, nil
+	ratings := geminiSafetyRatings(candidates.Get("0.safetyRatings"))
+	if reason := candidates.Get("0.finishReason").String(); geminiBlockedFinishReasons[reason] {
+		return nil, runtime_errors.NewLLMErrorWithDetails(runtime_errors.ErrContentFiltered,
+			"gemini response blocked: "+reason, map[string]interface{}{"finish_reason": reason, "safety_ratings": ratings})
 	}
 
-	return &Response{Content: []byte(content.String())}, nil
+	thought, text := geminiParts(candidates.Get("0.content.parts"))
+	resp := &Response{Content: []byte(text), SafetyRatings: ratings}
+	if thought != "" {
+		resp.Reasoning = []byte(thought)
+	}
+	return resp, nil
+}
+
+// safetySettings 生成安全设置，默认类别为 BLOCK_NONE，可按类别覆盖
+func (p *GeminiProvider) safetySettings() []ztype.Map {
+	settings := make([]ztype.Map, 0, len(geminiDefaultSafetyCategories)+len(p.options.SafetySettings))
+	seen := make(map[string]bool, len(geminiDefaultSafetyCategories))
+	for _, category := range geminiDefaultSafetyCategories {
+		threshold := GeminiBlockNone
+		if t, ok := p.options.SafetySettings[category]; ok && t != "" {
+			threshold = t
+		}
+		settings = append(settings, ztype.Map{"category": category, "threshold": threshold})
+		seen[category] = true
+	}
+
+	extra := make([]string, 0, len(p.options.SafetySettings))
+	for category := range p.options.SafetySettings {
+		if !seen[category] {
+			extra = append(extra, category)
+		}
+	}
+	sort.Strings(extra)
+	for _, category := range extra {
+		settings = append(settings, ztype.Map{"category": category, "threshold": p.options.SafetySettings[category]})
+	}
+	return settings
+}
+
+// geminiSafetyRatings 解析安全评级
+func geminiSafetyRatings(ratings *zjson.Res) []SafetyRating {
+	var result []SafetyRating
+	ratings.ForEach(func(_, r *zjson.Res) bool {
+		result = append(result, SafetyRating{
+			Category:    r.Get("category").String(),
+			Probability: r.Get("probability").String(),
+			Blocked:     r.Get("blocked").Bool(),
+		})
+		return true
+	})
+	return result
+}
+
+// geminiModelPath 替换 API 路径中的模型名称
+func geminiModelPath(path, model string) string {
+	model = strings.ReplaceAll(model, ":", "/")
+	i := strings.Index(path, "/models/")
+	if i < 0 {
+		return "/v1beta/models/" + model + ":generateContent"
+	}
+	start := i + len("/models/")
+	end := strings.Index(path[start:], ":")
+	if end < 0 {
+		return path[:start] + model
+	}
+	return path[:start] + model + path[start+end:]
+}
+
+// geminiParts 拆分思考片段（thought 为 true）与正文片段
+func geminiParts(parts *zjson.Res) (thought, text string) {
+	var t, c strings.Builder
+	parts.ForEach(func(_, part *zjson.Res) bool {
+		if part.Get("thought").Bool() {
+			t.WriteString(part.Get("text").String())
+		} else {
+			c.WriteString(part.Get("text").String())
+		}
+		return true
+	})
+	return t.String(), c.String()
+}
+
+// Models 列出支持 generateContent 的模型，上下文窗口等信息以服务端返回为准
+func (p *GeminiProvider) Models(ctx context.Context) ([]ModelInfo, error) {
+	version := "/v1beta"
+	if i := strings.Index(p.options.APIURL, "/models/"); i > 0 {
+		version = p.options.APIURL[:i]
+	}
+
+	res, err := p.doRaw(ctx, &p.options, "GET", version+"/models?pageSize=1000")
+	if err != nil {
+		return nil, err
+	}
+
+	var models []ModelInfo
+	res.JSONs().Get("models").ForEach(func(_, v *zjson.Res) bool {
+		var generate, stream bool
+		v.Get("supportedGenerationMethods").ForEach(func(_, m *zjson.Res) bool {
+			generate = generate || m.String() == "generateContent"
+			stream = stream || m.String() == "streamGenerateContent"
+			return true
+		})
+		if !generate {
+			return true
+		}
+
+		info := modelInfo("gemini", strings.TrimPrefix(v.Get("name").String(), "models/"))
+		info.Known = true
+		info.Capabilities.Streaming = stream
+		info.Capabilities.Reasoning = info.Capabilities.Reasoning || v.Get("thinking").Bool()
+		if n := v.Get("inputTokenLimit").Int(); n > 0 {
+			info.Capabilities.ContextWindow = n
+		}
+		if n := v.Get("outputTokenLimit").Int(); n > 0 {
+			info.Capabilities.MaxOutputTokens = n
+		}
+		models = append(models, info)
+		return true
+	})
+	return models, nil
 }
@@ -5,11 +5,13 @@ import (
 	"testing"
 
 	"github.com/sohaha/zlsgo"
+	"github.com/sohaha/zlsgo/zjson"
 	"github.com/sohaha/zlsgo/ztype"
 	"github.com/sohaha/zlsgo/zutil"
 	"github.com/zlsgo/zllm/agent"
 	"github.com/zlsgo/zllm/message"
 	"github.com/zlsgo/zllm/runtime"
+	runtime_errors "github.com/zlsgo/zllm/runtime/errors"
 )
 
 var gemini = agent.NewGemini(func(oa *agent.GeminiOptions) {
@@ -59,3 +61,57 @@ func TestNewGemini(t *testing.T) {
 		tt.Log(string(parse.Content))
 	})
 }
+
+func TestGeminiSafety(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	llm := agent.NewGemini(func(o *agent.GeminiOptions) {
+		o.SafetySettings = map[string]string{
+			agent.GeminiHarmHarassment:     agent.GeminiBlockMediumAndAbove,
+			agent.GeminiHarmCivicIntegrity: agent.GeminiBlockOnlyHigh,
+		}
+	})
+
+	msg := message.NewMessages()
+	_ = msg.Append(message.Message{Role: message.RoleSystem, Content: "be brief"})
+	_ = msg.AppendUser("hi")
+	body, err := llm.PrepareRequest(msg)
+	tt.NoError(err, true)
+
+	req := zjson.ParseBytes(body)
+	tt.Equal("be brief", req.Get("systemInstruction.parts.0.text").String())
+	tt.Equal(1, len(req.Get("contents").Array()))
+	tt.Equal("user", req.Get("contents.0.role").String())
+	tt.Equal(5, len(req.Get("safetySettings").Array()))
+	tt.Equal(agent.GeminiHarmHarassment, req.Get("safetySettings.0.category").String())
+	tt.Equal(agent.GeminiBlockMediumAndAbove, req.Get("safetySettings.0.threshold").String())
+	tt.Equal(agent.GeminiBlockNone, req.Get("safetySettings.1.threshold").String())
+	tt.Equal(agent.GeminiHarmCivicIntegrity, req.Get("safetySettings.4.category").String())
+
+	resp, err := llm.ParseResponse(zjson.Parse(`{"candidates":[{"content":{"parts":[{"text":"ok"}]},"finishReason":"STOP",
+		"safetyRatings":[{"category":"HARM_CATEGORY_HARASSMENT","probability":"NEGLIGIBLE"}]}]}`))
+	tt.NoError(err, true)
+	tt.Equal("ok", string(resp.Content))
+	tt.Equal(1, len(resp.SafetyRatings))
+	tt.Equal("NEGLIGIBLE", resp.SafetyRatings[0].Probability)
+
+	_, err = llm.ParseResponse(zjson.Parse(`{"promptFeedback":{"blockReason":"SAFETY","safetyRatings":[{"category":"HARM_CATEGORY_HARASSMENT","probability":"HIGH","blocked":true}]}}`))
+	llmErr, ok := err.(runtime_errors.LLMError)
+	tt.EqualTrue(ok)
+	tt.Equal(runtime_errors.ErrContentFiltered, llmErr.Code)
+	tt.Equal("SAFETY", llmErr.Details["block_reason"])
+
+	_, err = llm.ParseResponse(zjson.Parse(`{"candidates":[{"content":{"parts":[]},"finishReason":"SAFETY",
+		"safetyRatings":[{"category":"HARM_CATEGORY_HARASSMENT","probability":"HIGH","blocked":true}]}]}`))
+	llmErr, ok = err.(runtime_errors.LLMError)
+	tt.EqualTrue(ok)
+	tt.Equal(runtime_errors.ErrContentFiltered, llmErr.Code)
+	tt.EqualTrue(llmErr.Details["safety_ratings"].([]agent.SafetyRating)[0].Blocked)
+
+	only := message.NewMessages()
+	_ = only.Append(message.Message{Role: message.RoleSystem, Content: "system only"})
+	body, err = llm.PrepareRequest(only)
+	tt.NoError(err, true)
+	tt.Equal("system only", zjson.GetBytes(body, "contents.0.parts.0.text").String())
+	tt.EqualTrue(!zjson.GetBytes(body, "systemInstruction").Exists())
+}
@@ -0,0 +1,316 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sohaha/zlsgo/zjson"
+	"github.com/sohaha/zlsgo/ztype"
+	"github.com/zlsgo/zllm/message"
+	"github.com/zlsgo/zllm/runtime"
+	runtime_errors "github.com/zlsgo/zllm/runtime/errors"
+)
+
+// Interceptor 调用拦截器，按需设置钩子，未设置的钩子会被跳过
+// 同一钩子按注册顺序执行：先全局拦截器，再 Intercept 传入的拦截器；流式调用的钩子可能在其它 goroutine 中执行
+type Interceptor struct {
+	// BeforeRequest 发送请求前调用，可替换 call.Body（如脱敏），返回错误则中止本次调用
+	BeforeRequest func(ctx context.Context, call *Call) error
+	// AfterResponse 收到完整响应后调用
+	AfterResponse func(ctx context.Context, call *Call, res *zjson.Res)
+	// OnChunk 流式调用收到分片时调用
+	OnChunk func(ctx context.Context, call *Call, chunk string)
+	// OnToolCall 响应中包含工具调用时，对每个工具调用一次
+	OnToolCall func(ctx context.Context, call *Call, tool Tool)
+	// OnRetry 调用方重试前调用，attempt 从 1 开始
+	OnRetry func(ctx context.Context, call *Call, attempt int, err error)
+	// OnError 请求、流式响应或响应解析失败时调用
+	OnError func(ctx context.Context, call *Call, err error)
+}
+
+// Call 一次 LLM 调用的信息
+type Call struct {
+	Start    time.Time // 开始时间
+	Provider string    // 提供商名称，未知时为空
+	Model    string    // 模型名称，未知时为空
+	Body     []byte    // 请求体
+	Stream   bool      // 是否为流式调用
+
+	firstChunk bool
+}
+
+// Duration 调用开始至今的耗时
+func (c *Call) Duration() time.Duration {
+	return time.Since(c.Start)
+}
+
+var (
+	globalInterceptors   []*Interceptor
+	globalInterceptorsMu sync.RWMutex
+)
+
+// RegisterInterceptor 注册全局拦截器，作用于所有经 Intercept 包装的 LLM（zllm.CompleteLLM 会自动包装），
+// 返回的函数用于注销
+func RegisterInterceptor(i Interceptor) (unregister func()) {
+	p := &i
+	globalInterceptorsMu.Lock()
+	globalInterceptors = append(globalInterceptors, p)
+	globalInterceptorsMu.Unlock()
+
+	return func() {
+		globalInterceptorsMu.Lock()
+		defer globalInterceptorsMu.Unlock()
+
+		for n := range globalInterceptors {
+			if globalInterceptors[n] == p {
+				globalInterceptors = append(globalInterceptors[:n:n], globalInterceptors[n+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// InterceptProvider 在 LLM 外层执行拦截器链的包装器
+type InterceptProvider struct {
+	llm          LLM
+	interceptors []Interceptor
+}
+
+var (
+	_ LLM             = &InterceptProvider{}
+	_ CallOptionsLLM  = &InterceptProvider{}
+	_ ResolverLLM     = &InterceptProvider{}
+	_ CapabilitiesLLM = &InterceptProvider{}
+	_ TokenCounter    = &InterceptProvider{}
+)
+
+// Intercept 使用拦截器包装 LLM，已包装的 LLM 会追加拦截器而不重复包装
+//
+//	llm := agent.Intercept(agent.NewOpenAI(), agent.Interceptor{
+//		AfterResponse: func(ctx context.Context, call *agent.Call, res *zjson.Res) {
+//			log.Println(call.Model, call.Duration())
+//		},
+//	})
+func Intercept(llm LLM, interceptors ...Interceptor) LLM {
+	if p, ok := llm.(*InterceptProvider); ok {
+		if len(interceptors) == 0 {
+			return p
+		}
+		c := *p
+		c.interceptors = append(append([]Interceptor(nil), p.interceptors...), interceptors...)
+		return &c
+	}
+	return &InterceptProvider{llm: llm, interceptors: interceptors}
+}
+
+// Unwrap 返回被包装的 LLM
+func (p *InterceptProvider) Unwrap() LLM {
+	return p.llm
+}
+
+// WithCallOptions 对底层代理应用单次调用参数
+func (p *InterceptProvider) WithCallOptions(co CallOptions) LLM {
+	c := *p
+	c.llm = ApplyCallOptions(p.llm, co)
+	return &c
+}
+
+// Resolve 解析被包装 LLM 实际使用的 LLM，并保留拦截器
+func (p *InterceptProvider) Resolve(ctx context.Context, messages *message.Messages, options ...func(ztype.Map) ztype.Map) (LLM, error) {
+	llm, err := Resolve(ctx, p.llm, messages, options...)
+	if err != nil || llm == p.llm {
+		return p, err
+	}
+	c := *p
+	c.llm = llm
+	return &c, nil
+}
+
+// Capabilities 返回被包装 LLM 的模型能力
+func (p *InterceptProvider) Capabilities() (Capabilities, bool) {
+	return ModelCapabilities(p.llm)
+}
+
+// CountTokens 使用被包装 LLM 的 token 计数，不支持时按通用规则估算
+func (p *InterceptProvider) CountTokens(text string) int {
+	if c, ok := p.llm.(TokenCounter); ok {
+		return c.CountTokens(text)
+	}
+	return message.EstimateTokens(text)
+}
+
+// PrepareRequest 由被包装的 LLM 构建请求
+func (p *InterceptProvider) PrepareRequest(messages *message.Messages, options ...func(ztype.Map) ztype.Map) ([]byte, error) {
+	return p.llm.PrepareRequest(messages, options...)
+}
+
+// ParseResponse 由被包装的 LLM 解析响应
+func (p *InterceptProvider) ParseResponse(body *zjson.Res) (*Response, error) {
+	return p.llm.ParseResponse(body)
+}
+
+// Generate 执行拦截器链并发送请求
+func (p *InterceptProvider) Generate(ctx context.Context, data []byte) (*zjson.Res, error) {
+	chain := p.chain()
+	call := p.newCall(data, false)
+	if err := chain.before(ctx, call); err != nil {
+		return nil, err
+	}
+
+	res, err := p.llm.Generate(ctx, call.Body)
+	if err != nil {
+		chain.error(ctx, call, err)
+		return nil, err
+	}
+	p.after(ctx, chain, call, res)
+	return res, nil
+}
+
+// Stream 执行拦截器链并发送流式请求，流结束但没有响应时触发 OnError
+func (p *InterceptProvider) Stream(ctx context.Context, data []byte, callback func(string, []byte)) (<-chan *zjson.Res, error) {
+	chain := p.chain()
+	call := p.newCall(data, true)
+	if err := chain.before(ctx, call); err != nil {
+		return nil, err
+	}
+
+	// 未传回调时同样包装，保证 OnChunk 与首 token 耗时统计生效
+	wrapped := func(chunk string, raw []byte) {
+		chain.chunk(ctx, call, chunk)
+		if callback != nil {
+			callback(chunk, raw)
+		}
+	}
+	inner, err := p.llm.Stream(ctx, call.Body, wrapped)
+	if err != nil {
+		chain.error(ctx, call, err)
+		return nil, err
+	}
+
+	done := make(chan *zjson.Res, 1)
+	go func() {
+		defer close(done)
+
+		res, ok := <-inner
+		if !ok || res == nil {
+			err := ctx.Err()
+			if err == nil {
+				err = runtime_errors.NewLLMError(runtime_errors.ErrInvalidResponse, "stream ended without response")
+			}
+			chain.error(ctx, call, err)
+			return
+		}
+		p.after(ctx, chain, call, res)
+		done <- res
+	}()
+	return done, nil
+}
+
+// NotifyRetry 通知 LLM 的拦截器即将重试，未经 Intercept 包装时忽略
+func NotifyRetry(ctx context.Context, llm LLM, body []byte, attempt int, err error) {
+	if p, ok := llm.(*InterceptProvider); ok {
+		p.chain().retry(ctx, p.newCall(body, false), attempt, err)
+	}
+}
+
+// after 触发响应与工具调用钩子
+func (p *InterceptProvider) after(ctx context.Context, chain interceptorChain, call *Call, res *zjson.Res) {
+	chain.after(ctx, call, res)
+	if !chain.has(func(i *Interceptor) bool { return i.OnToolCall != nil }) {
+		return
+	}
+	if resp, err := p.llm.ParseResponse(res); err == nil {
+		for _, tool := range resp.Tools {
+			chain.toolCall(ctx, call, tool)
+		}
+	}
+}
+
+// newCall 创建调用信息
+func (p *InterceptProvider) newCall(body []byte, stream bool) *Call {
+	provider, model := DescribeModel(p.llm)
+	return &Call{Start: time.Now(), Provider: provider, Model: model, Body: body, Stream: stream}
+}
+
+// chain 返回全局拦截器、指标统计（已启用时）与本实例拦截器组成的调用链
+func (p *InterceptProvider) chain() interceptorChain {
+	globalInterceptorsMu.RLock()
+	chain := make(interceptorChain, 0, len(globalInterceptors)+len(p.interceptors)+1)
+	chain = append(chain, globalInterceptors...)
+	globalInterceptorsMu.RUnlock()
+
+	if runtime.Metering() {
+		chain = append(chain, metricsInterceptor)
+	}
+
+	for i := range p.interceptors {
+		chain = append(chain, &p.interceptors[i])
+	}
+	return chain
+}
+
+// interceptorChain 拦截器调用链
+type interceptorChain []*Interceptor
+
+func (c interceptorChain) has(fn func(*Interceptor) bool) bool {
+	for _, i := range c {
+		if fn(i) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c interceptorChain) before(ctx context.Context, call *Call) error {
+	for _, i := range c {
+		if i.BeforeRequest == nil {
+			continue
+		}
+		if err := i.BeforeRequest(ctx, call); err != nil {
+			c.error(ctx, call, err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (c interceptorChain) after(ctx context.Context, call *Call, res *zjson.Res) {
+	for _, i := range c {
+		if i.AfterResponse != nil {
+			i.AfterResponse(ctx, call, res)
+		}
+	}
+}
+
+func (c interceptorChain) chunk(ctx context.Context, call *Call, chunk string) {
+	for _, i := range c {
+		if i.OnChunk != nil {
+			i.OnChunk(ctx, call, chunk)
+		}
+	}
+}
+
+func (c interceptorChain) toolCall(ctx context.Context, call *Call, tool Tool) {
+	for _, i := range c {
+		if i.OnToolCall != nil {
+			i.OnToolCall(ctx, call, tool)
+		}
+	}
+}
+
+func (c interceptorChain) retry(ctx context.Context, call *Call, attempt int, err error) {
+	for _, i := range c {
+		if i.OnRetry != nil {
+			i.OnRetry(ctx, call, attempt, err)
+		}
+	}
+}
+
+func (c interceptorChain) error(ctx context.Context, call *Call, err error) {
+	for _, i := range c {
+		if i.OnError != nil {
+			i.OnError(ctx, call, err)
+		}
+	}
+}
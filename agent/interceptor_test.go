@@ -0,0 +1,133 @@
+package agent_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sohaha/zlsgo"
+	"github.com/sohaha/zlsgo/zjson"
+	"github.com/zlsgo/zllm/agent"
+	"github.com/zlsgo/zllm/agent/agenttest"
+)
+
+func TestInterceptor(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	var (
+		mu     sync.Mutex
+		events []string
+	)
+	record := func(e string) {
+		mu.Lock()
+		events = append(events, e)
+		mu.Unlock()
+	}
+
+	var global int
+	unregister := agent.RegisterInterceptor(agent.Interceptor{
+		AfterResponse: func(context.Context, *agent.Call, *zjson.Res) { global++ },
+	})
+
+	mock := agenttest.NewMock(
+		agenttest.ToolCall("weather", `{"city":"北京"}`),
+		agenttest.Text("hi"),
+		agenttest.Chunks(time.Millisecond, "a", "b"),
+		agenttest.Error(errors.New("boom")),
+	)
+	llm := agent.Intercept(mock, agent.Interceptor{
+		BeforeRequest: func(_ context.Context, call *agent.Call) error {
+			if bytes.Contains(call.Body, []byte("forbidden")) {
+				return errors.New("blocked")
+			}
+			call.Body = bytes.ReplaceAll(call.Body, []byte("secret"), []byte("***"))
+			record("before")
+			return nil
+		},
+		AfterResponse: func(_ context.Context, call *agent.Call, _ *zjson.Res) {
+			record("after")
+		},
+		OnChunk:    func(_ context.Context, _ *agent.Call, chunk string) { record("chunk:" + chunk) },
+		OnToolCall: func(_ context.Context, _ *agent.Call, tool agent.Tool) { record("tool:" + tool.Name) },
+		OnRetry:    func(_ context.Context, _ *agent.Call, attempt int, err error) { record("retry:" + err.Error()) },
+		OnError:    func(_ context.Context, _ *agent.Call, err error) { record("error:" + err.Error()) },
+	})
+
+	ctx := context.Background()
+	_, err := llm.Generate(ctx, []byte("my secret"))
+	tt.NoError(err, true)
+	tt.EqualTrue(strings.Contains(string(mock.Requests()[0]), "***"))
+
+	res, err := llm.Generate(ctx, []byte("hello"))
+	tt.NoError(err, true)
+	resp, err := llm.ParseResponse(res)
+	tt.NoError(err, true)
+	tt.Equal("hi", string(resp.Content))
+
+	done, err := llm.Stream(ctx, []byte("hello"), func(string, []byte) {})
+	tt.NoError(err, true)
+	<-done
+
+	_, err = llm.Generate(ctx, []byte("hello"))
+	tt.EqualTrue(err != nil)
+
+	_, err = llm.Generate(ctx, []byte("forbidden"))
+	tt.Equal("blocked", err.Error())
+	tt.Equal(4, mock.Calls())
+
+	agent.NotifyRetry(ctx, llm, nil, 1, errors.New("timeout"))
+
+	mu.Lock()
+	tt.Equal([]string{
+		"before", "after", "tool:weather",
+		"before", "after",
+		"before", "chunk:a", "chunk:b", "after",
+		"before", "error:boom",
+		"error:blocked",
+		"retry:timeout",
+	}, events)
+	mu.Unlock()
+	tt.Equal(3, global)
+
+	unregister()
+	_, _ = llm.Generate(ctx, []byte("hello"))
+	tt.Equal(3, global)
+
+	openai := agent.Intercept(agent.NewOpenAI(func(o *agent.OpenAIOptions) { o.Model = "gpt-4o" }))
+	caps, ok := agent.ModelCapabilities(agent.ApplyCallOptions(openai, agent.CallOptions{Model: "gpt-4o-mini"}))
+	tt.EqualTrue(ok && caps.Tools)
+	tt.Equal(openai, agent.Intercept(openai))
+}
+
+func TestInterceptStreamNilCallback(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	var (
+		mu     sync.Mutex
+		chunks []string
+		stream bool
+	)
+	llm := agent.Intercept(agenttest.NewMock(agenttest.Chunks(time.Millisecond, "a", "b")), agent.Interceptor{
+		BeforeRequest: func(_ context.Context, call *agent.Call) error {
+			stream = call.Stream
+			return nil
+		},
+		OnChunk: func(_ context.Context, _ *agent.Call, chunk string) {
+			mu.Lock()
+			chunks = append(chunks, chunk)
+			mu.Unlock()
+		},
+	})
+
+	done, err := llm.Stream(context.Background(), []byte("hello"), nil)
+	tt.NoError(err, true)
+	tt.EqualTrue(<-done != nil)
+	tt.EqualTrue(stream)
+	mu.Lock()
+	tt.Equal([]string{"a", "b"}, chunks)
+	mu.Unlock()
+}
@@ -0,0 +1,91 @@
+package agent_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sohaha/zlsgo"
+	"github.com/zlsgo/zllm/agent"
+	"github.com/zlsgo/zllm/agent/agenttest"
+	"github.com/zlsgo/zllm/runtime"
+)
+
+type logRecord struct {
+	attrs map[string]any
+	msg   string
+	level runtime.Level
+}
+
+type captureLogger struct {
+	records []logRecord
+	mu      sync.Mutex
+}
+
+func (c *captureLogger) Enabled(context.Context, runtime.Level) bool { return true }
+
+func (c *captureLogger) Log(_ context.Context, level runtime.Level, msg string, attrs ...runtime.Attribute) {
+	r := logRecord{level: level, msg: msg, attrs: map[string]any{}}
+	for _, a := range attrs {
+		r.attrs[a.Key] = a.Value
+	}
+	c.mu.Lock()
+	c.records = append(c.records, r)
+	c.mu.Unlock()
+}
+
+func (c *captureLogger) find(msg string) (logRecord, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, r := range c.records {
+		if r.msg == msg {
+			return r, true
+		}
+	}
+	return logRecord{}, false
+}
+
+func TestLog(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	l := &captureLogger{}
+	runtime.SetLogger(l)
+	defer runtime.SetLogger(nil)
+
+	srv := agenttest.NewServer(agenttest.OpenAI, agenttest.Text("hi"), agenttest.HTTPError(401, "invalid key"))
+	defer srv.Close()
+
+	llm := agent.NewOpenAI(func(o *agent.OpenAIOptions) {
+		o.BaseURL = srv.URL + "/v1"
+		o.APIKey = "sk-secret-key"
+		o.Model = "gpt-4o"
+	})
+
+	_, err := llm.Generate(context.Background(), []byte(`{"model":"gpt-4o","messages":[{"role":"user","content":"hello"}]}`))
+	tt.NoError(err, true)
+
+	req, ok := l.find("LLM request")
+	tt.EqualTrue(ok)
+	tt.Equal(runtime.LevelDebug, req.level)
+	tt.Equal("openai", req.attrs[runtime.KeyProvider])
+	tt.Equal("gpt-4o", req.attrs[runtime.KeyModel])
+	tt.Equal(runtime.Redacted, req.attrs[runtime.KeyHeaders].(map[string]string)["Authorization"])
+	tt.EqualTrue(strings.Contains(req.attrs[runtime.KeyPrompt].(string), "hello"))
+
+	resp, ok := l.find("LLM response")
+	tt.EqualTrue(ok)
+	tt.Equal("chatcmpl-mock", resp.attrs[runtime.KeyRequestID])
+	tt.Equal(200, resp.attrs[runtime.KeyStatus])
+	tt.EqualTrue(resp.attrs[runtime.KeyLatency] != "")
+
+	_, err = llm.Generate(context.Background(), []byte(`{"model":"gpt-4o"}`))
+	tt.EqualTrue(err != nil)
+
+	failed, ok := l.find("LLM request failed")
+	tt.EqualTrue(ok)
+	tt.Equal(runtime.LevelWarn, failed.level)
+	tt.Equal("unauthorized", failed.attrs[runtime.KeyErrorCode])
+	tt.Equal(401, failed.attrs[runtime.KeyStatus])
+}
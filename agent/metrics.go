@@ -0,0 +1,48 @@
+package agent
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/sohaha/zlsgo/zjson"
+	"github.com/zlsgo/zllm/runtime"
+)
+
+// metricsInterceptor 启用指标（runtime.SetMetrics）后自动加入拦截器链，统计经 Intercept 包装的调用
+var metricsInterceptor = &Interceptor{
+	AfterResponse: func(_ context.Context, call *Call, res *zjson.Res) {
+		recordCall(call, "success")
+
+		if v := firstExists(res, inputTokenPaths...); v != nil {
+			runtime.AddMetric(runtime.MetricTokens, v.Float(), call.Provider, call.Model, "input")
+		}
+		if v := firstExists(res, outputTokenPaths...); v != nil {
+			runtime.AddMetric(runtime.MetricTokens, v.Float(), call.Provider, call.Model, "output")
+		}
+		if v := firstExists(res, cachedTokenPaths...); v != nil && v.Int() > 0 {
+			runtime.AddMetric(runtime.MetricTokens, v.Float(), call.Provider, call.Model, "cached")
+			runtime.AddMetric(runtime.MetricCacheHits, 1, call.Provider, call.Model)
+		}
+	},
+	OnChunk: func(_ context.Context, call *Call, _ string) {
+		if call.firstChunk {
+			return
+		}
+		call.firstChunk = true
+		runtime.ObserveMetric(runtime.MetricTimeToFirstToken, call.Duration().Seconds(), call.Provider, call.Model)
+	},
+	OnToolCall: func(_ context.Context, call *Call, tool Tool) {
+		runtime.AddMetric(runtime.MetricToolCalls, 1, call.Provider, call.Model, tool.Name)
+	},
+	OnError: func(_ context.Context, call *Call, err error) {
+		recordCall(call, "error")
+		runtime.AddMetric(runtime.MetricErrors, 1, call.Provider, call.Model, ErrorCode(err).String())
+	},
+}
+
+// recordCall 记录请求次数与耗时
+func recordCall(call *Call, status string) {
+	stream := strconv.FormatBool(call.Stream)
+	runtime.AddMetric(runtime.MetricRequests, 1, call.Provider, call.Model, stream, status)
+	runtime.ObserveMetric(runtime.MetricRequestDuration, call.Duration().Seconds(), call.Provider, call.Model, stream)
+}
@@ -0,0 +1,68 @@
+package agent_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sohaha/zlsgo"
+	"github.com/zlsgo/zllm/agent"
+	"github.com/zlsgo/zllm/agent/agenttest"
+	"github.com/zlsgo/zllm/runtime"
+	runtime_errors "github.com/zlsgo/zllm/runtime/errors"
+)
+
+func TestMetrics(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	metrics := runtime.NewMemoryMetrics()
+	runtime.SetMetrics(metrics)
+	defer runtime.SetMetrics(nil)
+
+	srv := agenttest.NewServer(agenttest.OpenAI,
+		agenttest.Text("hi").WithUsage(10, 5, 8),
+		agenttest.ToolCall("weather", `{"city":"北京"}`).WithUsage(3, 2, 0),
+		agenttest.Chunks(20*time.Millisecond, "a", "b"),
+		agenttest.HTTPError(429, "slow down"),
+	)
+	defer srv.Close()
+
+	llm := agent.Intercept(agent.NewOpenAI(func(o *agent.OpenAIOptions) {
+		o.BaseURL = srv.URL + "/v1"
+		o.APIKey = "sk-test"
+		o.Model = "gpt-4o"
+	}))
+
+	ctx := context.Background()
+	_, err := llm.Generate(ctx, []byte(`{"model":"gpt-4o"}`))
+	tt.NoError(err, true)
+	_, err = llm.Generate(ctx, []byte(`{"model":"gpt-4o"}`))
+	tt.NoError(err, true)
+
+	done, err := llm.Stream(ctx, []byte(`{"model":"gpt-4o","stream":true}`), func(string, []byte) {})
+	tt.NoError(err, true)
+	<-done
+
+	_, err = llm.Generate(ctx, []byte(`{"model":"gpt-4o"}`))
+	tt.EqualTrue(err != nil)
+
+	tt.Equal(2.0, metrics.Value(runtime.MetricRequests, "openai", "gpt-4o", "false", "success"))
+	tt.Equal(1.0, metrics.Value(runtime.MetricRequests, "openai", "gpt-4o", "true", "success"))
+	tt.Equal(1.0, metrics.Value(runtime.MetricRequests, "openai", "gpt-4o", "false", "error"))
+	tt.Equal(3, metrics.Count(runtime.MetricRequestDuration, "openai", "gpt-4o", "false"))
+
+	tt.Equal(13.0, metrics.Value(runtime.MetricTokens, "openai", "gpt-4o", "input"))
+	tt.Equal(7.0, metrics.Value(runtime.MetricTokens, "openai", "gpt-4o", "output"))
+	tt.Equal(8.0, metrics.Value(runtime.MetricTokens, "openai", "gpt-4o", "cached"))
+	tt.Equal(1.0, metrics.Value(runtime.MetricCacheHits, "openai", "gpt-4o"))
+	tt.Equal(1.0, metrics.Value(runtime.MetricToolCalls, "openai", "gpt-4o", "weather"))
+	tt.Equal(1.0, metrics.Value(runtime.MetricErrors, "openai", "gpt-4o", "rate_limited"))
+
+	tt.Equal(1, metrics.Count(runtime.MetricTimeToFirstToken, "openai", "gpt-4o"))
+	ttft := metrics.Value(runtime.MetricTimeToFirstToken, "openai", "gpt-4o")
+	tt.EqualTrue(ttft >= 0.015)
+
+	tt.Equal(runtime_errors.ErrTimeout, agent.ErrorCode(context.DeadlineExceeded))
+	tt.Equal(runtime_errors.ErrUnknown, agent.ErrorCode(errors.New("boom")))
+}
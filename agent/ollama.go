@@ -1,13 +1,20 @@
 package agent
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 
 	"github.com/sohaha/zlsgo/zhttp"
 	"github.com/sohaha/zlsgo/zjson"
+	"github.com/sohaha/zlsgo/zstring"
 	"github.com/sohaha/zlsgo/ztype"
 	"github.com/sohaha/zlsgo/zutil"
 	"github.com/zlsgo/zllm/message"
+	"github.com/zlsgo/zllm/runtime"
+	runtime_errors "github.com/zlsgo/zllm/runtime/errors"
 )
 
 type OllamaOptions struct {
@@ -18,6 +25,12 @@ type OllamaOptions struct {
 	Stream      bool
 	MaxRetries  uint
 	OnMessage   func(string, []byte)
+	Sampling    Sampling       // 通用生成参数，映射为 options.num_predict 等
+	RateLimit   RateLimit      // 客户端限流（每分钟请求数与 token 数）
+	KeepAlive   string         // 模型在内存中的保留时长，如 "5m"、"-1m"（常驻）、"0"（立即卸载）
+	NumCtx      int            // 上下文窗口大小，映射为 options.num_ctx
+	Options     map[string]any // 原生 options 参数（num_ctx、num_predict、seed、stop 等），优先级最高
+	Format      any            // 输出格式，"json" 或 JSON Schema
 }
 
 func (o *OllamaOptions) getAPIKey() []string {
@@ -57,7 +70,18 @@ type OllamaProvider struct {
 	endpoint string
 }
 
-var _ LLM = &OllamaProvider{}
+var (
+	_ LLM            = &OllamaProvider{}
+	_ CallOptionsLLM = &OllamaProvider{}
+)
+
+// WithCallOptions 返回应用单次调用参数后的副本
+func (p *OllamaProvider) WithCallOptions(co CallOptions) LLM {
+	c := *p
+	c.baseProvider = p.withCallOptions(co)
+	c.options.Model, c.options.Temperature = c.config.Model, c.config.Temperature
+	return &c
+}
 
 func NewOllama(opt ...func(*OllamaOptions)) LLM {
 	o := zutil.Optional(OllamaOptions{
@@ -75,6 +99,8 @@ func NewOllama(opt ...func(*OllamaOptions)) LLM {
 		Temperature: o.Temperature,
 		MaxRetries:  o.MaxRetries,
 		Stream:      o.Stream,
+		Sampling:    o.Sampling,
+		RateLimit:   o.RateLimit,
 	}
 
 	return &OllamaProvider{
@@ -99,13 +125,192 @@ func (p *OllamaProvider) Stream(ctx context.Context, body []byte, callback func(
 	if err != nil {
 		return nil, err
 	}
-	return p.baseProvider.streamWithConfig(ctx, &p.options, body, callback)
+	if callback == nil {
+		return p.baseProvider.streamWithConfig(ctx, &p.options, body, nil)
+	}
+
+	body, _ = zjson.SetBytes(body, "stream", true)
+
+	done := make(chan *zjson.Res, 1)
+	go func() {
+		defer close(done)
+		defer func() {
+			if r := recover(); r != nil {
+				runtime.Error(ctx, "Ollama stream goroutine panic", runtime.Attr("panic", r))
+			}
+		}()
+
+		streamCtx, cancel := context.WithTimeout(ctx, p.config.StreamTimeout)
+		defer cancel()
+
+		streamCtx, call := beginChat(streamCtx, "ollama", p.config.Model, p.options.BaseURL, nil, body, true)
+		json, err := p.streamChat(streamCtx, body, callback)
+		finishChat(streamCtx, call, json, 0, err)
+		if err != nil {
+			return
+		}
+
+		select {
+		case done <- json:
+		case <-ctx.Done():
+			runtime.Debug(ctx, "Ollama stream context canceled")
+		}
+	}()
+
+	return done, nil
+}
+
+// streamChat 逐行读取 /api/chat 的 NDJSON 流，合并为一个完整响应
+func (p *OllamaProvider) streamChat(ctx context.Context, body []byte, callback func(string, []byte)) (*zjson.Res, error) {
+	res, err := p.doRaw(ctx, &p.options, "POST", p.options.getAPIPath(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		last        []byte
+		tools       []json.RawMessage
+		content     = zstring.Buffer()
+		reasoning   = zstring.Buffer()
+		onReasoning = getReasoningCallback(ctx)
+	)
+	err = readNDJSON(res, func(line []byte) error {
+		chunk := zjson.ParseBytes(line)
+		msg := chunk.Get("message")
+		if thinking := msg.Get("thinking").String(); thinking != "" {
+			reasoning.WriteString(thinking)
+			if onReasoning != nil {
+				onReasoning(thinking, line)
+			}
+		}
+		if text := msg.Get("content").String(); text != "" {
+			content.WriteString(text)
+			if p.options.OnMessage != nil {
+				p.options.OnMessage(text, line)
+			}
+			callback(text, line)
+		}
+		msg.Get("tool_calls").ForEach(func(_, v *zjson.Res) bool {
+			tools = append(tools, json.RawMessage(v.Raw()))
+			return true
+		})
+		last = append(last[:0], line...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if last == nil {
+		return nil, errors.New("stream completed but no data received")
+	}
+
+	last, _ = zjson.SetBytes(last, "message.role", "assistant")
+	last, _ = zjson.SetBytes(last, "message.content", content.String())
+	if reasoning.Len() > 0 {
+		last, _ = zjson.SetBytes(last, "message.thinking", reasoning.String())
+	}
+	if len(tools) > 0 {
+		last, _ = zjson.SetBytes(last, "message.tool_calls", tools)
+	}
+	last, _ = zjson.SetBytes(last, "done", true)
+	return zjson.ParseBytes(last), nil
 }
 
 func (p *OllamaProvider) PrepareRequest(messages *message.Messages, options ...func(ztype.Map) ztype.Map) ([]byte, error) {
-	return p.PrepareMessagesRequest(messages, options...)
+	sampling, err := p.samplingOption("ollama", ollamaSampling)
+	if err != nil {
+		return nil, err
+	}
+	options = append([]func(ztype.Map) ztype.Map{sampling}, options...)
+	return p.PrepareMessagesRequest(messages, append(options, p.nativeOption)...)
+}
+
+// nativeOption 将请求转换为 Ollama 原生格式：temperature 等参数移入 options，并设置 keep_alive、format
+func (p *OllamaProvider) nativeOption(m ztype.Map) ztype.Map {
+	opts := ztype.Map{}
+	switch v := m["options"].(type) {
+	case ztype.Map:
+		opts = v
+	case map[string]any:
+		opts = v
+	}
+	if t, ok := m["temperature"]; ok {
+		if _, exists := opts["temperature"]; !exists {
+			opts["temperature"] = t
+		}
+		delete(m, "temperature")
+	}
+	if p.options.NumCtx > 0 {
+		opts["num_ctx"] = p.options.NumCtx
+	}
+	for k, v := range p.options.Options {
+		opts[k] = v
+	}
+	if len(opts) > 0 {
+		m["options"] = opts
+	}
+
+	// 原生接口的工具调用参数为 JSON 对象
+	if msgs, ok := m["messages"].([]ztype.Map); ok {
+		for _, msg := range msgs {
+			calls, ok := msg["tool_calls"].([]ztype.Map)
+			if !ok {
+				continue
+			}
+			for _, c := range calls {
+				if fn, ok := c["function"].(ztype.Map); ok {
+					fn["arguments"] = zjson.Parse(ztype.ToString(fn["arguments"])).Map()
+				}
+			}
+		}
+	}
+
+	if _, ok := m["keep_alive"]; !ok && p.options.KeepAlive != "" {
+		m["keep_alive"] = p.options.KeepAlive
+	}
+	if _, ok := m["format"]; !ok && p.options.Format != nil {
+		m["format"] = p.options.Format
+	}
+	return m
 }
 
 func (p *OllamaProvider) ParseResponse(body *zjson.Res) (*Response, error) {
-	return p.baseProvider.parseDefaultResponse(body)
+	if body.Get("choices").Exists() {
+		return p.baseProvider.parseDefaultResponse(body)
+	}
+	if e := body.Get("error"); e.Exists() {
+		return nil, runtime_errors.NewLLMError(runtime_errors.ErrServer, "ollama: "+errorMessage(e))
+	}
+
+	msg := body.Get("message")
+	if !msg.Exists() {
+		return nil, fmt.Errorf("error parsing response: %s", body.String())
+	}
+
+	var reasoning []byte
+	if thinking := msg.Get("thinking").String(); thinking != "" {
+		reasoning = []byte(thinking)
+	}
+
+	if calls := msg.Get("tool_calls"); len(calls.Array()) > 0 {
+		tools := make([]Tool, 0, len(calls.Array()))
+		calls.ForEach(func(_, v *zjson.Res) bool {
+			tools = append(tools, Tool{
+				ID:   v.Get("id").String(),
+				Name: v.Get("function.name").String(),
+				Args: v.Get("function.arguments").String(),
+			})
+			return true
+		})
+		return &Response{Tools: tools, Reasoning: reasoning}, nil
+	}
+
+	content := []byte(msg.Get("content").String())
+	if len(bytes.TrimSpace(content)) == 0 {
+		return nil, errors.New("empty response from API")
+	}
+	if reasoning == nil {
+		reasoning, content = splitReasoning(content)
+	}
+	return &Response{Content: content, Reasoning: reasoning}, nil
 }
@@ -0,0 +1,189 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/sohaha/zlsgo/zhttp"
+	"github.com/sohaha/zlsgo/zjson"
+	runtime_errors "github.com/zlsgo/zllm/runtime/errors"
+)
+
+// OllamaModelDetails 模型详情
+type OllamaModelDetails struct {
+	Format            string   `json:"format"`
+	Family            string   `json:"family"`
+	Families          []string `json:"families"`
+	ParameterSize     string   `json:"parameter_size"`
+	QuantizationLevel string   `json:"quantization_level"`
+}
+
+// OllamaModel 本地已下载的模型（/api/tags）
+type OllamaModel struct {
+	ModifiedAt time.Time          `json:"modified_at"`
+	Name       string             `json:"name"`
+	Model      string             `json:"model"`
+	Digest     string             `json:"digest"`
+	Details    OllamaModelDetails `json:"details"`
+	Size       int64              `json:"size"`
+}
+
+// OllamaRunningModel 已加载到内存的模型（/api/ps）
+type OllamaRunningModel struct {
+	ExpiresAt time.Time          `json:"expires_at"`
+	Name      string             `json:"name"`
+	Model     string             `json:"model"`
+	Digest    string             `json:"digest"`
+	Details   OllamaModelDetails `json:"details"`
+	Size      int64              `json:"size"`
+	SizeVRAM  int64              `json:"size_vram"`
+}
+
+// OllamaModelInfo 模型信息（/api/show）
+type OllamaModelInfo struct {
+	ModelInfo    map[string]any     `json:"model_info"`
+	Modelfile    string             `json:"modelfile"`
+	Parameters   string             `json:"parameters"`
+	Template     string             `json:"template"`
+	Details      OllamaModelDetails `json:"details"`
+	Capabilities []string           `json:"capabilities"`
+}
+
+// OllamaPullProgress 拉取模型的进度
+type OllamaPullProgress struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest"`
+	Total     int64  `json:"total"`
+	Completed int64  `json:"completed"`
+}
+
+// Models 列出本地已下载的模型及其能力
+func (p *OllamaProvider) Models(ctx context.Context) ([]ModelInfo, error) {
+	models, err := p.ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]ModelInfo, 0, len(models))
+	for i := range models {
+		infos = append(infos, modelInfo("ollama", models[i].Name))
+	}
+	return infos, nil
+}
+
+// ListModels 列出本地已下载的模型
+func (p *OllamaProvider) ListModels(ctx context.Context) ([]OllamaModel, error) {
+	res, err := p.doRaw(ctx, &p.options, "GET", "/api/tags")
+	if err != nil {
+		return nil, err
+	}
+
+	var data struct {
+		Models []OllamaModel `json:"models"`
+	}
+	if err = res.ToJSON(&data); err != nil {
+		return nil, err
+	}
+	return data.Models, nil
+}
+
+// RunningModels 列出已加载到内存的模型
+func (p *OllamaProvider) RunningModels(ctx context.Context) ([]OllamaRunningModel, error) {
+	res, err := p.doRaw(ctx, &p.options, "GET", "/api/ps")
+	if err != nil {
+		return nil, err
+	}
+
+	var data struct {
+		Models []OllamaRunningModel `json:"models"`
+	}
+	if err = res.ToJSON(&data); err != nil {
+		return nil, err
+	}
+	return data.Models, nil
+}
+
+// IsModelLoaded 判断模型是否已加载到内存，未指定标签时按 latest 匹配
+func (p *OllamaProvider) IsModelLoaded(ctx context.Context, name string) (bool, error) {
+	models, err := p.RunningModels(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	name = ollamaModelName(name)
+	for i := range models {
+		if ollamaModelName(models[i].Name) == name || ollamaModelName(models[i].Model) == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ShowModel 获取模型信息
+func (p *OllamaProvider) ShowModel(ctx context.Context, name string) (*OllamaModelInfo, error) {
+	body, _ := json.Marshal(map[string]any{"model": name})
+	res, err := p.doRaw(ctx, &p.options, "POST", "/api/show", body)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &OllamaModelInfo{}
+	if err = res.ToJSON(info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// PullModel 拉取模型，progress 不为空时逐条回调下载进度
+func (p *OllamaProvider) PullModel(ctx context.Context, name string, progress func(OllamaPullProgress)) error {
+	body, _ := json.Marshal(map[string]any{"model": name, "stream": true})
+	res, err := p.doRaw(ctx, &p.options, "POST", "/api/pull", body)
+	if err != nil {
+		return err
+	}
+
+	status := ""
+	err = readNDJSON(res, func(line []byte) error {
+		var v OllamaPullProgress
+		if err := json.Unmarshal(line, &v); err != nil {
+			return err
+		}
+		status = v.Status
+		if progress != nil {
+			progress(v)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if status != "success" {
+		return runtime_errors.NewLLMError(runtime_errors.ErrInvalidResponse, "ollama: pull "+name+" ended with status "+status)
+	}
+	return nil
+}
+
+// readNDJSON 逐行读取 NDJSON 响应，遇到 error 字段时返回错误
+func readNDJSON(res *zhttp.Res, fn func(line []byte) error) error {
+	return res.Stream(func(line []byte, _ bool) error {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			return nil
+		}
+		if e := zjson.GetBytes(line, "error"); e.Exists() {
+			return runtime_errors.NewLLMError(runtime_errors.ErrServer, "ollama: "+errorMessage(e))
+		}
+		return fn(line)
+	})
+}
+
+// ollamaModelName 规范化模型名称，未指定标签时补全 latest
+func ollamaModelName(name string) string {
+	if name != "" && !strings.Contains(name, ":") {
+		return name + ":latest"
+	}
+	return name
+}
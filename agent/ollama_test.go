@@ -2,6 +2,9 @@ package agent_test
 
 import (
 	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/sohaha/zlsgo"
@@ -9,6 +12,7 @@ import (
 	"github.com/sohaha/zlsgo/zjson"
 	"github.com/sohaha/zlsgo/ztype"
 	"github.com/zlsgo/zllm/agent"
+	"github.com/zlsgo/zllm/agent/agenttest"
 	"github.com/zlsgo/zllm/message"
 )
 
@@ -58,3 +62,123 @@ func TestNewOllama(t *testing.T) {
 	json := zjson.ParseBytes(str)
 	tt.Log(json.Get("Assistant").String())
 }
+
+func TestOllamaNative(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	srv := agenttest.NewServer(agenttest.Ollama, agenttest.Chunks(0, "he", "llo").WithReasoning("think"))
+	defer srv.Close()
+
+	llm := agent.NewOllama(func(o *agent.OllamaOptions) {
+		o.BaseURL = srv.URL
+		o.KeepAlive = "10m"
+		o.NumCtx = 4096
+		o.Options = map[string]any{"seed": 1}
+		o.Format = "json"
+		o.Sampling = agent.Sampling{MaxTokens: 128}
+	})
+
+	msg := message.NewMessages()
+	_ = msg.AppendUser("hi")
+	body, err := llm.PrepareRequest(msg)
+	tt.NoError(err, true)
+	req := zjson.ParseBytes(body)
+	tt.EqualTrue(!req.Get("temperature").Exists())
+	tt.Equal(0.48, req.Get("options.temperature").Float())
+	tt.Equal(4096, req.Get("options.num_ctx").Int())
+	tt.Equal(128, req.Get("options.num_predict").Int())
+	tt.Equal(1, req.Get("options.seed").Int())
+	tt.Equal("10m", req.Get("keep_alive").String())
+	tt.Equal("json", req.Get("format").String())
+
+	var chunks []string
+	done, err := llm.Stream(context.Background(), body, func(s string, _ []byte) {
+		chunks = append(chunks, s)
+	})
+	tt.NoError(err, true)
+	res := <-done
+	tt.EqualTrue(res != nil)
+	tt.Equal([]string{"he", "llo"}, chunks)
+
+	resp, err := llm.ParseResponse(res)
+	tt.NoError(err, true)
+	tt.Equal("hello", string(resp.Content))
+	tt.Equal("think", string(resp.Reasoning))
+	tt.EqualTrue(zjson.GetBytes(srv.Requests()[0].Body, "stream").Bool())
+
+	resp, err = llm.ParseResponse(zjson.Parse(`{"message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"weather","arguments":{"city":"sz"}}}]},"done":true}`))
+	tt.NoError(err, true)
+	tt.Equal(1, len(resp.Tools))
+	tt.Equal("weather", resp.Tools[0].Name)
+	tt.Equal("sz", zjson.Get(resp.Tools[0].Args, "city").String())
+
+	_, err = llm.ParseResponse(zjson.Parse(`{"error":"model not found"}`))
+	tt.EqualTrue(err != nil)
+}
+
+func TestOllamaModels(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	var pulled string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			_, _ = w.Write([]byte(`{"models":[{"name":"qwen2.5:3b","model":"qwen2.5:3b","size":1929912432,"details":{"family":"qwen2","parameter_size":"3.1B"}}]}`))
+		case "/api/ps":
+			_, _ = w.Write([]byte(`{"models":[{"name":"llama3:latest","model":"llama3:latest","size_vram":5137025024}]}`))
+		case "/api/show":
+			if zjson.ParseBytes(mustRead(r)).Get("model").String() != "qwen2.5:3b" {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"error":"model not found"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"template":"{{ .Prompt }}","details":{"family":"qwen2"},"capabilities":["completion","tools"]}`))
+		case "/api/pull":
+			pulled = zjson.ParseBytes(mustRead(r)).Get("model").String()
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			_, _ = w.Write([]byte(`{"status":"pulling manifest"}` + "\n" +
+				`{"status":"downloading","digest":"sha256:1","total":100,"completed":50}` + "\n" +
+				`{"status":"success"}` + "\n"))
+		}
+	}))
+	defer srv.Close()
+
+	llm := agent.NewOllama(func(o *agent.OllamaOptions) { o.BaseURL = srv.URL }).(*agent.OllamaProvider)
+	ctx := context.Background()
+
+	models, err := llm.ListModels(ctx)
+	tt.NoError(err, true)
+	tt.Equal(1, len(models))
+	tt.Equal("qwen2.5:3b", models[0].Name)
+	tt.Equal("3.1B", models[0].Details.ParameterSize)
+
+	loaded, err := llm.IsModelLoaded(ctx, "llama3")
+	tt.NoError(err, true)
+	tt.EqualTrue(loaded)
+	loaded, _ = llm.IsModelLoaded(ctx, "qwen2.5:3b")
+	tt.EqualTrue(!loaded)
+
+	info, err := llm.ShowModel(ctx, "qwen2.5:3b")
+	tt.NoError(err, true)
+	tt.Equal("qwen2", info.Details.Family)
+	tt.Equal([]string{"completion", "tools"}, info.Capabilities)
+
+	_, err = llm.ShowModel(ctx, "missing")
+	tt.EqualTrue(err != nil)
+	tt.Equal("model not found", err.Error())
+
+	var progress []agent.OllamaPullProgress
+	err = llm.PullModel(ctx, "llama3", func(p agent.OllamaPullProgress) {
+		progress = append(progress, p)
+	})
+	tt.NoError(err, true)
+	tt.Equal("llama3", pulled)
+	tt.Equal(3, len(progress))
+	tt.Equal(int64(50), progress[1].Completed)
+	tt.Equal("success", progress[2].Status)
+}
+
+func mustRead(r *http.Request) []byte {
+	b, _ := io.ReadAll(r.Body)
+	return b
+}
@@ -13,14 +13,17 @@ import (
 )
 
 type OpenAIOptions struct {
-	APIKey      string
-	Model       string
-	BaseURL     string
-	APIURL      string
-	Temperature float64
-	Stream      bool
-	MaxRetries  uint
-	OnMessage   func(string, []byte)
+	APIKey          string
+	Model           string
+	BaseURL         string
+	APIURL          string
+	Temperature     float64
+	Stream          bool
+	MaxRetries      uint
+	OnMessage       func(string, []byte)
+	ReasoningEffort string    // 推理强度：minimal、low、medium、high（可选，仅推理模型）
+	Sampling        Sampling  // 通用生成参数（max_tokens、top_p、stop 等）
+	RateLimit       RateLimit // 客户端限流（每分钟请求数与 token 数）
 }
 
 // 实现 providerConfig 接口
@@ -60,10 +63,29 @@ type OpenAIProvider struct {
 }
 
 func (p *OpenAIProvider) PrepareRequest(messages *message.Messages, options ...func(ztype.Map) ztype.Map) ([]byte, error) {
-	return p.PrepareMessagesRequest(messages, options...)
+	sampling, err := p.samplingOption("openai", openAISampling)
+	if err != nil {
+		return nil, err
+	}
+	defaults := []func(ztype.Map) ztype.Map{sampling}
+	if p.options.ReasoningEffort != "" {
+		defaults = append(defaults, WithReasoningEffort(p.options.ReasoningEffort))
+	}
+	return p.PrepareMessagesRequest(messages, append(defaults, options...)...)
 }
 
-var _ LLM = &OpenAIProvider{}
+var (
+	_ LLM            = &OpenAIProvider{}
+	_ CallOptionsLLM = &OpenAIProvider{}
+)
+
+// WithCallOptions 返回应用单次调用参数后的副本
+func (p *OpenAIProvider) WithCallOptions(co CallOptions) LLM {
+	c := *p
+	c.baseProvider = p.withCallOptions(co)
+	c.options.Model, c.options.Temperature = c.config.Model, c.config.Temperature
+	return &c
+}
 
 // 创建新的 OpenAI LLM 代理
 //
@@ -84,7 +106,7 @@ func NewOpenAI(opt ...func(*OpenAIOptions)) LLM {
 	}, opt...)
 
 	if o.APIKey == "" {
-		runtime.Log("Warning: OPENAI_API_KEY not set, provider will be non-functional")
+		runtime.Warn(context.Background(), "OPENAI_API_KEY not set, provider will be non-functional", runtime.Attr(runtime.KeyProvider, "openai"))
 	}
 
 	// 使用新的配置系统
@@ -93,6 +115,8 @@ func NewOpenAI(opt ...func(*OpenAIOptions)) LLM {
 		WithModel(o.Model).
 		WithTemperature(o.Temperature).
 		WithRetries(o.MaxRetries).
+		WithSampling(o.Sampling).
+		WithRateLimit(o.RateLimit).
 		WithTimeout(30*time.Second, 60*time.Second) // 默认超时时间
 
 	baseProvider := newBaseProvider(config)
@@ -126,3 +150,19 @@ func (p *OpenAIProvider) Stream(ctx context.Context, body []byte, callback func(
 func (p *OpenAIProvider) ParseResponse(body *zjson.Res) (*Response, error) {
 	return p.baseProvider.parseDefaultResponse(body)
 }
+
+// Models 列出服务端可用模型，能力信息来自模型目录
+func (p *OpenAIProvider) Models(ctx context.Context) ([]ModelInfo, error) {
+	res, err := p.doRaw(ctx, &p.options, "GET", "/models")
+	if err != nil {
+		return nil, err
+	}
+
+	data := res.JSONs().Get("data")
+	models := make([]ModelInfo, 0, len(data.Array()))
+	data.ForEach(func(_, v *zjson.Res) bool {
+		models = append(models, modelInfo("openai", v.Get("id").String()))
+		return true
+	})
+	return models, nil
+}
@@ -0,0 +1,164 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	neturl "net/url"
+
+	"github.com/sohaha/zlsgo/zhttp"
+	"github.com/sohaha/zlsgo/zjson"
+	runtime_errors "github.com/zlsgo/zllm/runtime/errors"
+)
+
+var _ BatchLLM = &OpenAIProvider{}
+
+// SubmitBatch 上传 JSONL 输入文件并创建 OpenAI Batch 任务
+func (p *OpenAIProvider) SubmitBatch(ctx context.Context, requests []BatchRequest) (*Batch, error) {
+	endpoint := p.batchEndpoint()
+	var input bytes.Buffer
+	for i := range requests {
+		body, err := completeMessage(p, requests[i].Body)
+		if err != nil {
+			return nil, err
+		}
+		line, err := json.Marshal(map[string]any{
+			"custom_id": requests[i].CustomID,
+			"method":    "POST",
+			"url":       endpoint,
+			"body":      json.RawMessage(batchBody(body)),
+		})
+		if err != nil {
+			return nil, err
+		}
+		input.Write(line)
+		input.WriteByte('\n')
+	}
+
+	res, err := p.doBatch(ctx, &p.options, "POST", "/files", zhttp.Param{"purpose": "batch"}, zhttp.FileUpload{
+		File:      io.NopCloser(&input),
+		FileName:  "batch.jsonl",
+		FieldName: "file",
+	})
+	if err != nil {
+		return nil, err
+	}
+	fileID := res.JSON("id").String()
+	if fileID == "" {
+		return nil, runtime_errors.NewLLMError(runtime_errors.ErrInvalidResponse, "openai batch: missing input file id")
+	}
+
+	body, _ := json.Marshal(map[string]any{
+		"input_file_id":     fileID,
+		"endpoint":          endpoint,
+		"completion_window": "24h",
+	})
+	res, err = p.doBatch(ctx, &p.options, "POST", "/batches", body)
+	if err != nil {
+		return nil, err
+	}
+	return openAIBatch(res.JSONs()), nil
+}
+
+// GetBatch 查询 OpenAI Batch 任务
+func (p *OpenAIProvider) GetBatch(ctx context.Context, id string) (*Batch, error) {
+	res, err := p.doBatch(ctx, &p.options, "GET", "/batches/"+neturl.PathEscape(id))
+	if err != nil {
+		return nil, err
+	}
+	return openAIBatch(res.JSONs()), nil
+}
+
+// CancelBatch 取消 OpenAI Batch 任务
+func (p *OpenAIProvider) CancelBatch(ctx context.Context, id string) (*Batch, error) {
+	res, err := p.doBatch(ctx, &p.options, "POST", "/batches/"+neturl.PathEscape(id)+"/cancel")
+	if err != nil {
+		return nil, err
+	}
+	return openAIBatch(res.JSONs()), nil
+}
+
+// BatchResults 下载输出文件与错误文件，并通过 ParseResponse 解析每个响应
+func (p *OpenAIProvider) BatchResults(ctx context.Context, id string) ([]BatchResult, error) {
+	batch, err := p.GetBatch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !batch.Status.Done() {
+		return nil, runtime_errors.NewLLMError(runtime_errors.ErrInvalidRequest, "batch "+id+" is "+string(batch.Status))
+	}
+
+	var results []BatchResult
+	for _, key := range []string{"output_file_id", "error_file_id"} {
+		fileID := batch.Raw.Get(key).String()
+		if fileID == "" {
+			continue
+		}
+		res, err := p.doBatch(ctx, &p.options, "GET", "/files/"+neturl.PathEscape(fileID)+"/content")
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range bytes.Split(res.Bytes(), []byte("\n")) {
+			if line = bytes.TrimSpace(line); len(line) > 0 {
+				results = append(results, p.batchResult(zjson.ParseBytes(line)))
+			}
+		}
+	}
+	return results, nil
+}
+
+// batchResult 解析输出文件中的一行
+func (p *OpenAIProvider) batchResult(line *zjson.Res) BatchResult {
+	r := BatchResult{CustomID: line.Get("custom_id").String()}
+	if e := line.Get("error"); e.IsObject() {
+		r.Err = batchError("openai", 0, errorMessage(e))
+		return r
+	}
+
+	status := line.Get("response.status_code").Int()
+	body := line.Get("response.body")
+	if status >= 400 {
+		r.Err = batchError("openai", status, errorMessage(body.Get("error")))
+		return r
+	}
+	r.Response, r.Err = p.ParseResponse(body)
+	return r
+}
+
+// batchEndpoint 批处理请求使用的接口路径，如 /v1/chat/completions
+func (p *OpenAIProvider) batchEndpoint() string {
+	path := ""
+	if endpoints := p.options.getEndpoints(); len(endpoints) > 0 {
+		if u, err := neturl.Parse(endpoints[0]); err == nil {
+			path = u.Path
+		}
+	}
+	return path + p.options.getAPIPath()
+}
+
+// openAIBatch 转换 OpenAI Batch 对象
+func openAIBatch(res *zjson.Res) *Batch {
+	status := BatchInProgress
+	switch res.Get("status").String() {
+	case "completed":
+		status = BatchCompleted
+	case "failed":
+		status = BatchFailed
+	case "expired":
+		status = BatchExpired
+	case "cancelling":
+		status = BatchCanceling
+	case "cancelled":
+		status = BatchCanceled
+	}
+
+	return &Batch{
+		Raw:       res,
+		ID:        res.Get("id").String(),
+		Status:    status,
+		Total:     res.Get("request_counts.total").Int(),
+		Succeeded: res.Get("request_counts.completed").Int(),
+		Failed:    res.Get("request_counts.failed").Int(),
+	}
+}
@@ -0,0 +1,492 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sohaha/zlsgo/zhttp"
+	"github.com/sohaha/zlsgo/zjson"
+	"github.com/sohaha/zlsgo/ztype"
+	"github.com/sohaha/zlsgo/zutil"
+	"github.com/zlsgo/zllm/message"
+	"github.com/zlsgo/zllm/runtime"
+	runtime_errors "github.com/zlsgo/zllm/runtime/errors"
+)
+
+// OpenAIResponsesOptions OpenAI Responses API 配置选项
+type OpenAIResponsesOptions struct {
+	APIKey             string               // API 密钥，支持逗号分隔的多密钥负载均衡
+	Model              string               // 模型名称
+	BaseURL            string               // API 基础 URL
+	APIURL             string               // API 路径（默认 /responses）
+	Temperature        float64              // 采样温度，为 0 时不发送（推理模型不支持该参数）
+	Stream             bool                 // 启用流式响应
+	MaxRetries         uint                 // 失败请求的最大重试次数
+	MaxOutputTokens    int                  // 最大输出 token 数（可选）
+	ReasoningEffort    string               // 推理强度：minimal、low、medium、high（可选）
+	PreviousResponseID string               // 延续的上一次响应 ID，由服务端保存对话状态（可选）
+	Store              *bool                // 是否在服务端保存响应（可选）
+	OnMessage          func(string, []byte) // 流式消息回调函数
+	Sampling           Sampling             // 通用生成参数，仅支持 max_tokens 与 top_p
+	RateLimit          RateLimit            // 客户端限流（每分钟请求数与 token 数）
+}
+
+func (o *OpenAIResponsesOptions) getAPIKey() []string {
+	return parseValue(o.APIKey)
+}
+
+func (o *OpenAIResponsesOptions) getEndpoints() []string {
+	return parseValue(o.BaseURL)
+}
+
+func (o *OpenAIResponsesOptions) getAPIPath() string {
+	return o.APIURL
+}
+
+func (o *OpenAIResponsesOptions) buildHeaders(apiKey string) zhttp.Header {
+	return buildAuthHeaders(apiKey)
+}
+
+func (o *OpenAIResponsesOptions) getStreamProcessor() string {
+	return "openai_responses"
+}
+
+func (o *OpenAIResponsesOptions) getMaxRetries() uint {
+	return o.MaxRetries
+}
+
+func (o *OpenAIResponsesOptions) getOnMessage() func(string, []byte) {
+	return o.OnMessage
+}
+
+// OpenAIResponsesProvider OpenAI Responses API 的 LLM 代理实现
+type OpenAIResponsesProvider struct {
+	*baseProvider
+	options OpenAIResponsesOptions
+}
+
+var (
+	_ LLM            = &OpenAIResponsesProvider{}
+	_ CallOptionsLLM = &OpenAIResponsesProvider{}
+)
+
+// WithCallOptions 返回应用单次调用参数后的副本
+func (p *OpenAIResponsesProvider) WithCallOptions(co CallOptions) LLM {
+	c := *p
+	c.baseProvider = p.withCallOptions(co)
+	c.options.Model, c.options.Temperature = c.config.Model, c.config.Temperature
+	return &c
+}
+
+// NewOpenAIResponses 创建基于 /v1/responses 的 OpenAI LLM 代理
+//
+//	agent.NewOpenAIResponses(func(o *agent.OpenAIResponsesOptions) {
+//		o.APIKey = "sk-...your-api-key..."
+//		o.Model = "o4-mini"
+//		o.ReasoningEffort = "low"
+//	})
+func NewOpenAIResponses(opt ...func(*OpenAIResponsesOptions)) LLM {
+	o := zutil.Optional(OpenAIResponsesOptions{
+		APIKey:     zutil.Getenv("OPENAI_API_KEY", ""),
+		Model:      zutil.Getenv("OPENAI_MODEL", "gpt-4.1"),
+		MaxRetries: 3,
+		BaseURL:    zutil.Getenv("OPENAI_BASE_URL", "https://api.openai.com/v1"),
+		APIURL:     "/responses",
+	}, opt...)
+
+	if o.APIKey == "" {
+		runtime.Warn(context.Background(), "OPENAI_API_KEY not set, provider will be non-functional", runtime.Attr(runtime.KeyProvider, "openai_responses"))
+	}
+
+	config := DefaultConfig().
+		WithAPIKey(o.APIKey).
+		WithModel(o.Model).
+		WithTemperature(o.Temperature).
+		WithRetries(o.MaxRetries).
+		WithSampling(o.Sampling).
+		WithRateLimit(o.RateLimit)
+	config.Stream = o.Stream
+
+	return &OpenAIResponsesProvider{
+		baseProvider: newBaseProvider(config),
+		options:      o,
+	}
+}
+
+// WithPreviousResponseID 设置延续的上一次响应 ID
+func WithPreviousResponseID(id string) func(ztype.Map) ztype.Map {
+	return func(m ztype.Map) ztype.Map {
+		if id != "" {
+			m["previous_response_id"] = id
+		}
+		return m
+	}
+}
+
+// WithJSONSchema 设置结构化输出的 JSON Schema
+func WithJSONSchema(name string, schema any) func(ztype.Map) ztype.Map {
+	return func(m ztype.Map) ztype.Map {
+		m["text"] = ztype.Map{
+			"format": ztype.Map{
+				"type":   "json_schema",
+				"name":   name,
+				"schema": schema,
+				"strict": true,
+			},
+		}
+		return m
+	}
+}
+
+func (p *OpenAIResponsesProvider) Generate(ctx context.Context, body []byte) (*zjson.Res, error) {
+	var err error
+	body, err = completeMessage(p, body)
+	if err != nil {
+		return nil, err
+	}
+	return p.baseProvider.generateWithConfig(ctx, &p.options, body)
+}
+
+func (p *OpenAIResponsesProvider) Stream(ctx context.Context, body []byte, callback func(string, []byte)) (<-chan *zjson.Res, error) {
+	var err error
+	body, err = completeMessage(p, body)
+	if err != nil {
+		return nil, err
+	}
+	return p.baseProvider.streamWithConfig(ctx, &p.options, body, callback)
+}
+
+// PrepareRequest 将消息转换为 Responses API 格式，系统消息合并为 instructions
+func (p *OpenAIResponsesProvider) PrepareRequest(messages *message.Messages, options ...func(ztype.Map) ztype.Map) ([]byte, error) {
+	sampling, err := p.samplingOption("openai_responses", responsesSampling)
+	if err != nil {
+		return nil, err
+	}
+
+	req := ztype.Map{
+		"model":  p.config.Model,
+		"stream": p.config.Stream,
+	}
+	if p.config.Temperature > 0 {
+		req["temperature"] = p.config.Temperature
+	}
+	if p.options.MaxOutputTokens > 0 {
+		req["max_output_tokens"] = p.options.MaxOutputTokens
+	}
+	if p.options.ReasoningEffort != "" {
+		req["reasoning"] = ztype.Map{"effort": p.options.ReasoningEffort}
+	}
+	if p.options.PreviousResponseID != "" {
+		req["previous_response_id"] = p.options.PreviousResponseID
+	}
+	if p.options.Store != nil {
+		req["store"] = *p.options.Store
+	}
+
+	sys, input := responsesInput(messages)
+	if len(sys) > 0 {
+		req["instructions"] = strings.Join(sys, "\n\n")
+	}
+	req["input"] = input
+	req = sampling(req)
+
+	for _, v := range options {
+		req = v(req)
+	}
+
+	if tools, ok := req["tools"]; ok {
+		req["tools"] = responsesTools(tools)
+	}
+
+	return json.Marshal(req)
+}
+
+// responsesInput 转换为 Responses API 的 input 项，系统消息单独返回，
+// 工具调用与工具结果使用 function_call、function_call_output 项并通过 call_id 关联
+func responsesInput(messages *message.Messages) ([]string, []ztype.Map) {
+	history := messages.History(true)
+	raw := messages.HistoryMessages(true)
+	var sys []string
+	input := make([]ztype.Map, 0, len(history))
+	for i := range history {
+		role, content := history[i][0], history[i][1]
+		switch {
+		case role == message.RoleSystem:
+			sys = append(sys, content)
+		case role == message.RoleAssistant && len(raw[i].ToolCalls) > 0:
+			// 推理项需原样位于其函数调用之前
+			for _, b := range raw[i].Reasoning {
+				if b.Type == responsesReasoningType && b.Signature != "" {
+					input = append(input, responsesReasoningItem(b))
+				}
+			}
+			if raw[i].Content != "" {
+				input = append(input, ztype.Map{"role": role, "content": raw[i].Content})
+			}
+			for _, c := range raw[i].ToolCalls {
+				input = append(input, ztype.Map{"type": "function_call", "call_id": c.ID, "name": c.Name, "arguments": c.Args})
+			}
+		case role == message.RoleUser && len(raw[i].ToolOutputs) > 0:
+			for _, o := range raw[i].ToolOutputs {
+				input = append(input, ztype.Map{"type": "function_call_output", "call_id": o.ID, "output": o.Content})
+			}
+			if content != raw[i].Content {
+				// 附加了输出格式要求时保留文本
+				input = append(input, ztype.Map{"role": role, "content": content})
+			}
+		default:
+			input = append(input, ztype.Map{"role": role, "content": content})
+		}
+	}
+	return sys, input
+}
+
+// responsesReasoningType Responses API 推理项类型，Signature 保存推理项 ID，Data 保存加密内容
+const responsesReasoningType = "reasoning"
+
+// responsesReasoningItem 转换为 Responses API 推理项
+func responsesReasoningItem(b message.ReasoningBlock) ztype.Map {
+	summary := []ztype.Map{}
+	if b.Thinking != "" {
+		summary = append(summary, ztype.Map{"type": "summary_text", "text": b.Thinking})
+	}
+	item := ztype.Map{"type": responsesReasoningType, "id": b.Signature, "summary": summary}
+	if b.Data != "" {
+		item["encrypted_content"] = b.Data
+	}
+	return item
+}
+
+// ParseResponse 解析 Responses API 返回，function_call 项转换为工具调用
+func (p *OpenAIResponsesProvider) ParseResponse(body *zjson.Res) (*Response, error) {
+	if body == nil {
+		return nil, errors.New("empty response")
+	}
+	if e := body.Get("error"); e.Exists() && e.IsObject() {
+		return nil, errors.New(e.Get("message").String())
+	}
+
+	output := body.Get("output")
+	if !output.Exists() {
+		return nil, fmt.Errorf("error parsing response: %s", body.String())
+	}
+
+	var (
+		text, reasoning strings.Builder
+		tools           []Tool
+		blocks          []message.ReasoningBlock
+	)
+	output.ForEach(func(_, item *zjson.Res) bool {
+		switch item.Get("type").String() {
+		case responsesReasoningType:
+			var summary strings.Builder
+			item.Get("summary").ForEach(func(_, s *zjson.Res) bool {
+				summary.WriteString(s.Get("text").String())
+				return true
+			})
+			reasoning.WriteString(summary.String())
+			blocks = append(blocks, message.ReasoningBlock{
+				Type:      responsesReasoningType,
+				Thinking:  summary.String(),
+				Signature: item.Get("id").String(),
+				Data:      item.Get("encrypted_content").String(),
+			})
+		case "function_call":
+			tools = append(tools, Tool{
+				ID:   item.Get("call_id").String(),
+				Name: item.Get("name").String(),
+				Args: item.Get("arguments").String(),
+			})
+		case "message":
+			item.Get("content").ForEach(func(_, c *zjson.Res) bool {
+				if c.Get("type").String() == "output_text" {
+					text.WriteString(c.Get("text").String())
+				}
+				return true
+			})
+		}
+		return true
+	})
+
+	resp := &Response{ID: body.Get("id").String(), ReasoningBlocks: blocks}
+	if reasoning.Len() > 0 {
+		resp.Reasoning = []byte(reasoning.String())
+	}
+	if len(tools) > 0 {
+		resp.Tools = tools
+		return resp, nil
+	}
+	if strings.TrimSpace(text.String()) == "" {
+		if status := body.Get("status").String(); status != "" && status != "completed" {
+			return nil, fmt.Errorf("response %s: %s", status, body.Get("incomplete_details.reason").String())
+		}
+		return nil, errors.New("empty response from API")
+	}
+	resp.Content = []byte(text.String())
+	return resp, nil
+}
+
+// responsesTools 将 Chat Completions 格式的工具定义展开为 Responses API 格式
+func responsesTools(tools any) any {
+	list, ok := tools.([]ztype.Map)
+	if !ok {
+		var arr []ztype.Map
+		b, err := json.Marshal(tools)
+		if err != nil || json.Unmarshal(b, &arr) != nil {
+			return tools
+		}
+		list = arr
+	}
+
+	result := make([]ztype.Map, 0, len(list))
+	for _, t := range list {
+		fn, ok := t["function"]
+		if !ok {
+			result = append(result, t)
+			continue
+		}
+		flat := ztype.Map{"type": "function"}
+		for k, v := range ztype.ToMap(fn) {
+			flat[k] = v
+		}
+		result = append(result, flat)
+	}
+	return result
+}
+
+func processOpenAIResponsesStream(ctx context.Context, sse *zhttp.SSEEngine, config *streamConfig, timeout time.Duration) (*zjson.Res, error) {
+	processor := &openAIResponsesStreamProcessor{}
+	res, err := processStreamGeneric(ctx, sse, config, processor, timeout)
+	if failed := processor.failure(); failed != nil {
+		return nil, failed
+	}
+	if completed := processor.result(); completed != nil {
+		// response.completed 事件包含完整结果（含工具调用与响应 ID），优先使用
+		return completed, nil
+	}
+	return res, err
+}
+
+// openAIResponsesStreamProcessor Responses API 流式处理器实现
+// 事件类型：response.created, response.output_item.added, response.output_text.delta,
+// response.function_call_arguments.delta, response.output_item.done, response.completed, response.failed, error
+type openAIResponsesStreamProcessor struct {
+	err       error
+	id        string
+	completed []byte
+	reasoning reasoningBuffer
+	mu        sync.Mutex
+}
+
+// ProcessReasoning 提取推理摘要增量
+func (p *openAIResponsesStreamProcessor) ProcessReasoning(ev *zhttp.SSEEvent) string {
+	if zjson.GetBytes(ev.Data, "type").String() != "response.reasoning_summary_text.delta" {
+		return ""
+	}
+	return p.reasoning.write(zjson.GetBytes(ev.Data, "delta").String())
+}
+
+func (p *openAIResponsesStreamProcessor) ProcessMessage(ev *zhttp.SSEEvent, config *streamConfig) (bool, string) {
+	t := zjson.GetBytes(ev.Data, "type").String()
+	if t == "" {
+		t = ev.Event
+	}
+
+	switch t {
+	case "response.created":
+		p.mu.Lock()
+		p.id = zjson.GetBytes(ev.Data, "response.id").String()
+		p.mu.Unlock()
+		return false, ""
+	case "response.output_text.delta":
+		return false, zjson.GetBytes(ev.Data, "delta").String()
+	case "response.completed", "response.incomplete", "response.failed":
+		p.mu.Lock()
+		p.completed = []byte(zjson.GetBytes(ev.Data, "response").Raw())
+		p.mu.Unlock()
+		return true, ""
+	case "error":
+		p.mu.Lock()
+		p.err = responsesStreamError(zjson.GetBytes(ev.Data, "code").String(), zjson.GetBytes(ev.Data, "message").String())
+		p.mu.Unlock()
+		return true, ""
+	default:
+		return false, ""
+	}
+}
+
+func (p *openAIResponsesStreamProcessor) BuildResponse(rawMessage []byte, result string) *zjson.Res {
+	if completed := p.result(); completed != nil {
+		return completed
+	}
+
+	output := []map[string]any{{
+		"type":    "message",
+		"role":    "assistant",
+		"content": []map[string]any{{"type": "output_text", "text": result}},
+	}}
+	if reasoning := p.reasoning.String(); reasoning != "" {
+		output = append([]map[string]any{{
+			"type":    "reasoning",
+			"summary": []map[string]any{{"type": "summary_text", "text": reasoning}},
+		}}, output...)
+	}
+	p.mu.Lock()
+	id := p.id
+	p.mu.Unlock()
+	m := map[string]any{
+		"id":     id,
+		"object": "response",
+		"status": "completed",
+		"output": output,
+	}
+	b, _ := zjson.Marshal(m)
+	return zjson.ParseBytes(b)
+}
+
+// result 返回 response.completed 事件中的完整响应
+func (p *openAIResponsesStreamProcessor) result() *zjson.Res {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.completed) == 0 {
+		return nil
+	}
+	return zjson.ParseBytes(p.completed)
+}
+
+// failure 返回流中 error 事件对应的错误
+func (p *openAIResponsesStreamProcessor) failure() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.err
+}
+
+// responsesStreamError 将流中的 error 事件转换为 LLMError
+func responsesStreamError(code, msg string) error {
+	if msg == "" {
+		msg = "responses stream error"
+	}
+	if code != "" {
+		msg = code + ": " + msg
+	}
+
+	errCode := runtime_errors.ErrServer
+	switch code {
+	case "rate_limit_exceeded":
+		errCode = runtime_errors.ErrRateLimited
+	case "insufficient_quota":
+		errCode = runtime_errors.ErrQuotaExceeded
+	case "context_length_exceeded":
+		errCode = runtime_errors.ErrTokenLimit
+	case "invalid_request_error", "invalid_prompt":
+		errCode = runtime_errors.ErrInvalidRequest
+	}
+	return runtime_errors.NewLLMError(errCode, msg)
+}
@@ -0,0 +1,124 @@
+package agent_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sohaha/zlsgo"
+	"github.com/sohaha/zlsgo/zjson"
+	"github.com/sohaha/zlsgo/ztype"
+	"github.com/zlsgo/zllm/agent"
+	"github.com/zlsgo/zllm/agent/agenttest"
+	"github.com/zlsgo/zllm/message"
+)
+
+func TestOpenAIResponses(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	srv := agenttest.NewServer(agenttest.OpenAIResponses,
+		agenttest.Text("hello"),
+		agenttest.ToolCall("get_weather", `{"city":"Paris"}`),
+		agenttest.Chunks(time.Millisecond, "Hel", "lo"),
+		agenttest.ToolCall("get_weather", `{"city":"Rome"}`),
+	)
+	defer srv.Close()
+
+	llm := agent.NewOpenAIResponses(func(o *agent.OpenAIResponsesOptions) {
+		o.BaseURL = srv.URL
+		o.APIKey = "sk-test"
+		o.Model = "o4-mini"
+		o.ReasoningEffort = "low"
+		o.MaxOutputTokens = 256
+	})
+
+	tt.Run("Request", func(tt *zlsgo.TestUtil) {
+		p := message.NewPrompt("你好", func(po *message.PromptOptions) {
+			po.SystemPrompt = "你是助手"
+			po.ExamplesAsMessages = true
+			po.Examples = [][2]string{{"hi", "hello"}}
+		})
+		msg, err := p.ConvertToMessages()
+		tt.NoError(err, true)
+
+		tools := []ztype.Map{{"type": "function", "function": ztype.Map{"name": "get_weather", "parameters": ztype.Map{"type": "object"}}}}
+		body, err := llm.PrepareRequest(msg, agent.WithToolCallHint(tools), agent.WithPreviousResponseID("resp_1"))
+		tt.NoError(err, true)
+
+		j := zjson.ParseBytes(body)
+		tt.EqualTrue(j.Get("instructions").String() != "")
+		tt.Equal(3, len(j.Get("input").Array()))
+		tt.Equal("user", j.Get("input.0.role").String())
+		tt.Equal("low", j.Get("reasoning.effort").String())
+		tt.Equal(256, j.Get("max_output_tokens").Int())
+		tt.Equal("resp_1", j.Get("previous_response_id").String())
+		tt.Equal("get_weather", j.Get("tools.0.name").String())
+		tt.EqualTrue(!j.Get("tools.0.function").Exists())
+		tt.EqualTrue(!j.Get("temperature").Exists())
+	})
+
+	tt.Run("Generate", func(tt *zlsgo.TestUtil) {
+		res, err := llm.Generate(context.Background(), []byte("hi"))
+		tt.NoError(err, true)
+		resp, err := llm.ParseResponse(res)
+		tt.NoError(err, true)
+		tt.Equal("hello", string(resp.Content))
+		tt.Equal("resp_mock", resp.ID)
+
+		res, err = llm.Generate(context.Background(), []byte("weather?"))
+		tt.NoError(err, true)
+		resp, err = llm.ParseResponse(res)
+		tt.NoError(err, true)
+		tt.Equal([]agent.Tool{{ID: "call_0", Name: "get_weather", Args: `{"city":"Paris"}`}}, resp.Tools)
+
+		tt.Equal("/responses", srv.Requests()[0].Path)
+	})
+
+	tt.Run("Stream", func(tt *zlsgo.TestUtil) {
+		var (
+			mu     sync.Mutex
+			chunks []string
+		)
+		done, err := llm.Stream(context.Background(), []byte("hi"), func(s string, _ []byte) {
+			mu.Lock()
+			chunks = append(chunks, s)
+			mu.Unlock()
+		})
+		tt.NoError(err, true)
+		res := <-done
+		tt.EqualTrue(res != nil)
+		resp, err := llm.ParseResponse(res)
+		tt.NoError(err, true)
+		tt.Equal("Hello", string(resp.Content))
+		tt.Equal([]string{"Hel", "lo"}, chunks)
+		tt.Equal("resp_mock", resp.ID)
+
+		done, err = llm.Stream(context.Background(), []byte("weather?"), func(string, []byte) {})
+		tt.NoError(err, true)
+		res = <-done
+		tt.EqualTrue(res != nil)
+		resp, err = llm.ParseResponse(res)
+		tt.NoError(err, true)
+		tt.Equal("Rome", zjson.Parse(resp.Tools[0].Args).Get("city").String())
+	})
+
+	tt.Run("StreamError", func(tt *zlsgo.TestUtil) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte("data: {\"type\":\"response.output_text.delta\",\"delta\":\"Hel\"}\n\n"))
+			_, _ = w.Write([]byte("data: {\"type\":\"error\",\"code\":\"server_error\",\"message\":\"boom\"}\n\n"))
+		}))
+		defer srv.Close()
+
+		llm := agent.NewOpenAIResponses(func(o *agent.OpenAIResponsesOptions) {
+			o.BaseURL = srv.URL
+			o.APIKey = "sk-test"
+		})
+		done, err := llm.Stream(context.Background(), []byte("hi"), func(string, []byte) {})
+		tt.NoError(err, true)
+		tt.EqualTrue(<-done == nil)
+	})
+}
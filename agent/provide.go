@@ -17,8 +17,38 @@ type LLM interface {
 	ParseResponse(*zjson.Res) (*Response, error)
 }
 
+// TokenCounter 支持估算 token 数的LLM代理
+type TokenCounter interface {
+	CountTokens(text string) int
+}
+
+// ResolverLLM 按请求选择实际 LLM 的代理（如路由器），调用方在构建请求前通过 Resolve 解析，
+// 使路由可以读取上下文中的约束与截止时间
+type ResolverLLM interface {
+	Resolve(ctx context.Context, messages *message.Messages, options ...func(ztype.Map) ztype.Map) (LLM, error)
+}
+
+// Resolve 解析实际处理请求的 LLM，不支持解析时原样返回
+func Resolve(ctx context.Context, llm LLM, messages *message.Messages, options ...func(ztype.Map) ztype.Map) (LLM, error) {
+	if r, ok := llm.(ResolverLLM); ok {
+		return r.Resolve(ctx, messages, options...)
+	}
+	return llm, nil
+}
+
 // Response LLM响应格式
 type Response struct {
-	Content []byte `json:"content"`
-	Tools   []Tool `json:"tools"`
+	ID              string                   `json:"id,omitempty"` // 响应 ID（OpenAI Responses），可通过 WithPreviousResponseID 延续对话
+	Content         []byte                   `json:"content"`
+	Reasoning       []byte                   `json:"reasoning,omitempty"`        // 推理（思考）内容
+	ReasoningBlocks []message.ReasoningBlock `json:"reasoning_blocks,omitempty"` // 带签名的推理块，多轮工具调用时需原样回传
+	Tools           []Tool                   `json:"tools"`
+	SafetyRatings   []SafetyRating           `json:"safety_ratings,omitempty"` // 安全评级（Gemini）
+}
+
+// SafetyRating 内容安全评级
+type SafetyRating struct {
+	Category    string `json:"category"`
+	Probability string `json:"probability"`
+	Blocked     bool   `json:"blocked,omitempty"`
 }
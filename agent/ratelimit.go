@@ -0,0 +1,379 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sohaha/zlsgo/zhttp"
+	"github.com/sohaha/zlsgo/zstring"
+	"github.com/zlsgo/zllm/runtime"
+	runtime_errors "github.com/zlsgo/zllm/runtime/errors"
+)
+
+// RateLimit 客户端限流配置，同一服务地址、API Key、模型与限流配置共享限额
+type RateLimit struct {
+	RequestsPerMinute int  // 每分钟请求数，0 表示不限制
+	TokensPerMinute   int  // 每分钟 token 数（按请求体估算），0 表示不限制
+	DisableAdaptive   bool // 不根据响应头（x-ratelimit-*、retry-after）调整限额
+}
+
+// WithRateLimit 设置客户端限流
+func (c Config) WithRateLimit(r RateLimit) Config {
+	c.RateLimit = r
+	return c
+}
+
+// authHeaders 可能携带 API Key 的请求头
+var authHeaders = []string{"Authorization", "x-api-key", "x-goog-api-key", "api-key"}
+
+// rateLimiters 进程内共享的限流器，数量超过 maxRateLimiters 时清理闲置超过 rateLimiterIdle 的限流器
+var (
+	rateLimiters   = map[string]*rateLimiter{}
+	rateLimitersMu sync.Mutex
+)
+
+const (
+	maxRateLimiters = 256
+	rateLimiterIdle = 10 * time.Minute
+)
+
+// rateLimiter 请求数与 token 数两个令牌桶
+type rateLimiter struct {
+	used     time.Time
+	requests bucket
+	tokens   bucket
+	adaptive bool
+	mu       sync.Mutex
+}
+
+// bucket 按分钟补充的令牌桶，令牌可透支，透支部分需等待补充
+type bucket struct {
+	last       time.Time
+	blocked    time.Time
+	limit      float64
+	tokens     float64
+	configured float64 // 配置的限额
+	learned    float64 // 从响应头获取的限额
+}
+
+// setLimit 设置每分钟限额，0 表示不限制
+func (b *bucket) setLimit(limit float64, now time.Time) {
+	if limit == b.limit {
+		return
+	}
+	if b.limit <= 0 {
+		b.tokens, b.last = limit, now
+	} else {
+		b.refill(now)
+	}
+	b.limit = limit
+	if b.tokens > limit {
+		b.tokens = limit
+	}
+}
+
+// effectiveLimit 配置限额与响应头限额中较小的一个
+func (b *bucket) effectiveLimit() float64 {
+	if b.configured <= 0 || (b.learned > 0 && b.learned < b.configured) {
+		return b.learned
+	}
+	return b.configured
+}
+
+// refill 按经过的时间补充令牌
+func (b *bucket) refill(now time.Time) {
+	if b.limit <= 0 {
+		return
+	}
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens += elapsed * b.limit / 60
+		if b.tokens > b.limit {
+			b.tokens = b.limit
+		}
+	}
+	b.last = now
+}
+
+// reserve 预留 n 个令牌，返回需要等待的时间
+func (b *bucket) reserve(n float64, now time.Time) time.Duration {
+	var wait time.Duration
+	if b.blocked.After(now) {
+		wait = b.blocked.Sub(now)
+	}
+	if b.limit <= 0 || n <= 0 {
+		return wait
+	}
+
+	b.refill(now)
+	if n > b.limit {
+		n = b.limit
+	}
+	b.tokens -= n
+	if b.tokens < 0 {
+		if d := time.Duration(-b.tokens / b.limit * 60 * float64(time.Second)); d > wait {
+			wait = d
+		}
+	}
+	return wait
+}
+
+// release 归还未使用的令牌
+func (b *bucket) release(n float64) {
+	if b.limit <= 0 || n <= 0 {
+		return
+	}
+	if n > b.limit {
+		n = b.limit
+	}
+	b.tokens += n
+	if b.tokens > b.limit {
+		b.tokens = b.limit
+	}
+}
+
+// adapt 根据服务端返回的限额、剩余量与重置时间调整令牌桶
+func (b *bucket) adapt(limit, remaining float64, reset time.Duration, now time.Time) {
+	if limit > 0 {
+		b.learned = limit
+		b.setLimit(b.effectiveLimit(), now)
+	}
+	if remaining < 0 {
+		return
+	}
+	if b.limit > 0 {
+		b.refill(now)
+		if remaining < b.tokens {
+			b.tokens = remaining
+		}
+	}
+	if remaining == 0 && reset > 0 {
+		b.block(now.Add(reset))
+	}
+}
+
+// block 在指定时间前暂停放行
+func (b *bucket) block(until time.Time) {
+	if until.After(b.blocked) {
+		b.blocked = until
+	}
+}
+
+// rateLimiterFor 获取共享限流器，限流配置计入 key，创建后配置限额不再变化
+func rateLimiterFor(key string, r RateLimit) *rateLimiter {
+	key += fmt.Sprintf("|%d|%d|%t", r.RequestsPerMinute, r.TokensPerMinute, r.DisableAdaptive)
+	now := time.Now()
+
+	rateLimitersMu.Lock()
+	defer rateLimitersMu.Unlock()
+
+	if l, ok := rateLimiters[key]; ok {
+		l.touch(now)
+		return l
+	}
+
+	if len(rateLimiters) >= maxRateLimiters {
+		for k, l := range rateLimiters {
+			if l.idle(now) {
+				delete(rateLimiters, k)
+			}
+		}
+	}
+
+	l := &rateLimiter{used: now, adaptive: !r.DisableAdaptive}
+	l.requests.configured = float64(r.RequestsPerMinute)
+	l.requests.setLimit(l.requests.configured, now)
+	l.tokens.configured = float64(r.TokensPerMinute)
+	l.tokens.setLimit(l.tokens.configured, now)
+	rateLimiters[key] = l
+	return l
+}
+
+// touch 记录最近使用时间
+func (l *rateLimiter) touch(now time.Time) {
+	l.mu.Lock()
+	l.used = now
+	l.mu.Unlock()
+}
+
+// idle 是否闲置可清理，闲置期间令牌已补满且不处于暂停状态
+func (l *rateLimiter) idle(now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return now.Sub(l.used) > rateLimiterIdle && !l.requests.blocked.After(now) && !l.tokens.blocked.After(now)
+}
+
+// limitsTokens 是否限制 token 数
+func (l *rateLimiter) limitsTokens() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.tokens.limit > 0
+}
+
+// wait 等待限额可用，超出上下文截止时间时立即返回错误
+func (l *rateLimiter) wait(ctx context.Context, tokens int) error {
+	now := time.Now()
+	l.mu.Lock()
+	wait := l.requests.reserve(1, now)
+	if d := l.tokens.reserve(float64(tokens), now); d > wait {
+		wait = d
+	}
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+		l.release(tokens)
+		return runtime_errors.NewLLMError(runtime_errors.ErrRateLimited,
+			fmt.Sprintf("rate limit wait %v exceeds context deadline", wait.Round(time.Millisecond)))
+	}
+
+	runtime.Info(ctx, "Rate limited, waiting", runtime.Attr("wait", wait.Round(time.Millisecond).String()))
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.release(tokens)
+		return ctx.Err()
+	}
+}
+
+// release 归还一次请求预留的令牌
+func (l *rateLimiter) release(tokens int) {
+	l.mu.Lock()
+	l.requests.release(1)
+	l.tokens.release(float64(tokens))
+	l.mu.Unlock()
+}
+
+// update 根据响应头调整限额，429 时按 retry-after 暂停
+func (l *rateLimiter) update(header http.Header, status int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.adaptive || header == nil {
+		return
+	}
+
+	now := time.Now()
+	for _, kind := range []string{"requests", "tokens"} {
+		b := &l.requests
+		if kind == "tokens" {
+			b = &l.tokens
+		}
+		limit, remaining, reset := parseRateLimitHeaders(header, kind, now)
+		b.adapt(limit, remaining, reset, now)
+	}
+
+	if status == http.StatusTooManyRequests {
+		wait := parseRetryAfter(header.Get("retry-after"), now)
+		if wait <= 0 {
+			wait = time.Second
+		}
+		l.requests.block(now.Add(wait))
+		l.tokens.block(now.Add(wait))
+	}
+}
+
+// rateLimitTransport 在流式请求收到响应头时调整限流器
+type rateLimitTransport struct {
+	base    http.RoundTripper
+	limiter *rateLimiter
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(req)
+	if err == nil {
+		t.limiter.update(resp.Header, resp.StatusCode)
+	}
+	return resp, err
+}
+
+// client 返回经过该限流器调整限额的 HTTP 客户端副本
+func (l *rateLimiter) client(c *http.Client) *http.Client {
+	wrapped := *c
+	wrapped.Transport = &rateLimitTransport{base: c.Transport, limiter: l}
+	return &wrapped
+}
+
+// parseRateLimitHeaders 解析 OpenAI（x-ratelimit-*）与 Anthropic（anthropic-ratelimit-*）限流响应头，
+// 缺失的值返回 -1
+func parseRateLimitHeaders(header http.Header, kind string, now time.Time) (limit, remaining float64, reset time.Duration) {
+	number := func(names ...string) float64 {
+		for _, name := range names {
+			if v := header.Get(name); v != "" {
+				if n, err := strconv.ParseFloat(v, 64); err == nil {
+					return n
+				}
+			}
+		}
+		return -1
+	}
+
+	limit = number("x-ratelimit-limit-"+kind, "anthropic-ratelimit-"+kind+"-limit")
+	remaining = number("x-ratelimit-remaining-"+kind, "anthropic-ratelimit-"+kind+"-remaining")
+	if v := header.Get("x-ratelimit-reset-" + kind); v != "" {
+		reset, _ = time.ParseDuration(v)
+	} else if v := header.Get("anthropic-ratelimit-" + kind + "-reset"); v != "" {
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			reset = t.Sub(now)
+		}
+	}
+	return limit, remaining, reset
+}
+
+// parseRetryAfter 解析 retry-after，支持秒数与 HTTP 日期两种格式
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if n, err := strconv.ParseFloat(v, 64); err == nil {
+		return time.Duration(n * float64(time.Second))
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// rateLimiter 按请求地址、API Key 与模型获取限流器
+func (bp *baseProvider) rateLimiter(url string, headers zhttp.Header) *rateLimiter {
+	host := url
+	if u, err := neturl.Parse(url); err == nil && u.Host != "" {
+		host = u.Host
+	}
+
+	key := ""
+	for _, name := range authHeaders {
+		if v := headers[name]; v != "" {
+			key = zstring.Md5(strings.TrimPrefix(v, "Bearer "))
+			break
+		}
+	}
+	return rateLimiterFor(host+"|"+key+"|"+bp.config.Model, bp.config.RateLimit)
+}
+
+// waitRateLimit 发送请求前等待限额
+func (bp *baseProvider) waitRateLimit(ctx context.Context, url string, headers zhttp.Header, body []byte) (*rateLimiter, error) {
+	l := bp.rateLimiter(url, headers)
+	tokens := 0
+	if l.limitsTokens() {
+		tokens = bp.CountTokens(zstring.Bytes2String(body))
+	}
+	return l, l.wait(ctx, tokens)
+}
@@ -0,0 +1,220 @@
+package agent_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sohaha/zlsgo"
+	"github.com/zlsgo/zllm/agent"
+	"github.com/zlsgo/zllm/agent/agenttest"
+	runtime_errors "github.com/zlsgo/zllm/runtime/errors"
+)
+
+func TestRateLimit(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	newServer := func(fn func(w http.ResponseWriter, n int32)) *httptest.Server {
+		var calls int32
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			fn(w, atomic.AddInt32(&calls, 1))
+		}))
+	}
+	newLLM := func(url string, limit agent.RateLimit) agent.LLM {
+		return agent.NewOpenAI(func(o *agent.OpenAIOptions) {
+			o.BaseURL = url
+			o.APIKey = "sk-test"
+			o.RateLimit = limit
+		})
+	}
+	ok := agenttest.OpenAIResponse(agenttest.Text("ok"), "gpt-4o")
+
+	tt.Run("deadline", func(tt *zlsgo.TestUtil) {
+		srv := newServer(func(w http.ResponseWriter, _ int32) { _, _ = w.Write(ok) })
+		defer srv.Close()
+
+		llm := newLLM(srv.URL, agent.RateLimit{RequestsPerMinute: 1})
+		_, err := llm.Generate(context.Background(), []byte("hi"))
+		tt.NoError(err, true)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err = llm.Generate(ctx, []byte("hi"))
+		llmErr, isLLMErr := err.(runtime_errors.LLMError)
+		tt.EqualTrue(isLLMErr)
+		tt.Equal(runtime_errors.ErrRateLimited, llmErr.Code)
+		tt.EqualTrue(time.Since(start) < 50*time.Millisecond)
+	})
+
+	tt.Run("cancel", func(tt *zlsgo.TestUtil) {
+		srv := newServer(func(w http.ResponseWriter, _ int32) { _, _ = w.Write(ok) })
+		defer srv.Close()
+
+		llm := newLLM(srv.URL, agent.RateLimit{TokensPerMinute: 10})
+		_, err := llm.Generate(context.Background(), []byte("hi"))
+		tt.NoError(err, true)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+		_, err = llm.Generate(ctx, []byte("hi"))
+		tt.Equal(context.Canceled, err)
+	})
+
+	tt.Run("adaptive", func(tt *zlsgo.TestUtil) {
+		srv := newServer(func(w http.ResponseWriter, n int32) {
+			if n == 1 {
+				w.Header().Set("x-ratelimit-limit-requests", "100")
+				w.Header().Set("x-ratelimit-remaining-requests", "0")
+				w.Header().Set("x-ratelimit-reset-requests", "200ms")
+			}
+			_, _ = w.Write(ok)
+		})
+		defer srv.Close()
+
+		llm := newLLM(srv.URL, agent.RateLimit{})
+		_, err := llm.Generate(context.Background(), []byte("hi"))
+		tt.NoError(err, true)
+
+		start := time.Now()
+		_, err = llm.Generate(context.Background(), []byte("hi"))
+		tt.NoError(err, true)
+		tt.EqualTrue(time.Since(start) >= 150*time.Millisecond)
+	})
+
+	tt.Run("keep-learned", func(tt *zlsgo.TestUtil) {
+		srv := newServer(func(w http.ResponseWriter, _ int32) {
+			w.Header().Set("x-ratelimit-limit-requests", "1")
+			_, _ = w.Write(ok)
+		})
+		defer srv.Close()
+
+		llm := newLLM(srv.URL, agent.RateLimit{RequestsPerMinute: 1000})
+		for i := 0; i < 2; i++ {
+			_, err := llm.Generate(context.Background(), []byte("hi"))
+			tt.NoError(err, true)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		_, err := llm.Generate(ctx, []byte("hi"))
+		llmErr, isLLMErr := err.(runtime_errors.LLMError)
+		tt.EqualTrue(isLLMErr)
+		tt.Equal(runtime_errors.ErrRateLimited, llmErr.Code)
+	})
+
+	tt.Run("retry-after", func(tt *zlsgo.TestUtil) {
+		srv := newServer(func(w http.ResponseWriter, n int32) {
+			if n == 1 {
+				w.Header().Set("retry-after", "30")
+				w.WriteHeader(http.StatusTooManyRequests)
+				_, _ = w.Write([]byte(`{"error":{"message":"rate limited"}}`))
+				return
+			}
+			_, _ = w.Write(ok)
+		})
+		defer srv.Close()
+
+		llm := newLLM(srv.URL, agent.RateLimit{})
+		_, err := llm.Generate(context.Background(), []byte("hi"))
+		tt.EqualTrue(err != nil)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_, err = llm.Generate(ctx, []byte("hi"))
+		llmErr, isLLMErr := err.(runtime_errors.LLMError)
+		tt.EqualTrue(isLLMErr)
+		tt.Equal(runtime_errors.ErrRateLimited, llmErr.Code)
+	})
+
+	tt.Run("stream", func(tt *zlsgo.TestUtil) {
+		var calls int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.Header().Set("retry-after", "30")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"error":{"message":"rate limited"}}`))
+		}))
+		defer srv.Close()
+
+		llm := newLLM(srv.URL, agent.RateLimit{})
+		done, err := llm.Stream(context.Background(), []byte("hi"), func(string, []byte) {})
+		tt.NoError(err, true)
+		tt.EqualTrue(<-done == nil)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		done, err = llm.Stream(ctx, []byte("hi"), func(string, []byte) {})
+		tt.NoError(err, true)
+		tt.EqualTrue(<-done == nil)
+		tt.Equal(int32(1), atomic.LoadInt32(&calls))
+	})
+
+	tt.Run("stream-adaptive", func(tt *zlsgo.TestUtil) {
+		var calls int32
+		srv := agenttest.NewServer(agenttest.OpenAI, agenttest.Text("ok")).Repeat()
+		defer srv.Close()
+		limited := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.Header().Set("x-ratelimit-limit-requests", "1")
+			srv.Config.Handler.ServeHTTP(w, r)
+		}))
+		defer limited.Close()
+
+		llm := newLLM(limited.URL, agent.RateLimit{})
+		for i := 0; i < 2; i++ {
+			done, err := llm.Stream(context.Background(), []byte("hi"), func(string, []byte) {})
+			tt.NoError(err, true)
+			tt.EqualTrue(<-done != nil)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		done, err := llm.Stream(ctx, []byte("hi"), func(string, []byte) {})
+		tt.NoError(err, true)
+		tt.EqualTrue(<-done == nil)
+		tt.Equal(int32(2), atomic.LoadInt32(&calls))
+	})
+
+	tt.Run("separate-config", func(tt *zlsgo.TestUtil) {
+		srv := newServer(func(w http.ResponseWriter, _ int32) { _, _ = w.Write(ok) })
+		defer srv.Close()
+
+		strict := newLLM(srv.URL, agent.RateLimit{RequestsPerMinute: 1})
+		loose := newLLM(srv.URL, agent.RateLimit{RequestsPerMinute: 1000})
+		_, err := strict.Generate(context.Background(), []byte("hi"))
+		tt.NoError(err, true)
+		for i := 0; i < 3; i++ {
+			_, err = loose.Generate(context.Background(), []byte("hi"))
+			tt.NoError(err, true)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		_, err = strict.Generate(ctx, []byte("hi"))
+		llmErr, isLLMErr := err.(runtime_errors.LLMError)
+		tt.EqualTrue(isLLMErr)
+		tt.Equal(runtime_errors.ErrRateLimited, llmErr.Code)
+	})
+
+	tt.Run("disable-adaptive", func(tt *zlsgo.TestUtil) {
+		srv := newServer(func(w http.ResponseWriter, _ int32) {
+			w.Header().Set("x-ratelimit-remaining-requests", "0")
+			w.Header().Set("x-ratelimit-reset-requests", "30s")
+			_, _ = w.Write(ok)
+		})
+		defer srv.Close()
+
+		llm := newLLM(srv.URL, agent.RateLimit{DisableAdaptive: true})
+		for i := 0; i < 3; i++ {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			_, err := llm.Generate(ctx, []byte("hi"))
+			cancel()
+			tt.NoError(err, true)
+		}
+	})
+}
@@ -0,0 +1,99 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/sohaha/zlsgo/zhttp"
+	"github.com/sohaha/zlsgo/zjson"
+	"github.com/sohaha/zlsgo/ztype"
+	"github.com/zlsgo/zllm/message"
+	"github.com/zlsgo/zllm/runtime"
+)
+
+type reasoningCallbackKey struct{}
+
+// WithReasoningCallback 在上下文中设置流式推理内容回调，参数与流式回调一致：推理片段和原始数据
+func WithReasoningCallback(ctx context.Context, fn func(string, []byte)) context.Context {
+	return context.WithValue(ctx, reasoningCallbackKey{}, fn)
+}
+
+// getReasoningCallback 从上下文中获取流式推理内容回调
+func getReasoningCallback(ctx context.Context) func(string, []byte) {
+	if v, ok := ctx.Value(reasoningCallbackKey{}).(func(string, []byte)); ok {
+		return v
+	}
+	return nil
+}
+
+// WithReasoningEffort 设置 OpenAI 兼容接口的推理强度（reasoning_effort）
+func WithReasoningEffort(effort string) func(ztype.Map) ztype.Map {
+	return func(m ztype.Map) ztype.Map {
+		if effort != "" {
+			m["reasoning_effort"] = effort
+		}
+		return m
+	}
+}
+
+// reasoningStreamProcessor 支持推理内容的流式处理器，ProcessReasoning 返回本事件中的推理片段
+type reasoningStreamProcessor interface {
+	ProcessReasoning(ev *zhttp.SSEEvent) string
+}
+
+// reasoningBuffer 流式推理内容累积器
+type reasoningBuffer struct {
+	text strings.Builder
+	mu   sync.Mutex
+}
+
+func (b *reasoningBuffer) write(s string) string {
+	if s == "" {
+		return s
+	}
+	b.mu.Lock()
+	b.text.WriteString(s)
+	b.mu.Unlock()
+	return s
+}
+
+func (b *reasoningBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.text.String()
+}
+
+// openAIReasoning 提取 OpenAI 兼容格式消息中的推理内容（reasoning_content 或 reasoning）
+func openAIReasoning(msg *zjson.Res) string {
+	if r := msg.Get("reasoning_content").String(); r != "" {
+		return r
+	}
+	if r := msg.Get("reasoning"); r.Exists() && !r.IsObject() {
+		return r.String()
+	}
+	return ""
+}
+
+// splitReasoning 拆分内容开头的 <think> 推理块，返回推理内容与剩余内容
+func splitReasoning(content []byte) ([]byte, []byte) {
+	thinking, rest := runtime.SplitThinking(content)
+	if len(thinking) == 0 {
+		return nil, content
+	}
+	return thinking, rest
+}
+
+// anthropicReasoningBlocks 转换为 Anthropic 内容块，原样回传签名
+func anthropicReasoningBlocks(blocks []message.ReasoningBlock) []ztype.Map {
+	result := make([]ztype.Map, 0, len(blocks))
+	for _, b := range blocks {
+		switch b.Type {
+		case "redacted_thinking":
+			result = append(result, ztype.Map{"type": b.Type, "data": b.Data})
+		case "thinking":
+			result = append(result, ztype.Map{"type": b.Type, "thinking": b.Thinking, "signature": b.Signature})
+		}
+	}
+	return result
+}
@@ -0,0 +1,130 @@
+package agent_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sohaha/zlsgo"
+	"github.com/sohaha/zlsgo/zjson"
+	"github.com/zlsgo/zllm/agent"
+	"github.com/zlsgo/zllm/agent/agenttest"
+	"github.com/zlsgo/zllm/message"
+	"github.com/zlsgo/zllm/runtime"
+)
+
+func TestSplitThinking(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	thinking, content := runtime.SplitThinking([]byte("<think>\nplan\n</think>\n\nanswer"))
+	tt.Equal("plan", string(thinking))
+	tt.Equal("answer", string(content))
+
+	thinking, content = runtime.SplitThinking([]byte("answer"))
+	tt.EqualTrue(thinking == nil)
+	tt.Equal("answer", string(content))
+
+	tt.Equal(`{"a":1}`, string(runtime.ParseContent([]byte("<think>x</think>```json\n{\"a\":1}\n```"))))
+}
+
+func TestReasoning(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	reply := agenttest.Text("answer").WithReasoning("step by step")
+	newLLM := map[agenttest.Protocol]func(url string) agent.LLM{
+		agenttest.OpenAI: func(url string) agent.LLM {
+			return agent.NewOpenAI(func(o *agent.OpenAIOptions) {
+				o.BaseURL, o.APIURL, o.ReasoningEffort = url, "/chat/completions", "low"
+			})
+		},
+		agenttest.OpenAIResponses: func(url string) agent.LLM {
+			return agent.NewOpenAIResponses(func(o *agent.OpenAIResponsesOptions) { o.BaseURL = url })
+		},
+		agenttest.Anthropic: func(url string) agent.LLM {
+			return agent.NewAnthropic(func(o *agent.AnthropicOptions) { o.BaseURL = url })
+		},
+		agenttest.Gemini: func(url string) agent.LLM {
+			return agent.NewGemini(func(o *agent.GeminiOptions) { o.BaseURL = url })
+		},
+	}
+
+	for protocol, fn := range newLLM {
+		srv := agenttest.NewServer(protocol).Push(reply).Repeat()
+		llm := fn(srv.URL)
+
+		res, err := llm.Generate(context.Background(), []byte("hi"))
+		tt.NoError(err, true)
+		resp, err := llm.ParseResponse(res)
+		tt.NoError(err, true)
+		tt.Log(protocol)
+		tt.Equal("answer", string(resp.Content))
+		tt.Equal("step by step", string(resp.Reasoning))
+
+		var streamed strings.Builder
+		ctx := agent.WithReasoningCallback(context.Background(), func(s string, _ []byte) {
+			streamed.WriteString(s)
+		})
+		done, err := llm.Stream(ctx, []byte("hi"), func(string, []byte) {})
+		tt.NoError(err, true)
+		res = <-done
+		tt.EqualTrue(res != nil)
+		resp, err = llm.ParseResponse(res)
+		tt.NoError(err, true)
+		tt.Equal("answer", string(resp.Content))
+		tt.Equal("step by step", string(resp.Reasoning))
+		tt.Equal("step by step", streamed.String())
+
+		if protocol == agenttest.OpenAI {
+			tt.Equal("low", zjson.GetBytes(srv.Requests()[0].Body, "reasoning_effort").String())
+		}
+		srv.Close()
+	}
+
+	llm := agent.NewOpenAI()
+	resp, err := llm.ParseResponse(zjson.ParseBytes(agenttest.OpenAIResponse(agenttest.Text("<think>why</think>ok"), "m")))
+	tt.NoError(err, true)
+	tt.Equal("why", string(resp.Reasoning))
+	tt.Equal("ok", string(resp.Content))
+}
+
+func TestAnthropicThinking(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	srv := agenttest.NewServer(agenttest.Anthropic, agenttest.ToolCall("weather", `{"city":"sz"}`).WithReasoning("need weather"))
+	defer srv.Close()
+
+	llm := agent.NewAnthropic(func(o *agent.AnthropicOptions) {
+		o.BaseURL = srv.URL
+		o.MaxTokens = 1024
+		o.ThinkingBudget = 2048
+	})
+
+	res, err := llm.Generate(context.Background(), []byte("weather?"))
+	tt.NoError(err, true)
+	resp, err := llm.ParseResponse(res)
+	tt.NoError(err, true)
+	tt.Equal(1, len(resp.Tools))
+	tt.Equal("weather", resp.Tools[0].Name)
+	tt.Equal(1, len(resp.ReasoningBlocks))
+	tt.Equal(agenttest.MockSignature, resp.ReasoningBlocks[0].Signature)
+
+	sent := zjson.ParseBytes(srv.Requests()[0].Body)
+	tt.Equal("enabled", sent.Get("thinking.type").String())
+	tt.Equal(2048, sent.Get("thinking.budget_tokens").Int())
+	tt.EqualTrue(sent.Get("max_tokens").Int() > 2048)
+	tt.EqualTrue(!sent.Get("temperature").Exists())
+
+	msg := message.NewMessages()
+	_ = msg.AppendUser("weather?")
+	_ = msg.Append(message.Message{Role: message.RoleAssistant, Content: "calling weather", Reasoning: resp.ReasoningBlocks})
+	_ = msg.AppendUser("sunny")
+	body, err := llm.PrepareRequest(msg)
+	tt.NoError(err, true)
+
+	assistant := zjson.GetBytes(body, "messages.1")
+	tt.Equal("assistant", assistant.Get("role").String())
+	tt.Equal("thinking", assistant.Get("content.0.type").String())
+	tt.Equal("need weather", assistant.Get("content.0.thinking").String())
+	tt.Equal(agenttest.MockSignature, assistant.Get("content.0.signature").String())
+	tt.Equal("calling weather", assistant.Get("content.1.text").String())
+}
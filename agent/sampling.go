@@ -0,0 +1,210 @@
+package agent
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sohaha/zlsgo/ztype"
+	runtime_errors "github.com/zlsgo/zllm/runtime/errors"
+)
+
+// Sampling 通用生成参数，零值表示不发送，由各提供商在 PrepareRequest 中映射为对应字段
+type Sampling struct {
+	MaxTokens        int                // 最大输出 token 数
+	TopP             float64            // 核采样参数（0.0-1.0）
+	TopK             int                // 仅从概率最高的 K 个候选词中采样
+	Stop             []string           // 停止序列
+	Seed             *int               // 随机种子
+	PresencePenalty  float64            // 存在惩罚（-2.0-2.0）
+	FrequencyPenalty float64            // 频率惩罚（-2.0-2.0）
+	LogitBias        map[string]float64 // token 偏置
+	N                int                // 候选数量
+}
+
+// 通用参数名称
+const (
+	paramMaxTokens        = "max_tokens"
+	paramTopP             = "top_p"
+	paramTopK             = "top_k"
+	paramStop             = "stop"
+	paramSeed             = "seed"
+	paramPresencePenalty  = "presence_penalty"
+	paramFrequencyPenalty = "frequency_penalty"
+	paramLogitBias        = "logit_bias"
+	paramN                = "n"
+)
+
+// 各提供商的参数映射，键为通用参数名，值为请求体中的路径（支持一级嵌套）
+var (
+	openAISampling = map[string]string{
+		paramMaxTokens:        "max_tokens",
+		paramTopP:             "top_p",
+		paramStop:             "stop",
+		paramSeed:             "seed",
+		paramPresencePenalty:  "presence_penalty",
+		paramFrequencyPenalty: "frequency_penalty",
+		paramLogitBias:        "logit_bias",
+		paramN:                "n",
+	}
+	deepseekSampling = map[string]string{
+		paramMaxTokens:        "max_tokens",
+		paramTopP:             "top_p",
+		paramStop:             "stop",
+		paramPresencePenalty:  "presence_penalty",
+		paramFrequencyPenalty: "frequency_penalty",
+	}
+	responsesSampling = map[string]string{
+		paramMaxTokens: "max_output_tokens",
+		paramTopP:      "top_p",
+	}
+	anthropicSampling = map[string]string{
+		paramMaxTokens: "max_tokens",
+		paramTopP:      "top_p",
+		paramTopK:      "top_k",
+		paramStop:      "stop_sequences",
+	}
+	geminiSampling = map[string]string{
+		paramMaxTokens:        "generationConfig.maxOutputTokens",
+		paramTopP:             "generationConfig.topP",
+		paramTopK:             "generationConfig.topK",
+		paramStop:             "generationConfig.stopSequences",
+		paramSeed:             "generationConfig.seed",
+		paramPresencePenalty:  "generationConfig.presencePenalty",
+		paramFrequencyPenalty: "generationConfig.frequencyPenalty",
+		paramN:                "generationConfig.candidateCount",
+	}
+	ollamaSampling = map[string]string{
+		paramMaxTokens:        "options.num_predict",
+		paramTopP:             "options.top_p",
+		paramTopK:             "options.top_k",
+		paramStop:             "options.stop",
+		paramSeed:             "options.seed",
+		paramPresencePenalty:  "options.presence_penalty",
+		paramFrequencyPenalty: "options.frequency_penalty",
+	}
+)
+
+// WithSampling 设置通用生成参数
+func (c Config) WithSampling(s Sampling) Config {
+	c.Sampling = s
+	return c
+}
+
+// IsZero 是否未设置任何参数
+func (s Sampling) IsZero() bool {
+	return len(s.values()) == 0
+}
+
+// values 返回已设置的参数
+func (s Sampling) values() map[string]any {
+	v := make(map[string]any)
+	if s.MaxTokens > 0 {
+		v[paramMaxTokens] = s.MaxTokens
+	}
+	if s.TopP > 0 {
+		v[paramTopP] = s.TopP
+	}
+	if s.TopK > 0 {
+		v[paramTopK] = s.TopK
+	}
+	if len(s.Stop) > 0 {
+		v[paramStop] = s.Stop
+	}
+	if s.Seed != nil {
+		v[paramSeed] = *s.Seed
+	}
+	if s.PresencePenalty != 0 {
+		v[paramPresencePenalty] = s.PresencePenalty
+	}
+	if s.FrequencyPenalty != 0 {
+		v[paramFrequencyPenalty] = s.FrequencyPenalty
+	}
+	if len(s.LogitBias) > 0 {
+		v[paramLogitBias] = s.LogitBias
+	}
+	if s.N > 0 {
+		v[paramN] = s.N
+	}
+	return v
+}
+
+// Validate 校验参数取值范围
+func (s Sampling) Validate() error {
+	var invalid []string
+	if s.MaxTokens < 0 {
+		invalid = append(invalid, "max_tokens must be >= 0")
+	}
+	if s.TopP < 0 || s.TopP > 1 {
+		invalid = append(invalid, "top_p must be between 0 and 1")
+	}
+	if s.TopK < 0 {
+		invalid = append(invalid, "top_k must be >= 0")
+	}
+	if s.PresencePenalty < -2 || s.PresencePenalty > 2 {
+		invalid = append(invalid, "presence_penalty must be between -2 and 2")
+	}
+	if s.FrequencyPenalty < -2 || s.FrequencyPenalty > 2 {
+		invalid = append(invalid, "frequency_penalty must be between -2 and 2")
+	}
+	if s.N < 0 {
+		invalid = append(invalid, "n must be >= 0")
+	}
+	if len(invalid) > 0 {
+		return runtime_errors.NewLLMError(runtime_errors.ErrInvalidRequest, "invalid sampling parameters: "+strings.Join(invalid, "; "))
+	}
+	return nil
+}
+
+// samplingOption 校验通用生成参数并返回写入请求体的选项，提供商不支持的参数返回错误
+func (bp *baseProvider) samplingOption(provider string, fields map[string]string) (func(ztype.Map) ztype.Map, error) {
+	s := bp.config.Sampling
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+
+	values := s.values()
+	var unsupported []string
+	for k := range values {
+		if _, ok := fields[k]; !ok {
+			unsupported = append(unsupported, k)
+		}
+	}
+	if len(unsupported) > 0 {
+		sort.Strings(unsupported)
+		return nil, runtime_errors.NewLLMError(runtime_errors.ErrInvalidRequest,
+			fmt.Sprintf("%s does not support sampling parameters: %s", provider, strings.Join(unsupported, ", ")))
+	}
+	if s.N > 1 && bp.config.Stream {
+		return nil, runtime_errors.NewLLMError(runtime_errors.ErrInvalidRequest,
+			fmt.Sprintf("%s: n > 1 is not supported with streaming", provider))
+	}
+
+	return func(m ztype.Map) ztype.Map {
+		for k, v := range values {
+			setRequestPath(m, fields[k], v)
+		}
+		return m
+	}, nil
+}
+
+// setRequestPath 按路径写入请求体，支持一级嵌套
+func setRequestPath(m ztype.Map, path string, v any) {
+	parent, key, ok := strings.Cut(path, ".")
+	if !ok {
+		m[path] = v
+		return
+	}
+
+	var child ztype.Map
+	switch c := m[parent].(type) {
+	case ztype.Map:
+		child = c
+	case map[string]any:
+		child = c
+	default:
+		child = ztype.Map{}
+	}
+	child[key] = v
+	m[parent] = child
+}
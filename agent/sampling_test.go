@@ -0,0 +1,90 @@
+package agent_test
+
+import (
+	"testing"
+
+	"github.com/sohaha/zlsgo"
+	"github.com/sohaha/zlsgo/zjson"
+	"github.com/zlsgo/zllm/agent"
+	"github.com/zlsgo/zllm/message"
+	runtime_errors "github.com/zlsgo/zllm/runtime/errors"
+)
+
+func TestSampling(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	seed := 7
+	full := agent.Sampling{
+		MaxTokens:        256,
+		TopP:             0.8,
+		TopK:             40,
+		Stop:             []string{"END"},
+		Seed:             &seed,
+		PresencePenalty:  0.5,
+		FrequencyPenalty: 0.2,
+	}
+	common := agent.Sampling{MaxTokens: 256, TopP: 0.8, Stop: []string{"END"}}
+
+	msg := message.NewMessages()
+	_ = msg.AppendUser("hi")
+	prepare := func(llm agent.LLM) *zjson.Res {
+		body, err := llm.PrepareRequest(msg)
+		tt.NoError(err, true)
+		return zjson.ParseBytes(body)
+	}
+
+	body := prepare(agent.NewOpenAI(func(o *agent.OpenAIOptions) {
+		o.Sampling = common
+		o.Sampling.Seed = &seed
+		o.Sampling.N = 2
+		o.Sampling.LogitBias = map[string]float64{"50256": -100}
+	}))
+	tt.Equal(256, body.Get("max_tokens").Int())
+	tt.Equal(0.8, body.Get("top_p").Float())
+	tt.Equal("END", body.Get("stop.0").String())
+	tt.Equal(7, body.Get("seed").Int())
+	tt.Equal(2, body.Get("n").Int())
+	tt.Equal(-100.0, body.Get("logit_bias.50256").Float())
+
+	body = prepare(agent.NewOllama(func(o *agent.OllamaOptions) { o.Sampling = full }))
+	tt.Equal(256, body.Get("options.num_predict").Int())
+	tt.Equal(40, body.Get("options.top_k").Int())
+	tt.Equal(7, body.Get("options.seed").Int())
+	tt.Equal("END", body.Get("options.stop.0").String())
+
+	body = prepare(agent.NewGemini(func(o *agent.GeminiOptions) { o.Sampling = full }))
+	tt.Equal(256, body.Get("generationConfig.maxOutputTokens").Int())
+	tt.Equal(40, body.Get("generationConfig.topK").Int())
+	tt.Equal("END", body.Get("generationConfig.stopSequences.0").String())
+	tt.Equal(0.5, body.Get("generationConfig.temperature").Float())
+
+	body = prepare(agent.NewAnthropic(func(o *agent.AnthropicOptions) {
+		o.Sampling = agent.Sampling{MaxTokens: 512, TopK: 10, Stop: []string{"END"}}
+	}))
+	tt.Equal(512, body.Get("max_tokens").Int())
+	tt.Equal(10, body.Get("top_k").Int())
+	tt.Equal("END", body.Get("stop_sequences.0").String())
+
+	body = prepare(agent.NewOpenAIResponses(func(o *agent.OpenAIResponsesOptions) { o.Sampling = agent.Sampling{MaxTokens: 256, TopP: 0.8} }))
+	tt.Equal(256, body.Get("max_output_tokens").Int())
+
+	_, err := agent.NewAnthropic(func(o *agent.AnthropicOptions) { o.Sampling = full }).PrepareRequest(msg)
+	tt.EqualTrue(err != nil)
+	tt.Equal("anthropic does not support sampling parameters: frequency_penalty, presence_penalty, seed", err.Error())
+	llmErr, ok := err.(runtime_errors.LLMError)
+	tt.EqualTrue(ok)
+	tt.Equal(runtime_errors.ErrInvalidRequest, llmErr.Code)
+
+	_, err = agent.NewDeepseek(func(o *agent.DeepseekOptions) { o.Sampling = agent.Sampling{TopK: 5} }).PrepareRequest(msg)
+	tt.EqualTrue(err != nil)
+
+	_, err = agent.NewOpenAI(func(o *agent.OpenAIOptions) { o.Sampling = agent.Sampling{TopP: 1.5} }).PrepareRequest(msg)
+	tt.EqualTrue(err != nil)
+
+	_, err = agent.NewDeepseek(func(o *agent.DeepseekOptions) {
+		o.Stream = true
+		o.Sampling = agent.Sampling{MaxTokens: 10}
+	}).PrepareRequest(msg)
+	tt.NoError(err)
+	tt.EqualTrue(agent.Sampling{}.IsZero())
+}
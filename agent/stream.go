@@ -3,12 +3,16 @@ package agent
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/sohaha/zlsgo/zhttp"
 	"github.com/sohaha/zlsgo/zjson"
 	"github.com/sohaha/zlsgo/zstring"
+	"github.com/sohaha/zlsgo/ztype"
+	"github.com/zlsgo/zllm/message"
 	"github.com/zlsgo/zllm/runtime"
 )
 
@@ -32,7 +36,9 @@ func processOpenAIStream(ctx context.Context, sse *zhttp.SSEEngine, config *stre
 	return processStreamGeneric(ctx, sse, config, processor, timeout)
 }
 
-type openAIStreamProcessor struct{}
+type openAIStreamProcessor struct {
+	reasoning reasoningBuffer
+}
 
 func (p *openAIStreamProcessor) ProcessMessage(ev *zhttp.SSEEvent, config *streamConfig) (bool, string) {
 	if bytes.Equal(ev.Data, []byte("[DONE]")) {
@@ -43,21 +49,28 @@ func (p *openAIStreamProcessor) ProcessMessage(ev *zhttp.SSEEvent, config *strea
 	return false, content
 }
 
+// ProcessReasoning 提取 reasoning_content / reasoning 增量
+func (p *openAIStreamProcessor) ProcessReasoning(ev *zhttp.SSEEvent) string {
+	delta := zjson.GetBytes(ev.Data, "choices.0.delta")
+	if !delta.Exists() {
+		return ""
+	}
+	return p.reasoning.write(openAIReasoning(delta))
+}
+
 func (p *openAIStreamProcessor) BuildResponse(rawMessage []byte, result string) *zjson.Res {
 	choice := zjson.GetBytes(rawMessage, "choices.0")
 	_ = choice.Delete("delta")
 	_ = choice.Set("message.content", result)
 	_ = choice.Set("message.role", "assistant")
 	_ = choice.Set("message.finish_reason", "stop")
+	if reasoning := p.reasoning.String(); reasoning != "" {
+		_ = choice.Set("message.reasoning_content", reasoning)
+	}
 	json, _ := zjson.SetRawBytes(rawMessage, "choices.0", choice.Bytes())
 	return zjson.ParseBytes(json)
 }
 
-func processOllamaStream(ctx context.Context, sse *zhttp.SSEEngine, config *streamConfig, timeout time.Duration) (*zjson.Res, error) {
-	processor := &ollamaStreamProcessor{}
-	return processStreamGeneric(ctx, sse, config, processor, timeout)
-}
-
 // processAnthropicStream 处理Anthropic流
 func processAnthropicStream(ctx context.Context, sse *zhttp.SSEEngine, config *streamConfig, timeout time.Duration) (*zjson.Res, error) {
 	processor := &anthropicStreamProcessor{}
@@ -72,13 +85,15 @@ func processGeminiStream(ctx context.Context, sse *zhttp.SSEEngine, config *stre
 
 func processStreamGeneric(ctx context.Context, sse *zhttp.SSEEngine, config *streamConfig, processor streamProcessor, timeout time.Duration) (*zjson.Res, error) {
 	var (
-		rawMessage []byte
-		result     = zstring.Buffer()
+		rawMessage  []byte
+		result      = zstring.Buffer()
+		onReasoning = getReasoningCallback(ctx)
 	)
+	reasoner, _ := processor.(reasoningStreamProcessor)
 
 	defer func() {
 		if r := recover(); r != nil {
-			runtime.Log("Stream processing panic:", r)
+			runtime.Error(ctx, "Stream processing panic", runtime.Attr("panic", r))
 		}
 		if sse != nil {
 			sse.Close()
@@ -88,10 +103,23 @@ func processStreamGeneric(ctx context.Context, sse *zhttp.SSEEngine, config *str
 	done, processErr := sse.OnMessage(func(ev *zhttp.SSEEvent) {
 		defer func() {
 			if r := recover(); r != nil {
-				runtime.Log("SSE event processing panic:", r)
+				runtime.Error(ctx, "SSE event processing panic", runtime.Attr("panic", r))
 			}
 		}()
 
+		if reasoner != nil {
+			if reasoning := reasoner.ProcessReasoning(ev); reasoning != "" && onReasoning != nil {
+				func() {
+					defer func() {
+						if r := recover(); r != nil {
+							runtime.Error(ctx, "Reasoning callback panic", runtime.Attr("panic", r))
+						}
+					}()
+					onReasoning(reasoning, ev.Data)
+				}()
+			}
+		}
+
 		isDone, content := processor.ProcessMessage(ev, config)
 
 		if isDone {
@@ -109,7 +137,7 @@ func processStreamGeneric(ctx context.Context, sse *zhttp.SSEEngine, config *str
 				func() {
 					defer func() {
 						if r := recover(); r != nil {
-							runtime.Log("Stream callback panic:", r)
+							runtime.Error(ctx, "Stream callback panic", runtime.Attr("panic", r))
 						}
 					}()
 					config.OnMessage(content, ev.Data)
@@ -144,7 +172,7 @@ func processStreamGeneric(ctx context.Context, sse *zhttp.SSEEngine, config *str
 		return nil, fmt.Errorf("stream ended unexpectedly")
 	case <-timeoutCtx.Done():
 		if timeoutCtx.Err() == context.DeadlineExceeded {
-			runtime.Log("Stream processing timeout")
+			runtime.Warn(ctx, "Stream processing timeout", runtime.Attr("timeout", timeout.String()))
 			return nil, fmt.Errorf("stream processing timeout")
 		}
 		sse.Close()
@@ -152,38 +180,45 @@ func processStreamGeneric(ctx context.Context, sse *zhttp.SSEEngine, config *str
 	}
 }
 
-type ollamaStreamProcessor struct{}
-
-func (p *ollamaStreamProcessor) ProcessMessage(ev *zhttp.SSEEvent, config *streamConfig) (bool, string) {
-	values := bytes.Split(ev.Undefined, []byte("\n"))
-	for i := range values {
-		j := zjson.ParseBytes(values[i])
+// anthropicStreamProcessor Anthropic 流式处理器实现
+// 事件类型：message_start, content_block_start, content_block_delta(text/thinking/signature), content_block_stop, message_delta(stop_reason), message_stop
+type anthropicStreamProcessor struct {
+	blocks []message.ReasoningBlock
+	mu     sync.Mutex
+}
 
-		if j.Get("done").Bool() {
-			return true, ""
+// ProcessReasoning 累积 thinking / redacted_thinking 块及其签名
+func (p *anthropicStreamProcessor) ProcessReasoning(ev *zhttp.SSEEvent) string {
+	data := zjson.ParseBytes(ev.Data)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch data.Get("type").String() {
+	case "content_block_start":
+		block := data.Get("content_block")
+		switch t := block.Get("type").String(); t {
+		case "thinking":
+			p.blocks = append(p.blocks, message.ReasoningBlock{Type: t, Thinking: block.Get("thinking").String()})
+		case "redacted_thinking":
+			p.blocks = append(p.blocks, message.ReasoningBlock{Type: t, Data: block.Get("data").String()})
 		}
-
-		content := j.Get("message").Get("content").String()
-		if content != "" {
-			return false, content
+	case "content_block_delta":
+		if len(p.blocks) == 0 {
+			return ""
+		}
+		last := &p.blocks[len(p.blocks)-1]
+		switch data.Get("delta.type").String() {
+		case "thinking_delta":
+			thinking := data.Get("delta.thinking").String()
+			last.Thinking += thinking
+			return thinking
+		case "signature_delta":
+			last.Signature += data.Get("delta.signature").String()
 		}
 	}
-	return false, ""
+	return ""
 }
 
-func (p *ollamaStreamProcessor) BuildResponse(rawMessage []byte, result string) *zjson.Res {
-	choice := zjson.ParseBytes(rawMessage)
-	_ = choice.Set("done", true)
-	_ = choice.Set("message.content", result)
-	_ = choice.Set("message.role", "assistant")
-	_ = choice.Set("done_reason", "stop")
-	return choice
-}
-
-// anthropicStreamProcessor Anthropic 流式处理器实现
-// 事件类型：message_start, content_block_start, content_block_delta(text), content_block_stop, message_delta(stop_reason), message_stop
-type anthropicStreamProcessor struct{}
-
 func (p *anthropicStreamProcessor) ProcessMessage(ev *zhttp.SSEEvent, config *streamConfig) (bool, string) {
 	t := zjson.GetBytes(ev.Data, "type").String()
 	switch t {
@@ -207,10 +242,15 @@ func (p *anthropicStreamProcessor) ProcessMessage(ev *zhttp.SSEEvent, config *st
 
 func (p *anthropicStreamProcessor) BuildResponse(rawMessage []byte, result string) *zjson.Res {
 	// 使用 map 构建，后续 ParseBytes 转换
+	p.mu.Lock()
+	content := make([]ztype.Map, 0, len(p.blocks)+1)
+	content = append(content, anthropicReasoningBlocks(p.blocks)...)
+	p.mu.Unlock()
+
 	m := map[string]any{
 		"type":    "message",
 		"role":    "assistant",
-		"content": []map[string]any{{"type": "text", "text": result}},
+		"content": append(content, ztype.Map{"type": "text", "text": result}),
 	}
 	b, _ := zjson.Marshal(m)
 	return zjson.ParseBytes(b)
@@ -218,7 +258,21 @@ func (p *anthropicStreamProcessor) BuildResponse(rawMessage []byte, result strin
 
 // geminiStreamProcessor Gemini 流式处理器实现
 // Gemini 流式响应格式: {"candidates": [{"content": {"parts": [{"text": "..."}], "role": "model"}}]}
-type geminiStreamProcessor struct{}
+type geminiStreamProcessor struct {
+	reasoning     reasoningBuffer
+	finishReason  string
+	safetyRatings json.RawMessage
+	mu            sync.Mutex
+}
+
+// ProcessReasoning 提取 thought 为 true 的思考摘要片段
+func (p *geminiStreamProcessor) ProcessReasoning(ev *zhttp.SSEEvent) string {
+	if len(ev.Data) == 0 {
+		return ""
+	}
+	thought, _ := geminiParts(zjson.GetBytes(ev.Data, "candidates.0.content.parts"))
+	return p.reasoning.write(thought)
+}
 
 func (p *geminiStreamProcessor) ProcessMessage(ev *zhttp.SSEEvent, config *streamConfig) (bool, string) {
 	// 检查是否为结束标记
@@ -228,17 +282,26 @@ func (p *geminiStreamProcessor) ProcessMessage(ev *zhttp.SSEEvent, config *strea
 
 	data := zjson.ParseBytes(ev.Data)
 
-	// 提取文本内容
-	content := data.Get("candidates.0.content.parts.0.text")
-	if content.Exists() {
-		text := content.String()
-		if text != "" {
-			return false, text
+	// 记录结束原因与安全评级，用于构建最终响应
+	finishReason := data.Get("candidates.0.finishReason")
+	if ratings := data.Get("candidates.0.safetyRatings"); ratings.Exists() || finishReason.Exists() {
+		p.mu.Lock()
+		if finishReason.Exists() {
+			p.finishReason = finishReason.String()
+		}
+		if ratings.Exists() {
+			p.safetyRatings = json.RawMessage(ratings.Raw())
 		}
+		p.mu.Unlock()
+	}
+
+	// 提取文本内容（跳过思考片段）
+	if _, text := geminiParts(data.Get("candidates.0.content.parts")); text != "" {
+		return false, text
 	}
 
 	// 检查完成标志
-	if data.Get("candidates.0.finishReason").Exists() {
+	if finishReason.Exists() {
 		return true, ""
 	}
 
@@ -246,20 +309,31 @@ func (p *geminiStreamProcessor) ProcessMessage(ev *zhttp.SSEEvent, config *strea
 }
 
 func (p *geminiStreamProcessor) BuildResponse(rawMessage []byte, result string) *zjson.Res {
+	parts := []map[string]any{{"text": result}}
+	if reasoning := p.reasoning.String(); reasoning != "" {
+		parts = append([]map[string]any{{"text": reasoning, "thought": true}}, parts...)
+	}
+
+	p.mu.Lock()
+	candidate := map[string]any{
+		"content": map[string]any{
+			"parts": parts,
+			"role":  "model",
+		},
+		"finishReason": "STOP",
+		"index":        0,
+	}
+	if p.finishReason != "" {
+		candidate["finishReason"] = p.finishReason
+	}
+	if len(p.safetyRatings) > 0 {
+		candidate["safetyRatings"] = p.safetyRatings
+	}
+	p.mu.Unlock()
+
 	// 构建 Gemini 响应格式
 	m := map[string]any{
-		"candidates": []map[string]any{
-			{
-				"content": map[string]any{
-					"parts": []map[string]any{
-						{"text": result},
-					},
-					"role": "model",
-				},
-				"finishReason": "STOP",
-				"index":        0,
-			},
-		},
+		"candidates": []map[string]any{candidate},
 		"usageMetadata": map[string]any{
 			"promptTokenCount":     0,
 			"candidatesTokenCount": 0,
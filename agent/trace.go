@@ -0,0 +1,206 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	neturl "net/url"
+	"time"
+
+	"github.com/sohaha/zlsgo/zjson"
+	"github.com/sohaha/zlsgo/zstring"
+	"github.com/zlsgo/zllm/runtime"
+	runtime_errors "github.com/zlsgo/zllm/runtime/errors"
+)
+
+// traceSystem 返回追踪中使用的 gen_ai.system 名称
+func traceSystem(config providerConfig) string {
+	switch c := config.(type) {
+	case *CompatibleOptions:
+		return c.providerName()
+	case *DeepseekOptions:
+		return "deepseek"
+	case *AzureOptions:
+		return "az.ai.openai"
+	case *GeminiOptions:
+		return "gcp.gemini"
+	}
+	if p := config.getStreamProcessor(); p != "openai_responses" {
+		return p
+	}
+	return "openai"
+}
+
+// chatCall 一次模型请求的日志与追踪信息
+type chatCall struct {
+	start  time.Time
+	span   runtime.Span
+	system string
+	model  string
+}
+
+// beginChat 记录请求日志并开始追踪片段，model 为请求体未指定模型时的默认值
+func beginChat(ctx context.Context, system, model, url string, headers map[string]string, body []byte, stream bool) (context.Context, *chatCall) {
+	if m := zjson.GetBytes(body, "model").String(); m != "" {
+		model = m
+	}
+	call := &chatCall{start: time.Now(), system: system, model: model}
+
+	if runtime.LogEnabled(ctx, runtime.LevelDebug) {
+		runtime.Debug(ctx, "LLM request",
+			runtime.Attr(runtime.KeyProvider, system), runtime.Attr(runtime.KeyModel, model),
+			runtime.Attr("stream", stream), runtime.Attr("url", url),
+			runtime.HeadersAttr(headers), runtime.ContentAttr(runtime.KeyPrompt, zstring.Bytes2String(body)))
+	}
+	if !runtime.Tracing() {
+		return ctx, call
+	}
+
+	req := zjson.ParseBytes(body)
+	attrs := []runtime.Attribute{
+		runtime.Attr(runtime.AttrOperationName, runtime.OperationChat),
+		runtime.Attr(runtime.AttrSystem, system),
+		runtime.Attr(runtime.AttrRequestModel, model),
+		runtime.Attr(runtime.AttrStream, stream),
+	}
+	if u, err := neturl.Parse(url); err == nil && u.Host != "" {
+		attrs = append(attrs, runtime.Attr(runtime.AttrServerAddress, u.Hostname()))
+	}
+	if t := firstExists(req, "temperature", "generationConfig.temperature", "options.temperature"); t != nil {
+		attrs = append(attrs, runtime.Attr(runtime.AttrRequestTemperature, t.Float()))
+	}
+	if n := firstExists(req, "max_tokens", "max_completion_tokens", "max_output_tokens",
+		"generationConfig.maxOutputTokens", "options.num_predict"); n != nil {
+		attrs = append(attrs, runtime.Attr(runtime.AttrRequestMaxTokens, n.Int()))
+	}
+	if runtime.CaptureContent() {
+		attrs = append(attrs, runtime.Attr(runtime.AttrPrompt, string(body)))
+	}
+
+	name := runtime.OperationChat
+	if model != "" {
+		name += " " + model
+	}
+	ctx, call.span = runtime.StartSpan(ctx, name, runtime.SpanKindClient, attrs...)
+	return ctx, call
+}
+
+// finishChat 记录响应日志，并在追踪片段中记录响应模型、token 用量与结束原因后结束
+func finishChat(ctx context.Context, call *chatCall, res *zjson.Res, status int, err error) {
+	logChat(ctx, call, res, status, err)
+	if call.span == nil {
+		return
+	}
+
+	var attrs []runtime.Attribute
+	if status > 0 {
+		attrs = append(attrs, runtime.Attr(runtime.AttrHTTPStatusCode, status))
+	}
+	if res != nil && res.Exists() {
+		if v := firstExists(res, "model", "modelVersion"); v != nil {
+			attrs = append(attrs, runtime.Attr(runtime.AttrResponseModel, v.String()))
+		}
+		if v := firstExists(res, "id", "responseId"); v != nil {
+			attrs = append(attrs, runtime.Attr(runtime.AttrResponseID, v.String()))
+		}
+		if v := firstExists(res, inputTokenPaths...); v != nil {
+			attrs = append(attrs, runtime.Attr(runtime.AttrUsageInputTokens, v.Int()))
+		}
+		if v := firstExists(res, outputTokenPaths...); v != nil {
+			attrs = append(attrs, runtime.Attr(runtime.AttrUsageOutputTokens, v.Int()))
+		}
+		if reasons := finishReasons(res); len(reasons) > 0 {
+			attrs = append(attrs, runtime.Attr(runtime.AttrResponseFinish, reasons))
+		}
+		if runtime.CaptureContent() {
+			attrs = append(attrs, runtime.Attr(runtime.AttrCompletion, res.String()))
+		}
+	}
+	call.span.SetAttributes(attrs...)
+	runtime.EndSpan(call.span, err)
+}
+
+// logChat 记录一次模型请求的结果，失败时以警告级别记录
+func logChat(ctx context.Context, call *chatCall, res *zjson.Res, status int, err error) {
+	level := runtime.LevelDebug
+	if err != nil {
+		level = runtime.LevelWarn
+	}
+	if !runtime.LogEnabled(ctx, level) {
+		return
+	}
+
+	attrs := []runtime.Attribute{
+		runtime.Attr(runtime.KeyProvider, call.system),
+		runtime.Attr(runtime.KeyModel, call.model),
+		runtime.Attr(runtime.KeyLatency, time.Since(call.start).Round(time.Millisecond).String()),
+	}
+	if status > 0 {
+		attrs = append(attrs, runtime.Attr(runtime.KeyStatus, status))
+	}
+	if res != nil && res.Exists() {
+		if v := firstExists(res, "id", "responseId"); v != nil {
+			attrs = append(attrs, runtime.Attr(runtime.KeyRequestID, v.String()))
+		}
+	}
+	if err != nil {
+		attrs = append(attrs, runtime.Attr(runtime.KeyErrorCode, ErrorCode(err).String()), runtime.ErrAttr(err))
+		runtime.Warn(ctx, "LLM request failed", attrs...)
+		return
+	}
+	if res != nil {
+		attrs = append(attrs, runtime.ContentAttr(runtime.KeyResponse, res.String()))
+	}
+	runtime.Debug(ctx, "LLM response", attrs...)
+}
+
+// 各协议响应中 token 用量所在字段
+var (
+	inputTokenPaths  = []string{"usage.prompt_tokens", "usage.input_tokens", "usageMetadata.promptTokenCount", "prompt_eval_count"}
+	outputTokenPaths = []string{"usage.completion_tokens", "usage.output_tokens", "usageMetadata.candidatesTokenCount", "eval_count"}
+	cachedTokenPaths = []string{"usage.prompt_tokens_details.cached_tokens", "usage.input_tokens_details.cached_tokens",
+		"usage.cache_read_input_tokens", "usageMetadata.cachedContentTokenCount"}
+)
+
+// finishReasons 提取各协议响应中的结束原因
+func finishReasons(res *zjson.Res) []string {
+	var reasons []string
+	for _, path := range []string{"choices.#.finish_reason", "candidates.#.finishReason"} {
+		res.Get(path).ForEach(func(_, v *zjson.Res) bool {
+			if s := v.String(); s != "" {
+				reasons = append(reasons, s)
+			}
+			return true
+		})
+	}
+	if len(reasons) == 0 {
+		if v := firstExists(res, "stop_reason", "done_reason"); v != nil && v.String() != "" {
+			reasons = append(reasons, v.String())
+		}
+	}
+	return reasons
+}
+
+// firstExists 返回第一个存在的字段
+func firstExists(res *zjson.Res, paths ...string) *zjson.Res {
+	for _, path := range paths {
+		if v := res.Get(path); v.Exists() {
+			return v
+		}
+	}
+	return nil
+}
+
+// ErrorCode 返回错误对应的错误码，上下文超时与取消分别归为 ErrTimeout 与 ErrContextCanceled
+func ErrorCode(err error) runtime_errors.ErrorCode {
+	var llmErr runtime_errors.LLMError
+	switch {
+	case errors.As(err, &llmErr):
+		return llmErr.Code
+	case errors.Is(err, context.DeadlineExceeded):
+		return runtime_errors.ErrTimeout
+	case errors.Is(err, context.Canceled):
+		return runtime_errors.ErrContextCanceled
+	default:
+		return runtime_errors.ErrUnknown
+	}
+}
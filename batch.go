@@ -0,0 +1,254 @@
+package zllm
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sohaha/zlsgo/zstring"
+	"github.com/sohaha/zlsgo/ztype"
+	"github.com/sohaha/zlsgo/zutil"
+	"github.com/zlsgo/zllm/agent"
+	"github.com/zlsgo/zllm/message"
+	"github.com/zlsgo/zllm/runtime"
+	runtime_errors "github.com/zlsgo/zllm/runtime/errors"
+)
+
+// 批量调用默认配置
+const (
+	DefaultBatchConcurrency = 4 // 默认并发数
+	DefaultBatchRetries     = 2 // 默认单条重试次数
+)
+
+// BatchOptions 批量调用配置
+type BatchOptions struct {
+	// OnProgress 每条完成（成功、失败或从断点恢复）后回调，可能被并发调用
+	OnProgress func(BatchProgress)
+	// Checkpoint 断点文件路径，成功结果逐条追加写入，重新运行时跳过已完成且输入未变化的条目
+	Checkpoint string
+	// Options 透传给每次 CompleteLLM 的请求参数
+	Options []func(ztype.Map) ztype.Map
+	// Concurrency 最大并发数
+	Concurrency int
+	// Retries 单条失败后重新调用 CompleteLLM 的次数，仅重试可重试的错误，负数表示不重试；
+	// 每次 CompleteLLM 内部对可重试错误还会最多重试 2 次，默认配置下单条最多发送 (2+1)×3 次请求（不含提供商自身的 MaxRetries）
+	Retries int
+	// RetryInterval 首次重试间隔，之后按次数递增，不超过 MaxRetryInterval
+	RetryInterval time.Duration
+}
+
+// BatchResult 单条结果
+type BatchResult struct {
+	Err      error  // 重试后仍失败的错误
+	Content  string // 响应内容
+	Index    int    // 在输入中的下标
+	Attempts int    // 调用 CompleteLLM 的次数，从断点恢复时为 0
+	Resumed  bool   // 是否从断点文件恢复
+}
+
+// BatchProgress 批量进度
+type BatchProgress struct {
+	Result BatchResult // 刚完成的条目
+	Total  int         // 总条目数
+	Done   int         // 已完成条目数（含失败）
+	Failed int         // 失败条目数
+}
+
+// batchCheckpoint 断点文件中的一行
+type batchCheckpoint struct {
+	Key     string `json:"key"`
+	Content string `json:"content"`
+	Index   int    `json:"index"`
+}
+
+// BatchCompleteLLM 以有限并发批量执行 CompleteLLM
+// 单条失败不会中断其它条目，错误记录在对应结果的 Err 中；仅在断点文件读写失败或上下文取消时返回错误，
+// 此时已完成的结果仍会返回。*message.Messages 条目调用失败时会撤销该次调用追加的工具消息
+func BatchCompleteLLM[T promptMsg](ctx context.Context, llm agent.LLM, msgs []T, opt ...func(*BatchOptions)) ([]BatchResult, error) {
+	o := zutil.Optional(BatchOptions{
+		Concurrency:   DefaultBatchConcurrency,
+		Retries:       DefaultBatchRetries,
+		RetryInterval: SecondRetryInterval,
+	}, opt...)
+	if o.Concurrency <= 0 {
+		o.Concurrency = 1
+	}
+
+	keys := make([]string, len(msgs))
+	for i := range msgs {
+		keys[i] = batchKey(msgs[i])
+	}
+
+	results := make([]BatchResult, len(msgs))
+	for i := range results {
+		results[i].Index = i
+	}
+
+	done, err := loadBatchCheckpoint(o.Checkpoint)
+	if err != nil {
+		return results, err
+	}
+
+	var file *os.File
+	if o.Checkpoint != "" {
+		file, err = os.OpenFile(o.Checkpoint, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return results, err
+		}
+		defer file.Close()
+	}
+
+	var (
+		mu       sync.Mutex
+		progress = BatchProgress{Total: len(msgs)}
+		writeErr error
+	)
+	finish := func(r BatchResult) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		results[r.Index] = r
+		progress.Done++
+		if r.Err != nil {
+			progress.Failed++
+		} else if file != nil && !r.Resumed && writeErr == nil {
+			line, _ := json.Marshal(batchCheckpoint{Index: r.Index, Key: keys[r.Index], Content: r.Content})
+			_, writeErr = file.Write(append(line, '\n'))
+		}
+		progress.Result = r
+		if o.OnProgress != nil {
+			o.OnProgress(progress)
+		}
+	}
+
+	pending := make([]int, 0, len(msgs))
+	for i := range msgs {
+		if c, ok := done[i]; ok && c.Key == keys[i] {
+			finish(BatchResult{Index: i, Content: c.Content, Resumed: true})
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	var (
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, o.Concurrency)
+	)
+	for n, i := range pending {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			mu.Lock()
+			for _, j := range pending[n:] {
+				results[j].Err = ctx.Err()
+			}
+			mu.Unlock()
+			break
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			finish(batchComplete(ctx, llm, msgs[i], i, o))
+		}(i)
+	}
+	wg.Wait()
+
+	if writeErr != nil {
+		return results, writeErr
+	}
+	return results, ctx.Err()
+}
+
+// batchComplete 执行单条请求，可重试的错误按递增间隔重试
+func batchComplete[T promptMsg](ctx context.Context, llm agent.LLM, msg T, index int, o BatchOptions) BatchResult {
+	r := BatchResult{Index: index}
+	history, _ := any(msg).(*message.Messages)
+	for {
+		r.Attempts++
+		n := 0
+		if history != nil {
+			n = history.Len()
+		}
+		r.Content, r.Err = CompleteLLM(ctx, llm, msg, o.Options...)
+		if r.Err != nil && history != nil {
+			// 失败的调用可能已追加工具调用轮次，重试前恢复原始历史
+			history.Truncate(n)
+		}
+		if r.Err == nil || r.Attempts > o.Retries || !isBatchRetryable(r.Err) {
+			return r
+		}
+
+		interval := time.Duration(r.Attempts) * o.RetryInterval
+		if interval > MaxRetryInterval {
+			interval = MaxRetryInterval
+		}
+		runtime.Info(ctx, "Batch item failed, retrying", runtime.Attr("index", index), runtime.Attr("attempt", r.Attempts),
+			runtime.Attr("interval", interval.String()), runtime.Attr(runtime.KeyErrorCode, agent.ErrorCode(r.Err).String()), runtime.ErrAttr(r.Err))
+		agent.NotifyRetry(ctx, agent.Intercept(llm), nil, r.Attempts, r.Err)
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return r
+		}
+	}
+}
+
+// isBatchRetryable 判断批量条目的错误是否值得重试，非 LLMError（如网络、超时）默认重试
+func isBatchRetryable(err error) bool {
+	var llmErr runtime_errors.LLMError
+	if errors.As(err, &llmErr) {
+		return llmErr.IsRetryable()
+	}
+	return true
+}
+
+// batchKey 根据输入内容生成断点校验键，输入变化后断点中的旧结果不再复用
+func batchKey[T promptMsg](msg T) string {
+	switch v := any(msg).(type) {
+	case *message.Prompt:
+		return zstring.Md5(v.String())
+	case *message.Messages:
+		return zstring.Md5(v.Input() + "\n" + v.String())
+	}
+	return ""
+}
+
+// loadBatchCheckpoint 读取断点文件，文件不存在时返回空结果，损坏的行会被忽略
+func loadBatchCheckpoint(path string) (map[int]batchCheckpoint, error) {
+	done := map[int]batchCheckpoint{}
+	if path == "" {
+		return done, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return done, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var c batchCheckpoint
+		if json.Unmarshal(scanner.Bytes(), &c) == nil {
+			done[c.Index] = c
+		}
+	}
+	return done, scanner.Err()
+}
@@ -0,0 +1,121 @@
+package zllm
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sohaha/zlsgo"
+	"github.com/sohaha/zlsgo/ztype"
+	"github.com/zlsgo/zllm/agent/agenttest"
+	"github.com/zlsgo/zllm/message"
+	runtime_errors "github.com/zlsgo/zllm/runtime/errors"
+)
+
+// flakyLLM 前 fails 次构造请求时返回可重试错误
+type flakyLLM struct {
+	*agenttest.MockLLM
+	fails int32
+}
+
+func (f *flakyLLM) PrepareRequest(messages *message.Messages, options ...func(ztype.Map) ztype.Map) ([]byte, error) {
+	if atomic.AddInt32(&f.fails, -1) >= 0 {
+		return nil, runtime_errors.NewLLMError(runtime_errors.ErrServer, "unavailable")
+	}
+	return f.MockLLM.PrepareRequest(messages, options...)
+}
+
+func TestBatchCompleteLLM(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	checkpoint := filepath.Join(t.TempDir(), "batch.jsonl")
+	prompts := []*message.Prompt{message.NewPrompt("a"), message.NewPrompt("b"), message.NewPrompt("c")}
+
+	mock := agenttest.NewMock(agenttest.Text(`{"Assistant":"ok"}`)).Repeat()
+	llm := &flakyLLM{MockLLM: mock, fails: 2}
+
+	var progress []BatchProgress
+	opt := func(o *BatchOptions) {
+		o.Concurrency = 1
+		o.RetryInterval = time.Millisecond
+		o.Checkpoint = checkpoint
+		o.OnProgress = func(p BatchProgress) { progress = append(progress, p) }
+	}
+
+	results, err := BatchCompleteLLM(context.Background(), llm, prompts, opt)
+	tt.NoError(err, true)
+	tt.Equal(3, len(results))
+	tt.Equal(3, results[0].Attempts)
+	for _, r := range results {
+		tt.NoError(r.Err)
+		tt.Equal(`{"Assistant":"ok"}`, r.Content)
+	}
+	tt.Equal(3, len(progress))
+	tt.Equal(3, progress[2].Done)
+	tt.Equal(3, mock.Calls())
+
+	progress = progress[:0]
+	prompts = append(prompts, message.NewPrompt("d"))
+	prompts[1] = message.NewPrompt("b2")
+	results, err = BatchCompleteLLM(context.Background(), llm, prompts, opt)
+	tt.NoError(err, true)
+	tt.EqualTrue(results[0].Resumed && results[2].Resumed)
+	tt.EqualTrue(!results[1].Resumed && !results[3].Resumed)
+	tt.Equal(4, len(progress))
+	tt.Equal(5, mock.Calls())
+
+	failing := agenttest.NewMock(
+		agenttest.Text(`{"Assistant":"ok"}`),
+		agenttest.Error(runtime_errors.NewLLMError(runtime_errors.ErrBadRequest, "bad request")),
+	)
+	results, err = BatchCompleteLLM(context.Background(), failing, prompts[:2], func(o *BatchOptions) { o.Concurrency = 1 })
+	tt.NoError(err, true)
+	tt.NoError(results[0].Err)
+	tt.EqualTrue(results[1].Err != nil)
+	tt.Equal(1, results[1].Attempts)
+
+	parallel := agenttest.NewMock(agenttest.Text(`{"Assistant":"ok"}`).WithDelay(50 * time.Millisecond)).Repeat()
+	many := make([]*message.Prompt, 8)
+	for i := range many {
+		many[i] = message.NewPrompt("p")
+	}
+	start := time.Now()
+	results, err = BatchCompleteLLM(context.Background(), parallel, many, func(o *BatchOptions) { o.Concurrency = 4 })
+	tt.NoError(err, true)
+	tt.Equal(8, parallel.Calls())
+	tt.EqualTrue(time.Since(start) < 300*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results, err = BatchCompleteLLM(ctx, parallel, many)
+	tt.Equal(context.Canceled, err)
+	tt.Equal(context.Canceled, results[7].Err)
+}
+
+func TestBatchRetryRestoresMessages(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	serverErr := runtime_errors.NewLLMError(runtime_errors.ErrServer, "unavailable")
+	llm := agenttest.NewMock(
+		agenttest.ToolCall("echo", `{"text":"hi"}`),
+		agenttest.Error(serverErr),
+		agenttest.Error(serverErr),
+		agenttest.Text(`{"Assistant":"ok"}`),
+	)
+
+	msg := message.NewMessages()
+	_ = msg.AppendUser("say hi via tool")
+	ctx := WithToolRunner(context.Background(), mockToolRunner{})
+	results, err := BatchCompleteLLM(ctx, llm, []*message.Messages{msg}, func(o *BatchOptions) { o.RetryInterval = time.Millisecond })
+	tt.NoError(err, true)
+	tt.NoError(results[0].Err)
+	tt.Equal(2, results[0].Attempts)
+	tt.Equal(1, len(llm.LastMessages()))
+	tt.Equal(2, msg.Len())
+
+	tt.EqualTrue(!isBatchRetryable(fmt.Errorf("tool loop: %w", runtime_errors.NewLLMError(runtime_errors.ErrBadRequest, "bad request"))))
+	tt.EqualTrue(isBatchRetryable(fmt.Errorf("tool loop: %w", serverErr)))
+}
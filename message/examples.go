@@ -0,0 +1,177 @@
+package message
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/sohaha/zlsgo/zjson"
+	"github.com/sohaha/zlsgo/ztype"
+)
+
+// ExampleSelector 示例选择器，根据当前输入挑选要使用的示例
+type ExampleSelector interface {
+	Select(input string, examples [][2]string) [][2]string
+}
+
+// ExampleSelectorFunc 函数形式的示例选择器
+type ExampleSelectorFunc func(input string, examples [][2]string) [][2]string
+
+// Select 挑选示例
+func (f ExampleSelectorFunc) Select(input string, examples [][2]string) [][2]string {
+	return f(input, examples)
+}
+
+// RelevantExamples 创建按相关度挑选 k 个示例的选择器，结果保持示例原有顺序
+// 默认使用词与相邻词组的 Jaccard 相似度（中文按字切分），可通过 similarity 自定义
+func RelevantExamples(k int, similarity ...func(input, example string) float64) ExampleSelector {
+	score := TextSimilarity
+	if len(similarity) > 0 && similarity[0] != nil {
+		score = similarity[0]
+	}
+
+	return ExampleSelectorFunc(func(input string, examples [][2]string) [][2]string {
+		if k <= 0 || len(examples) <= k {
+			return examples
+		}
+
+		type scored struct {
+			index int
+			score float64
+		}
+		list := make([]scored, len(examples))
+		for i := range examples {
+			list[i] = scored{index: i, score: score(input, examples[i][0])}
+		}
+		sort.SliceStable(list, func(i, j int) bool {
+			return list[i].score > list[j].score
+		})
+
+		list = list[:k]
+		sort.Slice(list, func(i, j int) bool {
+			return list[i].index < list[j].index
+		})
+
+		selected := make([][2]string, 0, k)
+		for _, s := range list {
+			selected = append(selected, examples[s.index])
+		}
+		return selected
+	})
+}
+
+// TextSimilarity 计算两段文本的 Jaccard 相似度，取值 0~1
+func TextSimilarity(a, b string) float64 {
+	fa, fb := textFeatures(a), textFeatures(b)
+	if len(fa) == 0 || len(fb) == 0 {
+		return 0
+	}
+
+	inter := 0
+	for f := range fa {
+		if _, ok := fb[f]; ok {
+			inter++
+		}
+	}
+	return float64(inter) / float64(len(fa)+len(fb)-inter)
+}
+
+// textFeatures 提取文本的词与相邻词组特征
+func textFeatures(text string) map[string]struct{} {
+	var (
+		words []string
+		word  strings.Builder
+	)
+	flush := func() {
+		if word.Len() > 0 {
+			words = append(words, word.String())
+			word.Reset()
+		}
+	}
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.Is(unicode.Han, r) || unicode.In(r, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			flush()
+			words = append(words, string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+
+	features := make(map[string]struct{}, len(words)*2)
+	for i := range words {
+		features[words[i]] = struct{}{}
+		if i > 0 {
+			features[words[i-1]+" "+words[i]] = struct{}{}
+		}
+	}
+	return features
+}
+
+// selectExamples 按选择器挑选示例
+func (p *Prompt) selectExamples(input string) [][2]string {
+	if p.options.ExampleSelector == nil || len(p.options.Examples) == 0 {
+		return p.options.Examples
+	}
+	return p.options.ExampleSelector.Select(input, p.options.Examples)
+}
+
+// formatExample 按输出格式整理示例输出，使示例与要求的格式保持一致
+func formatExample(format OutputFormat, output string) string {
+	switch f := format.(type) {
+	case nil, outputNilFormat:
+		return output
+	case outputJSONFormat:
+		if len(f) == 0 || zjson.Valid(output) {
+			return output
+		}
+		keys := make([]string, 0, len(f))
+		for k := range f {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		if len(keys) == 1 {
+			return ztype.ToString(ztype.Map{keys[0]: output})
+		}
+		return ztype.ToString(exampleFields(keys, output))
+	default:
+		if s, err := format.Format(output); err == nil {
+			return s
+		}
+		return output
+	}
+}
+
+// exampleFields 将多字段输出格式的示例输出整理为字段映射，
+// 输出为 "字段: 值" 的多行文本时按行填充，否则整段内容填入第一个字段，其余字段留空
+func exampleFields(keys []string, output string) ztype.Map {
+	m := make(ztype.Map, len(keys))
+	for _, k := range keys {
+		m[k] = ""
+	}
+
+	matched := false
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		i := strings.IndexAny(line, ":：")
+		if i <= 0 {
+			continue
+		}
+		key := strings.TrimSpace(line[:i])
+		if _, ok := m[key]; !ok {
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(line[i:])
+		m[key] = strings.TrimSpace(line[i+size:])
+		matched = true
+	}
+	if !matched {
+		m[keys[0]] = output
+	}
+	return m
+}
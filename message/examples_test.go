@@ -0,0 +1,108 @@
+package message_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sohaha/zlsgo"
+	"github.com/zlsgo/zllm/message"
+)
+
+func TestExamplesAsMessages(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	examples := [][2]string{
+		{"Hello", "你好"},
+		{"Good morning", `{"result":"早上好"}`},
+	}
+
+	p := message.NewPrompt("翻译：{{text}}", func(po *message.PromptOptions) {
+		po.SystemPrompt = "你是一位翻译专家"
+		po.Examples = examples
+		po.OutputFormat = message.CustomOutputFormat(map[string]string{"result": "{}"})
+	})
+
+	msg, err := p.ConvertToMessages(message.PromptConvertOptions{
+		Placeholder:        map[string]string{"text": "Thanks"},
+		ExamplesAsMessages: true,
+	})
+	tt.NoError(err, true)
+
+	history := msg.History(true)
+	tt.Equal(6, len(history))
+	tt.Equal(message.RoleSystem, history[0][0])
+	tt.EqualTrue(strings.Contains(history[0][1], "你是一位翻译专家"))
+	tt.EqualTrue(!strings.Contains(history[0][1], "## Examples"))
+	tt.EqualTrue(!strings.Contains(history[0][1], "# Input"))
+	tt.Equal([]string{message.RoleUser, "Hello"}, history[1])
+	tt.Equal([]string{message.RoleAssistant, `{"result":"你好"}`}, history[2])
+	tt.Equal([]string{message.RoleUser, "Good morning"}, history[3])
+	tt.Equal([]string{message.RoleAssistant, `{"result":"早上好"}`}, history[4])
+	tt.Equal([]string{message.RoleUser, "翻译：Thanks"}, history[5])
+
+	out, err := msg.ParseFormat([]byte(`{"result":"谢谢"}`))
+	tt.NoError(err, true)
+	tt.Equal(`{"result":"谢谢"}`, string(out))
+
+	msg, err = p.ConvertToMessages(message.PromptConvertOptions{Placeholder: map[string]string{"text": "Thanks"}})
+	tt.NoError(err, true)
+	tt.Equal(1, len(msg.History(true)))
+	tt.EqualTrue(strings.Contains(msg.History(true)[0][1], "## Examples"))
+}
+
+func TestRelevantExamples(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	examples := [][2]string{
+		{"今天天气怎么样", "晴"},
+		{"How do I reset my password", "Use the reset link"},
+		{"明天天气如何", "雨"},
+		{"What is the price", "$10"},
+	}
+
+	selected := message.RelevantExamples(2).Select("后天天气怎么样", examples)
+	tt.Equal([][2]string{examples[0], examples[2]}, selected)
+
+	selected = message.RelevantExamples(1).Select("reset password please", examples)
+	tt.Equal([][2]string{examples[1]}, selected)
+
+	tt.Equal(examples, message.RelevantExamples(10).Select("x", examples))
+	tt.Equal(0.0, message.TextSimilarity("", "abc"))
+	tt.Equal(1.0, message.TextSimilarity("Hello World", "hello, world"))
+
+	p := message.NewPrompt("{{q}}", func(po *message.PromptOptions) {
+		po.SystemPrompt = "客服"
+		po.Examples = examples
+		po.ExampleSelector = message.RelevantExamples(1)
+		po.ExamplesAsMessages = true
+	})
+	msg, err := p.ConvertToMessages(message.PromptConvertOptions{Placeholder: map[string]string{"q": "What is the price of it"}})
+	tt.NoError(err, true)
+	history := msg.History(false)
+	tt.Equal(3, len(history))
+	tt.Equal("What is the price", history[0][1])
+	tt.Equal(`{"Assistant":"$10"}`, history[1][1])
+	tt.Equal([]string{message.RoleUser, "What is the price of it"}, history[2])
+	tt.Equal("user: What is the price\nassistant: $10\nuser: What is the price of it", msg.String())
+}
+
+func TestExamplesMultiKeyFormat(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	p := message.NewPrompt("{{text}}", func(po *message.PromptOptions) {
+		po.Examples = [][2]string{
+			{"Hello", "answer: 你好\nlang: zh"},
+			{"Bye", "再见"},
+			{"Thanks", `{"answer":"谢谢","lang":"zh"}`},
+		}
+		po.ExamplesAsMessages = true
+		po.OutputFormat = message.CustomOutputFormat(map[string]string{"answer": "{}", "lang": "{}"})
+	})
+	msg, err := p.ConvertToMessages(message.PromptConvertOptions{Placeholder: map[string]string{"text": "Hi"}})
+	tt.NoError(err, true)
+
+	history := msg.History(true)
+	tt.Equal(`{"answer":"你好","lang":"zh"}`, history[2][1])
+	tt.Equal(`{"answer":"再见","lang":""}`, history[4][1])
+	tt.Equal(`{"answer":"谢谢","lang":"zh"}`, history[6][1])
+}
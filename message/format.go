@@ -2,7 +2,7 @@
 package message
 
 // definitionOutputFormat 定义输出格式
-func definitionOutputFormat(format string) string {
+func definitionOutputFormat(locale Locale, format string) string {
 	if format == "" {
 		return ""
 	}
@@ -10,8 +10,5 @@ func definitionOutputFormat(format string) string {
 	// The return format is as follows, where "{}" represents a placeholder.
 	// Please provide your response in JSON format:
 	// - Respond using JSON
-	return `## Output Format
-Please strictly adhere to this output format, do not include any extra content, where "{}" represents a placeholder:
-
-` + format
+	return "## " + locale.OutputFormat + "\n" + locale.OutputFormatIntro + "\n\n" + format
 }
@@ -15,10 +15,36 @@ import (
 type Message struct {
 	Role         string
 	Content      string
+	Reasoning    []ReasoningBlock // 助手消息的推理内容块，多轮对话中原样回传
+	ToolCalls    []ToolCall       // 助手消息中的工具调用
+	ToolOutputs  []ToolOutput     // 用户消息携带的工具调用结果，Content 为其文本形式
 	options      MessageOptions
 	outputFormat bool
 }
 
+// ReasoningBlock 推理（思考）内容块
+type ReasoningBlock struct {
+	Type      string `json:"type"`                // thinking、redacted_thinking 或 reasoning（Responses API）
+	Thinking  string `json:"thinking,omitempty"`  // 推理文本
+	Signature string `json:"signature,omitempty"` // 服务端签名，回传时用于校验；Responses API 中为推理项 ID
+	Data      string `json:"data,omitempty"`      // redacted_thinking 或推理项的加密内容
+}
+
+// ToolCall 工具调用
+type ToolCall struct {
+	ID   string `json:"id,omitempty"` // 调用 ID，工具结果通过该 ID 对应调用
+	Name string `json:"name"`
+	Args string `json:"args"`
+}
+
+// ToolOutput 工具调用结果
+type ToolOutput struct {
+	ID      string `json:"id,omitempty"` // 对应的工具调用 ID
+	Name    string `json:"name"`
+	Content string `json:"content"`
+	IsError bool   `json:"is_error,omitempty"`
+}
+
 func (p *Message) Prompt() string {
 	if p.options.Format != nil {
 		return p.options.Format.String()
@@ -57,7 +83,21 @@ func (p *Messages) AppendUser(message string, wrapOutputFormat ...OutputFormat)
 	return p.Append(Message{
 		Role:    RoleUser,
 		Content: message,
-	}, func(options *MessageOptions) {
+	}, p.userOptions(wrapOutputFormat))
+}
+
+// AppendToolOutputs 添加工具调用结果，content 为结果的文本形式，供不支持原生工具消息的提供商使用
+func (p *Messages) AppendToolOutputs(content string, outputs []ToolOutput) error {
+	return p.Append(Message{
+		Role:        RoleUser,
+		Content:     content,
+		ToolOutputs: outputs,
+	}, p.userOptions(nil))
+}
+
+// userOptions 用户消息的格式选项
+func (p *Messages) userOptions(wrapOutputFormat []OutputFormat) func(options *MessageOptions) {
+	return func(options *MessageOptions) {
 		if len(wrapOutputFormat) > 0 {
 			options.Format = wrapOutputFormat[0]
 		} else {
@@ -65,7 +105,7 @@ func (p *Messages) AppendUser(message string, wrapOutputFormat ...OutputFormat)
 				options.InheritFormat = true
 			}
 		}
-	})
+	}
 }
 
 // AppendAssistant 添加助手消息
@@ -132,6 +172,13 @@ func (p *Messages) Len() int {
 	return len(p.messages)
 }
 
+// Truncate 只保留前 n 条消息，用于撤销失败调用追加的消息
+func (p *Messages) Truncate(n int) {
+	if n >= 0 && n < len(p.messages) {
+		p.messages = p.messages[:n]
+	}
+}
+
 // ParseFormat 解析格式化响应
 func (p *Messages) ParseFormat(response []byte) ([]byte, error) {
 	var outputFormat OutputFormat
@@ -162,30 +209,58 @@ func (p *Messages) ParseFormat(response []byte) ([]byte, error) {
 
 // History 获取历史消息
 func (p *Messages) History(wrapPrompt bool) [][]string {
-	m := make([][]string, 0, p.Len()+1)
+	m, _ := p.history(wrapPrompt)
+	return m
+}
 
-	if p.formatInput != "" || p.input != "" {
-		role := RoleUser
-		if p.input == "" {
-			role = RoleSystem
+// HistoryReasoning 获取与 History 逐条对应的推理内容块
+func (p *Messages) HistoryReasoning(wrapPrompt bool) [][]ReasoningBlock {
+	m, index := p.history(wrapPrompt)
+	r := make([][]ReasoningBlock, len(m))
+	for i := range index {
+		if index[i] >= 0 {
+			r[i] = p.messages[index[i]].Reasoning
 		}
-		if wrapPrompt && p.formatInput != "" {
-			m = append(m, []string{role, p.formatInput})
-		} else {
-			m = append(m, []string{role, p.input})
+	}
+	return r
+}
+
+// HistoryMessages 获取与 History 逐条对应的原始消息，输入对应零值消息，
+// 用于按协议还原工具调用与工具结果
+func (p *Messages) HistoryMessages(wrapPrompt bool) []Message {
+	m, index := p.history(wrapPrompt)
+	r := make([]Message, len(m))
+	for i := range index {
+		if index[i] >= 0 {
+			r[i] = p.messages[index[i]]
 		}
 	}
+	return r
+}
+
+// history 构建历史消息，同时返回每条记录对应的消息下标（-1 表示输入）
+func (p *Messages) history(wrapPrompt bool) ([][]string, []int) {
+	m := make([][]string, 0, p.Len()+1)
+	index := make([]int, 0, p.Len()+1)
+
+	// 示例作为对话轮次时输入为空，结构化内容仅在 wrapPrompt 时作为系统消息
+	role, content := RoleUser, p.input
+	if p.input == "" {
+		role = RoleSystem
+	}
+	if wrapPrompt && p.formatInput != "" {
+		content = p.formatInput
+	}
+	if content != "" {
+		m = append(m, []string{role, content})
+		index = append(index, -1)
+	}
 
 	for i := range p.messages {
 		if wrapPrompt && p.messages[i].options.Format != nil {
 			if p.messages[i].Role != RoleUser || (p.messages[i].Role == RoleUser && i == len(p.messages)-1) {
 				if p.messages[i].Role == RoleUser {
-					format := definitionOutputFormat(p.messages[i].options.Format.String())
-					if format != "" {
-						m = append(m, []string{p.messages[i].Role, "# System\n\n" + format + "\n\n\n# Input\nThe following content is entirely user input:\n\n" + p.messages[i].Content})
-					} else {
-						m = append(m, []string{p.messages[i].Role, "# System\n\n" + "\n\n\n# Input\nThe following content is entirely user input:\n\n" + p.messages[i].Content})
-					}
+					m = append(m, []string{p.messages[i].Role, p.prompt.renderer().WrapInput(p.messages[i].options.Format.String(), p.messages[i].Content)})
 				} else {
 					c, err := p.messages[i].options.Format.Format(p.messages[i].Content)
 					if err != nil {
@@ -193,14 +268,29 @@ func (p *Messages) History(wrapPrompt bool) [][]string {
 					}
 					m = append(m, []string{p.messages[i].Role, c})
 				}
+				index = append(index, i)
 				continue
 			}
 		}
 
-		m = append(m, []string{p.messages[i].Role, p.messages[i].Content})
+		content := p.messages[i].Content
+		if content == "" && len(p.messages[i].ToolCalls) > 0 {
+			content = ToolCallsText(p.messages[i].ToolCalls)
+		}
+		m = append(m, []string{p.messages[i].Role, content})
+		index = append(index, i)
 	}
 
-	return m
+	return m, index
+}
+
+// ToolCallsText 工具调用的文本形式，用于不支持原生工具调用消息的提供商
+func ToolCallsText(calls []ToolCall) string {
+	list := make([]ztype.Map, 0, len(calls))
+	for _, c := range calls {
+		list = append(list, ztype.Map{"name": c.Name, "args": c.Args})
+	}
+	return ztype.ToString(list)
 }
 
 func (p *Messages) String() string {
@@ -219,7 +309,7 @@ func (p *Messages) String() string {
 
 		if history[i][0] == "assistant" {
 			var msgIndex int
-			if p.prompt != nil && (p.formatInput != "" || p.input != "") {
+			if p.prompt != nil && p.input != "" {
 				msgIndex = i - 1 // 第一个是 prompt 输入，减去 1
 			} else {
 				msgIndex = i
@@ -263,13 +353,17 @@ func (p *Messages) String() string {
 // PromptConvertOptions 提示转换选项
 type PromptConvertOptions struct {
 	Placeholder  map[string]string
+	Vars         map[string]any
 	OutputFormat OutputFormat
+	// ExamplesAsMessages 将示例作为对话轮次发送，与 PromptOptions.ExamplesAsMessages 任一开启即生效
+	ExamplesAsMessages bool
 }
 
+// inputMarker 渲染指令部分时输入所在位置的标记，渲染完成后替换为输入内容
+const inputMarker = "\x00zllm:input\x00"
+
 // ConvertToMessages 转换为消息集合
 func (p *Prompt) ConvertToMessages(options ...PromptConvertOptions) (messages *Messages, err error) {
-	t := zstring.String2Bytes(p.Input)
-
 	var o PromptConvertOptions
 	if len(options) > 0 {
 		o = options[0]
@@ -277,24 +371,75 @@ func (p *Prompt) ConvertToMessages(options ...PromptConvertOptions) (messages *M
 		o = PromptConvertOptions{}
 	}
 
-	ut := p.Bytes(o)
+	// 模板引擎只渲染指令部分，输入是用户内容，不作为模板解析，避免模板注入
+	input := p.Input
+	rendering := len(o.Placeholder) > 0 || len(p.options.Placeholder) > 0 || len(o.Vars) > 0 || len(p.options.Vars) > 0 || p.options.Template != nil
+	if rendering && p.options.Template == nil {
+		var b []byte
+		if b, err = p.render(input, o); err != nil {
+			return nil, err
+		}
+		input = string(b)
+	}
+
+	examples := p.selectExamples(input)
+	asMessages := (o.ExamplesAsMessages || p.options.ExamplesAsMessages) && len(examples) > 0
 
-	if len(o.Placeholder) > 0 || len(p.options.Placeholder) > 0 {
-		ut, err = p.buildTemplate(zstring.Bytes2String(ut), o.Placeholder)
+	var ut []byte
+	if asMessages {
+		ut = p.build(o, nil, "")
+	} else {
+		marker := input
+		if marker != "" {
+			marker = inputMarker
+		}
+		ut = p.build(o, examples, marker)
+	}
+	if rendering {
+		ut, err = p.render(zstring.Bytes2String(ut), o)
 		if err != nil {
 			return
 		}
-		t, _ = p.buildTemplate(zstring.Bytes2String(t), o.Placeholder)
 	}
+	formatInput := strings.ReplaceAll(zstring.Bytes2String(ut), inputMarker, input)
 
 	messages = &Messages{
 		prompt:      p,
-		input:       zstring.Bytes2String(t),
-		formatInput: zstring.Bytes2String(ut),
+		input:       input,
+		formatInput: formatInput,
 		messages:    []Message{},
 		options:     o,
 	}
 
+	if !asMessages {
+		return
+	}
+
+	// 示例作为对话轮次时，结构化内容作为系统消息，当前输入作为最后一条用户消息
+	messages.input = ""
+	format := p.outputFormat(o)
+	for _, e := range examples {
+		in, out := e[0], e[1]
+		if rendering {
+			var b []byte
+			if b, err = p.render(in, o); err != nil {
+				return nil, err
+			}
+			in = string(b)
+			if b, err = p.render(out, o); err != nil {
+				return nil, err
+			}
+			out = string(b)
+		}
+		messages.messages = append(messages.messages,
+			Message{Role: RoleUser, Content: in},
+			Message{Role: RoleAssistant, Content: formatExample(format, out)},
+		)
+	}
+	if input != "" {
+		messages.messages = append(messages.messages, Message{Role: RoleUser, Content: input})
+	}
+
 	return
 }
 
@@ -1,10 +1,7 @@
 package message
 
 import (
-	"fmt"
-
 	"github.com/sohaha/zlsgo/zstring"
-	"github.com/sohaha/zlsgo/ztype"
 	"github.com/sohaha/zlsgo/zutil"
 )
 
@@ -40,6 +37,13 @@ type PromptOptions struct {
 	Examples     [][2]string
 	SystemPrompt string
 	Placeholder  map[string]string
+	Vars         map[string]any // 类型化的模板变量，配合 Template 使用
+	Template     TemplateEngine // 模板引擎，为空时使用 {{tag}} 占位符替换；设置后只渲染系统提示、步骤、规则等指令部分，输入作为用户内容原样插入
+	Renderer     PromptRenderer // 提示词渲染器，为空时使用 DefaultRenderer
+	// ExamplesAsMessages 将示例作为真实的 user/assistant 对话轮次发送，而不是写入系统提示词
+	ExamplesAsMessages bool
+	// ExampleSelector 示例选择器，为空时使用全部示例
+	ExampleSelector ExampleSelector
 }
 
 // NewPrompt 创建新的提示词
@@ -62,97 +66,51 @@ func (p *Prompt) IsEmpty() bool {
 
 // Bytes 生成字节数组形式的提示词
 func (p *Prompt) Bytes(options ...PromptConvertOptions) []byte {
-	if p.IsEmpty() {
-		return []byte(p.Input)
-	}
-
-	builder := zutil.GetBuff()
-	defer zutil.PutBuff(builder)
-
-	builder.WriteString("# System\n")
-
-	if p.options.SystemPrompt != "" {
-		builder.WriteString(p.options.SystemPrompt)
-		builder.WriteString("\n\n")
-	} else {
-		builder.WriteString("\n")
+	var o PromptConvertOptions
+	if len(options) > 0 {
+		o = options[0]
 	}
+	return p.build(o, p.selectExamples(p.Input), p.Input)
+}
 
-	if len(p.options.Steps) > 0 {
-		builder.WriteString("## Steps\n")
-		builder.WriteString("Please strictly follow these steps:\n\n")
-		for i := range p.options.Steps {
-			builder.WriteString("  ")
-			builder.WriteString(ztype.ToString(i + 1))
-			builder.WriteString(". ")
-			builder.WriteString(p.options.Steps[i])
-			builder.WriteString("\n")
-		}
-		builder.WriteString("\n")
+// outputFormat 返回生效的输出格式
+func (p *Prompt) outputFormat(o PromptConvertOptions) OutputFormat {
+	if o.OutputFormat != nil {
+		return o.OutputFormat
 	}
+	return p.options.OutputFormat
+}
 
-	if len(p.options.Rules) > 0 {
-		builder.WriteString("## Rules\n")
-		builder.WriteString("Please note and strictly adhere to the following rules:\n\n")
-		for _, d := range p.options.Rules {
-			builder.WriteString("  - ")
-			builder.WriteString(d)
-			builder.WriteString("\n")
-		}
-		builder.WriteString("\n")
+// build 使用指定的示例与输入生成提示词
+func (p *Prompt) build(o PromptConvertOptions, examples [][2]string, input string) []byte {
+	if p.IsEmpty() {
+		return []byte(input)
 	}
 
-	outputFormat := p.options.OutputFormat
-	if len(options) > 0 && options[0].OutputFormat != nil {
-		outputFormat = options[0].OutputFormat
-	}
+	outputFormat := p.outputFormat(o)
+	format := ""
 	if outputFormat != nil {
-		format := definitionOutputFormat(outputFormat.String())
-		if format != "" {
-			builder.WriteString(format)
-			builder.WriteString("\n\n")
-		}
-	}
-
-	if len(p.options.Examples) > 0 {
-		builder.WriteString("## Examples\n")
-		builder.WriteString("Here are some examples to guide:\n\n")
-		for i := range p.options.Examples {
-			builder.WriteString(fmt.Sprintf("  **Example %d:**\n", i+1))
-			builder.WriteString("    - **Input**: ")
-			builder.WriteString(p.options.Examples[i][0])
-			builder.WriteString("\n")
-			builder.WriteString("    - **Output**: ")
-			builder.WriteString(p.options.Examples[i][1])
-			builder.WriteString("\n")
-		}
-		builder.WriteString("\n")
-	}
-
-	if p.options.MaxLength > 0 {
-		builder.WriteString("## Max Length\n")
-		builder.WriteString(fmt.Sprintf("Please limit your response to approximately %d words.", p.options.MaxLength))
-		builder.WriteString("\n\n")
+		format = outputFormat.String()
 	}
 
-	if len(p.Messages) > 0 {
-		builder.WriteString("## Messages\n")
-		for _, msg := range p.Messages {
-			builder.WriteString(fmt.Sprintf("%s: %s\n", msg.Role, msg.Content))
-			if msg.CacheType != "" {
-				builder.WriteString(fmt.Sprintf("(Cache: %s)\n", msg.CacheType))
-			}
-		}
-		builder.WriteString("\n\n")
-	}
+	return p.renderer().Render(PromptDocument{
+		SystemPrompt: p.options.SystemPrompt,
+		Steps:        p.options.Steps,
+		Rules:        p.options.Rules,
+		OutputFormat: format,
+		Examples:     examples,
+		MaxLength:    p.options.MaxLength,
+		Messages:     p.Messages,
+		Input:        input,
+	})
+}
 
-	if p.Input != "" {
-		builder.WriteString("\n# Input\n")
-		builder.WriteString("The following content is entirely user input:\n\n")
-		builder.WriteString(p.Input)
+// renderer 返回提示词渲染器，未设置时使用默认渲染器
+func (p *Prompt) renderer() PromptRenderer {
+	if p == nil || p.options.Renderer == nil {
+		return DefaultRenderer
 	}
-
-	return builder.Bytes()
+	return p.options.Renderer
 }
 
 // String 返回字符串形式的提示词
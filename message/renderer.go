@@ -0,0 +1,299 @@
+package message
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sohaha/zlsgo/ztype"
+	"github.com/sohaha/zlsgo/zutil"
+)
+
+// PromptDocument 待渲染的提示词各部分内容
+type PromptDocument struct {
+	SystemPrompt string
+	Steps        []string
+	Rules        []string
+	OutputFormat string
+	Examples     [][2]string
+	MaxLength    int
+	Messages     []PromptMessage
+	Input        string
+}
+
+// PromptRenderer 提示词渲染器，负责生成提示词的章节标题、说明等脚手架文本
+type PromptRenderer interface {
+	// Render 渲染完整的提示词
+	Render(doc PromptDocument) []byte
+	// WrapInput 为带输出格式要求的用户消息添加说明
+	WrapInput(outputFormat, input string) string
+}
+
+// Locale 提示词脚手架的本地化文本
+type Locale struct {
+	System            string
+	Steps             string
+	StepsIntro        string
+	Rules             string
+	RulesIntro        string
+	OutputFormat      string
+	OutputFormatIntro string
+	Examples          string
+	ExamplesIntro     string
+	Example           string // 示例标题，%d 为序号
+	ExampleInput      string
+	ExampleOutput     string
+	MaxLength         string
+	MaxLengthText     string // 长度限制说明，%d 为长度
+	Messages          string
+	Cache             string
+	Input             string
+	InputIntro        string
+}
+
+var (
+	// LocaleEN 英文脚手架
+	LocaleEN = Locale{
+		System:            "System",
+		Steps:             "Steps",
+		StepsIntro:        "Please strictly follow these steps:",
+		Rules:             "Rules",
+		RulesIntro:        "Please note and strictly adhere to the following rules:",
+		OutputFormat:      "Output Format",
+		OutputFormatIntro: `Please strictly adhere to this output format, do not include any extra content, where "{}" represents a placeholder:`,
+		Examples:          "Examples",
+		ExamplesIntro:     "Here are some examples to guide:",
+		Example:           "Example %d",
+		ExampleInput:      "Input",
+		ExampleOutput:     "Output",
+		MaxLength:         "Max Length",
+		MaxLengthText:     "Please limit your response to approximately %d words.",
+		Messages:          "Messages",
+		Cache:             "Cache",
+		Input:             "Input",
+		InputIntro:        "The following content is entirely user input:",
+	}
+
+	// LocaleZH 中文脚手架
+	LocaleZH = Locale{
+		System:            "系统",
+		Steps:             "步骤",
+		StepsIntro:        "请严格按照以下步骤执行：",
+		Rules:             "规则",
+		RulesIntro:        "请注意并严格遵守以下规则：",
+		OutputFormat:      "输出格式",
+		OutputFormatIntro: `请严格按照以下格式输出，不要包含任何多余内容，其中 "{}" 表示占位符：`,
+		Examples:          "示例",
+		ExamplesIntro:     "以下示例供参考：",
+		Example:           "示例 %d",
+		ExampleInput:      "输入",
+		ExampleOutput:     "输出",
+		MaxLength:         "长度限制",
+		MaxLengthText:     "请将回复控制在约 %d 字以内。",
+		Messages:          "消息",
+		Cache:             "缓存",
+		Input:             "输入",
+		InputIntro:        "以下内容全部为用户输入：",
+	}
+)
+
+// DefaultRenderer 默认的英文 Markdown 渲染器
+var DefaultRenderer PromptRenderer = MarkdownRenderer(LocaleEN)
+
+// MarkdownRenderer 创建使用 Markdown 标题组织提示词的渲染器
+func MarkdownRenderer(locale Locale) PromptRenderer {
+	return markdownRenderer{locale: locale}
+}
+
+// markdownRenderer Markdown 风格渲染器
+type markdownRenderer struct {
+	locale Locale
+}
+
+// Render 渲染完整的提示词
+func (r markdownRenderer) Render(doc PromptDocument) []byte {
+	l := r.locale
+	builder := zutil.GetBuff()
+	defer zutil.PutBuff(builder)
+
+	builder.WriteString("# " + l.System + "\n")
+
+	if doc.SystemPrompt != "" {
+		builder.WriteString(doc.SystemPrompt)
+		builder.WriteString("\n\n")
+	} else {
+		builder.WriteString("\n")
+	}
+
+	if len(doc.Steps) > 0 {
+		builder.WriteString("## " + l.Steps + "\n")
+		builder.WriteString(l.StepsIntro + "\n\n")
+		for i := range doc.Steps {
+			builder.WriteString("  ")
+			builder.WriteString(ztype.ToString(i + 1))
+			builder.WriteString(". ")
+			builder.WriteString(doc.Steps[i])
+			builder.WriteString("\n")
+		}
+		builder.WriteString("\n")
+	}
+
+	if len(doc.Rules) > 0 {
+		builder.WriteString("## " + l.Rules + "\n")
+		builder.WriteString(l.RulesIntro + "\n\n")
+		for _, d := range doc.Rules {
+			builder.WriteString("  - ")
+			builder.WriteString(d)
+			builder.WriteString("\n")
+		}
+		builder.WriteString("\n")
+	}
+
+	if format := definitionOutputFormat(l, doc.OutputFormat); format != "" {
+		builder.WriteString(format)
+		builder.WriteString("\n\n")
+	}
+
+	if len(doc.Examples) > 0 {
+		builder.WriteString("## " + l.Examples + "\n")
+		builder.WriteString(l.ExamplesIntro + "\n\n")
+		for i := range doc.Examples {
+			builder.WriteString("  **" + fmt.Sprintf(l.Example, i+1) + ":**\n")
+			builder.WriteString("    - **" + l.ExampleInput + "**: ")
+			builder.WriteString(doc.Examples[i][0])
+			builder.WriteString("\n")
+			builder.WriteString("    - **" + l.ExampleOutput + "**: ")
+			builder.WriteString(doc.Examples[i][1])
+			builder.WriteString("\n")
+		}
+		builder.WriteString("\n")
+	}
+
+	if doc.MaxLength > 0 {
+		builder.WriteString("## " + l.MaxLength + "\n")
+		builder.WriteString(fmt.Sprintf(l.MaxLengthText, doc.MaxLength))
+		builder.WriteString("\n\n")
+	}
+
+	if len(doc.Messages) > 0 {
+		builder.WriteString("## " + l.Messages + "\n")
+		for _, msg := range doc.Messages {
+			builder.WriteString(fmt.Sprintf("%s: %s\n", msg.Role, msg.Content))
+			if msg.CacheType != "" {
+				builder.WriteString(fmt.Sprintf("(%s: %s)\n", l.Cache, msg.CacheType))
+			}
+		}
+		builder.WriteString("\n\n")
+	}
+
+	if doc.Input != "" {
+		builder.WriteString("\n# " + l.Input + "\n")
+		builder.WriteString(l.InputIntro + "\n\n")
+		builder.WriteString(doc.Input)
+	}
+
+	return []byte(builder.String())
+}
+
+// WrapInput 为带输出格式要求的用户消息添加说明
+func (r markdownRenderer) WrapInput(outputFormat, input string) string {
+	return "# " + r.locale.System + "\n\n" + definitionOutputFormat(r.locale, outputFormat) + "\n\n\n# " + r.locale.Input + "\n" + r.locale.InputIntro + "\n\n" + input
+}
+
+// XMLRenderer 创建使用 XML 标签组织提示词的渲染器，适用于偏好标签结构的模型（如 Claude）
+func XMLRenderer(locale ...Locale) PromptRenderer {
+	l := LocaleEN
+	if len(locale) > 0 {
+		l = locale[0]
+	}
+	return xmlRenderer{locale: l}
+}
+
+// xmlRenderer XML 标签风格渲染器
+type xmlRenderer struct {
+	locale Locale
+}
+
+// Render 渲染完整的提示词
+func (r xmlRenderer) Render(doc PromptDocument) []byte {
+	l := r.locale
+	builder := zutil.GetBuff()
+	defer zutil.PutBuff(builder)
+
+	if doc.SystemPrompt != "" {
+		writeTag(builder, "system", doc.SystemPrompt)
+	}
+
+	if len(doc.Steps) > 0 {
+		items := make([]string, 0, len(doc.Steps)+1)
+		items = append(items, l.StepsIntro)
+		for i := range doc.Steps {
+			items = append(items, ztype.ToString(i+1)+". "+doc.Steps[i])
+		}
+		writeTag(builder, "steps", strings.Join(items, "\n"))
+	}
+
+	if len(doc.Rules) > 0 {
+		items := make([]string, 0, len(doc.Rules)+1)
+		items = append(items, l.RulesIntro)
+		for _, d := range doc.Rules {
+			items = append(items, "- "+d)
+		}
+		writeTag(builder, "rules", strings.Join(items, "\n"))
+	}
+
+	if doc.OutputFormat != "" {
+		writeTag(builder, "output_format", l.OutputFormatIntro+"\n"+doc.OutputFormat)
+	}
+
+	if len(doc.Examples) > 0 {
+		examples := zutil.GetBuff()
+		examples.WriteString(l.ExamplesIntro + "\n")
+		for i := range doc.Examples {
+			examples.WriteString("<example>\n")
+			writeTag(examples, "input", doc.Examples[i][0])
+			writeTag(examples, "output", doc.Examples[i][1])
+			examples.WriteString("</example>\n")
+		}
+		writeTag(builder, "examples", strings.TrimSuffix(examples.String(), "\n"))
+		zutil.PutBuff(examples)
+	}
+
+	if doc.MaxLength > 0 {
+		writeTag(builder, "max_length", fmt.Sprintf(l.MaxLengthText, doc.MaxLength))
+	}
+
+	if len(doc.Messages) > 0 {
+		items := make([]string, 0, len(doc.Messages))
+		for _, msg := range doc.Messages {
+			items = append(items, fmt.Sprintf("<message role=%q>%s</message>", msg.Role, msg.Content))
+		}
+		writeTag(builder, "messages", strings.Join(items, "\n"))
+	}
+
+	if doc.Input != "" {
+		builder.WriteString(l.InputIntro + "\n")
+		writeTag(builder, "input", doc.Input)
+	}
+
+	return []byte(strings.TrimSuffix(builder.String(), "\n"))
+}
+
+// WrapInput 为带输出格式要求的用户消息添加说明
+func (r xmlRenderer) WrapInput(outputFormat, input string) string {
+	builder := zutil.GetBuff()
+	defer zutil.PutBuff(builder)
+
+	if outputFormat != "" {
+		writeTag(builder, "output_format", r.locale.OutputFormatIntro+"\n"+outputFormat)
+	}
+	builder.WriteString(r.locale.InputIntro + "\n")
+	writeTag(builder, "input", input)
+	return strings.TrimSuffix(builder.String(), "\n")
+}
+
+// writeTag 写入一个 XML 标签块
+func writeTag(builder interface{ WriteString(string) (int, error) }, tag, content string) {
+	_, _ = builder.WriteString("<" + tag + ">\n")
+	_, _ = builder.WriteString(content)
+	_, _ = builder.WriteString("\n</" + tag + ">\n")
+}
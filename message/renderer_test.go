@@ -0,0 +1,66 @@
+package message_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sohaha/zlsgo"
+	"github.com/zlsgo/zllm/message"
+)
+
+func TestRenderer(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	newPrompt := func(renderer message.PromptRenderer) *message.Prompt {
+		return message.NewPrompt("你好", func(po *message.PromptOptions) {
+			po.SystemPrompt = "你是一位翻译专家"
+			po.Steps = []string{"识别语言"}
+			po.Rules = []string{"保持原意"}
+			po.Examples = [][2]string{{"Hi", "你好"}}
+			po.MaxLength = 100
+			po.OutputFormat = message.CustomOutputFormat(map[string]string{"result": "{}"})
+			po.Renderer = renderer
+		})
+	}
+
+	tt.Run("Default", func(tt *zlsgo.TestUtil) {
+		s := newPrompt(nil).String()
+		tt.Equal(s, newPrompt(message.MarkdownRenderer(message.LocaleEN)).String())
+		tt.EqualTrue(strings.HasPrefix(s, "# System\n你是一位翻译专家\n\n## Steps\n"))
+		tt.EqualTrue(strings.HasSuffix(s, "\n# Input\nThe following content is entirely user input:\n\n你好"))
+	})
+
+	tt.Run("Chinese", func(tt *zlsgo.TestUtil) {
+		s := newPrompt(message.MarkdownRenderer(message.LocaleZH)).String()
+		tt.EqualTrue(strings.HasPrefix(s, "# 系统\n你是一位翻译专家\n\n## 步骤\n请严格按照以下步骤执行：\n\n  1. 识别语言\n"))
+		tt.EqualTrue(strings.Contains(s, "## 规则\n"))
+		tt.EqualTrue(strings.Contains(s, "  **示例 1:**\n    - **输入**: Hi\n"))
+		tt.EqualTrue(strings.Contains(s, "请将回复控制在约 100 字以内。"))
+		tt.EqualTrue(strings.HasSuffix(s, "\n# 输入\n以下内容全部为用户输入：\n\n你好"))
+		tt.EqualTrue(!strings.Contains(s, "Please"))
+	})
+
+	tt.Run("XML", func(tt *zlsgo.TestUtil) {
+		s := newPrompt(message.XMLRenderer()).String()
+		tt.EqualTrue(strings.HasPrefix(s, "<system>\n你是一位翻译专家\n</system>\n<steps>\n"))
+		tt.EqualTrue(strings.Contains(s, "<rules>\nPlease note and strictly adhere to the following rules:\n- 保持原意\n</rules>"))
+		tt.EqualTrue(strings.Contains(s, "<example>\n<input>\nHi\n</input>\n<output>\n你好\n</output>\n</example>"))
+		tt.EqualTrue(strings.Contains(s, `{"result":"{}"}`))
+		tt.EqualTrue(strings.HasSuffix(s, "<input>\n你好\n</input>"))
+	})
+
+	tt.Run("WrapInput", func(tt *zlsgo.TestUtil) {
+		p := message.NewPrompt("", func(po *message.PromptOptions) {
+			po.SystemPrompt = "你是一位助手"
+			po.Renderer = message.MarkdownRenderer(message.LocaleZH)
+		})
+		msg, err := p.ConvertToMessages()
+		tt.NoError(err, true)
+		tt.NoError(msg.AppendUser("你好", message.CustomOutputFormat(map[string]string{"answer": "{}"})), true)
+
+		history := msg.History(true)
+		last := history[len(history)-1][1]
+		tt.EqualTrue(strings.HasPrefix(last, "# 系统\n\n## 输出格式\n"))
+		tt.EqualTrue(strings.HasSuffix(last, "# 输入\n以下内容全部为用户输入：\n\n你好"))
+	})
+}
@@ -0,0 +1,166 @@
+package message
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/sohaha/zlsgo/ztype"
+	"github.com/sohaha/zlsgo/zutil"
+)
+
+// TemplateEngine 提示词模板引擎
+type TemplateEngine interface {
+	Render(tpl string, data map[string]any) ([]byte, error)
+}
+
+// GoTemplateOptions Go text/template 模板引擎配置
+type GoTemplateOptions struct {
+	Strict   bool              // 严格模式，引用未定义的变量时返回错误
+	Partials map[string]string // 可复用的片段，模板中通过 {{template "name" .}} 引用
+	Funcs    template.FuncMap  // 自定义模板函数
+	Left     string            // 左定界符，默认 {{
+	Right    string            // 右定界符，默认 }}
+}
+
+// goTemplateEngine 基于 text/template 的模板引擎
+type goTemplateEngine struct {
+	options GoTemplateOptions
+}
+
+// maxMissingKeys 非严格模式下补齐缺失变量的最大次数
+const maxMissingKeys = 32
+
+// missingKeyErr text/template 引用缺失变量时的错误信息
+var missingKeyErr = regexp.MustCompile(`map has no entry for key "([^"]*)"`)
+
+// missingValue 非严格模式下缺失变量的值，输出为空、条件判断为假、遍历时为空
+type missingValue []any
+
+func (missingValue) String() string { return "" }
+
+func (missingValue) MarshalJSON() ([]byte, error) { return []byte("null"), nil }
+
+// GoTemplate 创建基于 text/template 的模板引擎，支持条件、循环、片段和严格模式
+//
+//	message.NewPrompt("{{.text}}", func(po *message.PromptOptions) {
+//		po.Template = message.GoTemplate(func(o *message.GoTemplateOptions) {
+//			o.Strict = true
+//		})
+//	})
+func GoTemplate(options ...func(*GoTemplateOptions)) TemplateEngine {
+	return &goTemplateEngine{
+		options: zutil.Optional(GoTemplateOptions{Left: "{{", Right: "}}"}, options...),
+	}
+}
+
+// Render 渲染模板
+func (e *goTemplateEngine) Render(tpl string, data map[string]any) ([]byte, error) {
+	t := template.New("prompt").Option("missingkey=error").Delims(e.options.Left, e.options.Right).Funcs(templateFuncs)
+	if len(e.options.Funcs) > 0 {
+		t = t.Funcs(e.options.Funcs)
+	}
+
+	for name, partial := range e.options.Partials {
+		if _, err := t.New(name).Parse(partial); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := t.Parse(tpl); err != nil {
+		return nil, err
+	}
+
+	if e.options.Strict {
+		return execute(t, data)
+	}
+
+	// 非严格模式下将缺失的变量补为空值后重新渲染，嵌套字段缺失时按 text/template 默认行为输出
+	vars := make(map[string]any, len(data))
+	for k, v := range data {
+		vars[k] = v
+	}
+	for i := 0; i < maxMissingKeys; i++ {
+		b, err := execute(t, vars)
+		if err == nil {
+			return b, nil
+		}
+		m := missingKeyErr.FindStringSubmatch(err.Error())
+		if m == nil {
+			return nil, err
+		}
+		if _, ok := vars[m[1]]; ok {
+			break
+		}
+		vars[m[1]] = missingValue{}
+	}
+	return execute(t.Option("missingkey=default"), data)
+}
+
+// execute 执行模板
+func execute(t *template.Template, data map[string]any) ([]byte, error) {
+	builder := zutil.GetBuff()
+	defer zutil.PutBuff(builder)
+
+	if err := t.Execute(builder, data); err != nil {
+		return nil, err
+	}
+	return []byte(builder.String()), nil
+}
+
+// templateFuncs 内置模板函数
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"join": func(sep string, v any) string {
+		return strings.Join(ztype.ToSlice(v).String(), sep)
+	},
+	"default": func(def, v any) any {
+		if _, ok := v.(missingValue); ok || v == nil || ztype.ToString(v) == "" {
+			return def
+		}
+		return v
+	},
+	"trim":  strings.TrimSpace,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// templateData 合并提示词默认变量与调用时传入的变量，后者优先
+func (p *Prompt) templateData(o PromptConvertOptions) map[string]any {
+	data := make(map[string]any, len(p.options.Placeholder)+len(p.options.Vars)+len(o.Placeholder)+len(o.Vars))
+	for k, v := range p.options.Placeholder {
+		data[k] = v
+	}
+	for k, v := range p.options.Vars {
+		data[k] = v
+	}
+	for k, v := range o.Placeholder {
+		data[k] = v
+	}
+	for k, v := range o.Vars {
+		data[k] = v
+	}
+	return data
+}
+
+// render 使用提示词配置的模板引擎渲染模板，未配置时使用 {{tag}} 占位符替换
+func (p *Prompt) render(tpl string, o PromptConvertOptions) ([]byte, error) {
+	if p.options.Template != nil {
+		return p.options.Template.Render(tpl, p.templateData(o))
+	}
+
+	if len(o.Vars) == 0 && len(p.options.Vars) == 0 {
+		return p.buildTemplate(tpl, o.Placeholder)
+	}
+
+	data := p.templateData(o)
+	placeholder := make(map[string]string, len(data))
+	for k, v := range data {
+		placeholder[k] = ztype.ToString(v)
+	}
+	return p.buildTemplate(tpl, placeholder)
+}
@@ -0,0 +1,95 @@
+package message_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sohaha/zlsgo"
+	"github.com/zlsgo/zllm/message"
+)
+
+func TestGoTemplate(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	tt.Run("Conditions And Ranges", func(tt *zlsgo.TestUtil) {
+		p := message.NewPrompt("Hello", func(po *message.PromptOptions) {
+			po.SystemPrompt = `请翻译成 {{.language}}{{if .formal}}，使用正式语气{{end}}。
+{{range $i, $term := .terms}}- {{$term}}
+{{end}}`
+			po.Vars = map[string]any{"language": "中文", "formal": false}
+			po.Template = message.GoTemplate()
+		})
+
+		msg, err := p.ConvertToMessages(message.PromptConvertOptions{
+			Vars: map[string]any{"formal": true, "terms": []string{"LLM", "Token"}},
+		})
+		tt.NoError(err, true)
+
+		history := msg.History(true)
+		tt.EqualTrue(strings.HasSuffix(history[0][1], "\n\nHello"))
+		tt.EqualTrue(strings.Contains(history[0][1], "请翻译成 中文，使用正式语气。"))
+		tt.EqualTrue(strings.Contains(history[0][1], "- LLM\n- Token\n"))
+		tt.Equal("Hello", msg.History(false)[0][1])
+	})
+
+	tt.Run("Partials And Funcs", func(tt *zlsgo.TestUtil) {
+		p := message.NewPrompt("hi", func(po *message.PromptOptions) {
+			po.SystemPrompt = `{{template "user" .}}`
+			po.Template = message.GoTemplate(func(o *message.GoTemplateOptions) {
+				o.Partials = map[string]string{"user": `用户 {{upper .name}} 说：{{json .said}}`}
+			})
+		})
+
+		msg, err := p.ConvertToMessages(message.PromptConvertOptions{
+			Vars: map[string]any{"name": "bob", "said": `"hi"`},
+		})
+		tt.NoError(err, true)
+		tt.EqualTrue(strings.Contains(msg.History(true)[0][1], `用户 BOB 说："\"hi\""`))
+	})
+
+	tt.Run("Strict", func(tt *zlsgo.TestUtil) {
+		p := message.NewPrompt("hi", func(po *message.PromptOptions) {
+			po.SystemPrompt = "{{.text}} {{.missing}}"
+			po.Template = message.GoTemplate(func(o *message.GoTemplateOptions) {
+				o.Strict = true
+			})
+		})
+
+		_, err := p.ConvertToMessages(message.PromptConvertOptions{Vars: map[string]any{"text": "hi"}})
+		tt.EqualTrue(err != nil)
+
+		lenient := message.NewPrompt("hi", func(po *message.PromptOptions) {
+			po.SystemPrompt = "[{{.text}}{{.missing}}{{range .items}}x{{end}}{{default \"none\" .other}}] <no value>"
+			po.Template = message.GoTemplate()
+		})
+		msg, err := lenient.ConvertToMessages(message.PromptConvertOptions{Vars: map[string]any{"text": "hi"}})
+		tt.NoError(err, true)
+		tt.EqualTrue(strings.Contains(msg.History(true)[0][1], "[hinone] <no value>"))
+	})
+
+	tt.Run("Input Is Not A Template", func(tt *zlsgo.TestUtil) {
+		p := message.NewPrompt(`{{.secret}} {{template "x"}} {{`, func(po *message.PromptOptions) {
+			po.SystemPrompt = "助手 {{.name}}"
+			po.Template = message.GoTemplate(func(o *message.GoTemplateOptions) {
+				o.Strict = true
+			})
+		})
+
+		msg, err := p.ConvertToMessages(message.PromptConvertOptions{Vars: map[string]any{"name": "小明", "secret": "token"}})
+		tt.NoError(err, true)
+		history := msg.History(true)
+		tt.EqualTrue(strings.Contains(history[0][1], "助手 小明"))
+		tt.EqualTrue(strings.HasSuffix(history[0][1], `{{.secret}} {{template "x"}} {{`))
+		tt.Equal(`{{.secret}} {{template "x"}} {{`, msg.History(false)[0][1])
+	})
+
+	tt.Run("Default Placeholder With Vars", func(tt *zlsgo.TestUtil) {
+		p := message.NewPrompt("数量：{{count}}，名字：{{name}}", func(po *message.PromptOptions) {
+			po.Placeholder = map[string]string{"name": "小明"}
+		})
+
+		msg, err := p.ConvertToMessages(message.PromptConvertOptions{Vars: map[string]any{"count": 3}})
+		tt.NoError(err, true)
+		tt.Equal("数量：3，名字：小明", msg.History(false)[0][1])
+	})
+}
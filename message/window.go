@@ -0,0 +1,125 @@
+package message
+
+import "github.com/zlsgo/zllm/tokenizer"
+
+// messageTokenOverhead 每条消息的角色、分隔符等额外 token 开销
+const messageTokenOverhead = 4
+
+// TokenEstimator token 数估算函数
+type TokenEstimator func(text string) int
+
+// EstimateTokens 粗略估算文本的 token 数
+// 使用 tokenizer.OpenAIHeuristic：CJK 等宽字符按每字 1 token 计算，其余字符按每 4 个字符 1 token 计算
+func EstimateTokens(text string) int {
+	return tokenizer.OpenAIHeuristic.Count(text)
+}
+
+// WindowOptions 上下文窗口裁剪选项
+type WindowOptions struct {
+	MaxTokens int            // 请求允许的最大 token 数，0 表示不限制
+	KeepTurns int            // 最多保留的最近对话轮次，0 表示不限制
+	Estimator TokenEstimator // token 估算器，为空时使用 EstimateTokens
+}
+
+// Tokens 估算发送给模型的完整历史的 token 数
+func (p *Messages) Tokens(estimator TokenEstimator) int {
+	if estimator == nil {
+		estimator = EstimateTokens
+	}
+
+	total := 0
+	for _, v := range p.History(true) {
+		total += estimator(v[1]) + messageTokenOverhead
+	}
+	return total
+}
+
+// Window 按窗口选项裁剪历史消息，返回裁剪后的消息集合以及被丢弃的消息
+// 提示词生成的首条输入与开头的系统消息始终保留，裁剪以轮次为单位进行，
+// 保证工具调用与其工具结果、以及最后一条消息的输出格式包装不被拆散
+func (p *Messages) Window(opt WindowOptions) (*Messages, []Message) {
+	pinned := p.pinned()
+	starts := p.turnStarts(pinned)
+	if len(starts) <= 1 {
+		return p, nil
+	}
+
+	keep := 0
+	if opt.KeepTurns > 0 && len(starts) > opt.KeepTurns {
+		keep = len(starts) - opt.KeepTurns
+	}
+
+	window := p.cut(pinned, starts[keep])
+	if opt.MaxTokens > 0 {
+		for keep < len(starts)-1 && window.Tokens(opt.Estimator) > opt.MaxTokens {
+			keep++
+			window = p.cut(pinned, starts[keep])
+		}
+	}
+
+	if keep == 0 {
+		return p, nil
+	}
+
+	dropped := make([]Message, starts[keep]-pinned)
+	copy(dropped, p.messages[pinned:starts[keep]])
+	return window, dropped
+}
+
+// PrependSystem 返回在历史消息最前面插入一条系统消息后的副本
+func (p *Messages) PrependSystem(content string) *Messages {
+	m := p.slice(0)
+	m.messages = append([]Message{{Role: RoleSystem, Content: content}}, m.messages...)
+	return m
+}
+
+// slice 返回从指定位置开始的历史消息副本，提示词及选项保持不变
+func (p *Messages) slice(from int) *Messages {
+	messages := make([]Message, len(p.messages)-from)
+	copy(messages, p.messages[from:])
+
+	return &Messages{
+		prompt:      p.prompt,
+		input:       p.input,
+		formatInput: p.formatInput,
+		messages:    messages,
+		options:     p.options,
+	}
+}
+
+// cut 返回保留前 pinned 条消息并丢弃 [pinned, from) 区间的副本
+func (p *Messages) cut(pinned, from int) *Messages {
+	m := p.slice(from)
+	if pinned > 0 {
+		m.messages = append(append(make([]Message, 0, pinned+len(m.messages)), p.messages[:pinned]...), m.messages...)
+	}
+	return m
+}
+
+// pinned 返回开头连续系统消息的数量，这些消息不参与裁剪
+func (p *Messages) pinned() int {
+	n := 0
+	for n < len(p.messages) && p.messages[n].Role == RoleSystem {
+		n++
+	}
+	return n
+}
+
+// turnStarts 返回从 from 开始每个对话轮次起始消息的下标
+// 一个轮次从助手回复之后的第一条非助手消息开始，连续的用户消息归属同一轮次，
+// 携带工具调用的助手消息与其后的工具结果始终归属同一轮次
+func (p *Messages) turnStarts(from int) []int {
+	starts := make([]int, 0, (len(p.messages)-from)/2+1)
+	for i := from; i < len(p.messages); i++ {
+		if i == from {
+			starts = append(starts, i)
+			continue
+		}
+		prev := p.messages[i-1]
+		if p.messages[i].Role != RoleAssistant && prev.Role == RoleAssistant &&
+			len(prev.ToolCalls) == 0 && len(p.messages[i].ToolOutputs) == 0 {
+			starts = append(starts, i)
+		}
+	}
+	return starts
+}
@@ -0,0 +1,133 @@
+package message_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sohaha/zlsgo"
+	"github.com/zlsgo/zllm/message"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	tt.Equal(0, message.EstimateTokens(""))
+	tt.Equal(1, message.EstimateTokens("abcd"))
+	tt.Equal(2, message.EstimateTokens("abcde"))
+	tt.Equal(4, message.EstimateTokens("你好世界"))
+	tt.Equal(3, message.EstimateTokens("你好ok"))
+}
+
+func TestMessagesWindow(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	counter := func(text string) int { return len([]rune(text)) }
+
+	newHistory := func() *message.Messages {
+		p := message.NewPrompt("你好", func(po *message.PromptOptions) {
+			po.SystemPrompt = "你是一个机器人"
+		})
+		msg, err := p.ConvertToMessages()
+		tt.NoError(err, true)
+		_ = msg.AppendAssistant("第一轮回复")
+		_ = msg.AppendUser("第二轮提问")
+		_ = msg.AppendUser("工具结果")
+		_ = msg.AppendAssistant("第二轮回复")
+		_ = msg.AppendUser("第三轮提问", message.CustomOutputFormat(map[string]string{"结果": "{}"}))
+		return msg
+	}
+
+	tt.Run("NoLimit", func(tt *zlsgo.TestUtil) {
+		msg := newHistory()
+		window, dropped := msg.Window(message.WindowOptions{})
+		tt.Equal(0, len(dropped))
+		tt.Equal(msg, window)
+	})
+
+	tt.Run("KeepTurns", func(tt *zlsgo.TestUtil) {
+		msg := newHistory()
+		window, dropped := msg.Window(message.WindowOptions{KeepTurns: 1})
+		tt.Equal(4, len(dropped))
+		tt.Equal(1, window.Len())
+		tt.Equal(5, msg.Len())
+
+		history := window.History(true)
+		tt.Equal(2, len(history))
+		tt.Equal(true, strings.HasPrefix(history[0][1], "# System\n你是一个机器人"))
+		tt.Equal(true, strings.Contains(history[1][1], "{\"结果\":\"{}\"}"))
+		tt.Equal(true, strings.HasSuffix(history[1][1], "第三轮提问"))
+	})
+
+	tt.Run("KeepToolResultsWithTurn", func(tt *zlsgo.TestUtil) {
+		msg := newHistory()
+		window, dropped := msg.Window(message.WindowOptions{KeepTurns: 2})
+		tt.Equal(1, len(dropped))
+		tt.Equal("第一轮回复", dropped[0].Content)
+
+		history := window.History(false)
+		tt.Equal(message.RoleUser, history[1][0])
+		tt.Equal("第二轮提问", history[1][1])
+		tt.Equal("工具结果", history[2][1])
+	})
+
+	tt.Run("MaxTokens", func(tt *zlsgo.TestUtil) {
+		msg := newHistory()
+		total := msg.Tokens(counter)
+		window, dropped := msg.Window(message.WindowOptions{MaxTokens: total - 1, Estimator: counter})
+		tt.Equal(1, len(dropped))
+		tt.Equal(true, window.Tokens(counter) <= total-1)
+
+		window, dropped = msg.Window(message.WindowOptions{MaxTokens: 1, Estimator: counter})
+		tt.Equal(4, len(dropped))
+		tt.Equal(1, window.Len())
+	})
+
+	tt.Run("PinSystem", func(tt *zlsgo.TestUtil) {
+		msg := message.NewMessages()
+		_ = msg.Append(message.Message{Role: message.RoleSystem, Content: "规则"})
+		_ = msg.AppendUser("第一轮提问")
+		_ = msg.AppendAssistant("第一轮回复")
+		_ = msg.AppendUser("第二轮提问")
+
+		window, dropped := msg.Window(message.WindowOptions{KeepTurns: 1})
+		tt.Equal(2, len(dropped))
+		tt.Equal("第一轮提问", dropped[0].Content)
+
+		history := window.History(false)
+		tt.Equal(2, len(history))
+		tt.Equal(message.RoleSystem, history[0][0])
+		tt.Equal("规则", history[0][1])
+		tt.Equal("第二轮提问", history[1][1])
+	})
+
+	tt.Run("KeepToolCallWithResult", func(tt *zlsgo.TestUtil) {
+		msg := message.NewMessages()
+		_ = msg.AppendUser("第一轮提问")
+		_ = msg.Append(message.Message{
+			Role:      message.RoleAssistant,
+			Reasoning: []message.ReasoningBlock{{Type: "thinking", Thinking: "查天气", Signature: "sig"}},
+			ToolCalls: []message.ToolCall{{ID: "call_1", Name: "weather", Args: `{}`}},
+		})
+		_ = msg.AppendToolOutputs("晴", []message.ToolOutput{{ID: "call_1", Name: "weather", Content: "晴"}})
+		_ = msg.AppendAssistant("今天晴")
+		_ = msg.AppendUser("第二轮提问")
+
+		window, dropped := msg.Window(message.WindowOptions{KeepTurns: 2})
+		tt.Equal(0, len(dropped))
+		tt.Equal(5, window.Len())
+
+		window, dropped = msg.Window(message.WindowOptions{KeepTurns: 1})
+		tt.Equal(4, len(dropped))
+		tt.Equal(1, window.Len())
+	})
+
+	tt.Run("PrependSystem", func(tt *zlsgo.TestUtil) {
+		msg := newHistory()
+		window, _ := msg.Window(message.WindowOptions{KeepTurns: 1})
+		noted := window.PrependSystem("摘要")
+		history := noted.History(false)
+		tt.Equal(message.RoleSystem, history[1][0])
+		tt.Equal("摘要", history[1][1])
+		tt.Equal(1, window.Len())
+	})
+}
@@ -0,0 +1,31 @@
+module github.com/zlsgo/zllm/otel
+
+// go.opentelemetry.io/otel v1.44 要求 go 1.25，因此独立为子模块，主模块仍保持 go 1.18
+go 1.25.0
+
+require (
+	github.com/sohaha/zlsgo v1.7.20
+	// 主模块尚未发布包含该子模块所需接口的版本，发布后改为对应 tag，见 README 子模块发布
+	github.com/zlsgo/zllm v0.0.0-00010101000000-000000000000
+	go.opentelemetry.io/otel v1.44.0
+	go.opentelemetry.io/otel/sdk v1.44.0
+	go.opentelemetry.io/otel/trace v1.44.0
+)
+
+require (
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/go-logr/logr v1.4.4 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	go.opentelemetry.io/auto/sdk v1.2.1 // indirect
+	go.opentelemetry.io/otel/metric v1.44.0 // indirect
+	golang.org/x/crypto v0.21.0 // indirect
+	golang.org/x/exp v0.0.0-20230213192124-5e25df0256eb // indirect
+	golang.org/x/net v0.23.0 // indirect
+	golang.org/x/sync v0.1.0 // indirect
+	golang.org/x/sys v0.45.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+)
+
+// 本地开发时使用仓库内的主模块
+replace github.com/zlsgo/zllm => ../
@@ -0,0 +1,115 @@
+// Package otel 提供基于 OpenTelemetry 的链路追踪实现，遵循 GenAI 语义约定
+//
+//	otel.Setup(func(o *otel.Options) {
+//		o.CaptureContent = true
+//	})
+package otel
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sohaha/zlsgo/zutil"
+	"github.com/zlsgo/zllm/runtime"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName 追踪器名称
+const ScopeName = "github.com/zlsgo/zllm"
+
+// Options 链路追踪配置
+type Options struct {
+	// TracerProvider 追踪提供者，默认使用 otel 全局提供者
+	TracerProvider trace.TracerProvider
+	// CaptureContent 是否记录提示词、响应及工具参数等内容，可能包含敏感信息，默认关闭
+	CaptureContent bool
+}
+
+// Tracer OpenTelemetry 链路追踪
+type Tracer struct {
+	tracer         trace.Tracer
+	captureContent bool
+}
+
+var _ runtime.Tracer = &Tracer{}
+
+// New 创建 OpenTelemetry 链路追踪
+func New(opt ...func(*Options)) *Tracer {
+	o := zutil.Optional(Options{}, opt...)
+	if o.TracerProvider == nil {
+		o.TracerProvider = otel.GetTracerProvider()
+	}
+
+	return &Tracer{
+		tracer:         o.TracerProvider.Tracer(ScopeName),
+		captureContent: o.CaptureContent,
+	}
+}
+
+// Setup 创建 OpenTelemetry 链路追踪并设置为全局追踪
+func Setup(opt ...func(*Options)) *Tracer {
+	t := New(opt...)
+	runtime.SetTracer(t)
+	return t
+}
+
+// Start 开始追踪片段
+func (t *Tracer) Start(ctx context.Context, name string, kind runtime.SpanKind, attrs ...runtime.Attribute) (context.Context, runtime.Span) {
+	spanKind := trace.SpanKindInternal
+	if kind == runtime.SpanKindClient {
+		spanKind = trace.SpanKindClient
+	}
+
+	ctx, span := t.tracer.Start(ctx, name, trace.WithSpanKind(spanKind), trace.WithAttributes(convert(attrs)...))
+	return ctx, &otelSpan{span: span}
+}
+
+// CaptureContent 是否记录提示词与响应内容
+func (t *Tracer) CaptureContent() bool {
+	return t.captureContent
+}
+
+// otelSpan 包装 OpenTelemetry 追踪片段
+type otelSpan struct {
+	span trace.Span
+}
+
+func (s *otelSpan) SetAttributes(attrs ...runtime.Attribute) {
+	s.span.SetAttributes(convert(attrs)...)
+}
+
+func (s *otelSpan) RecordError(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+func (s *otelSpan) End() {
+	s.span.End()
+}
+
+// convert 转换为 OpenTelemetry 属性
+func convert(attrs []runtime.Attribute) []attribute.KeyValue {
+	kvs := make([]attribute.KeyValue, 0, len(attrs))
+	for _, a := range attrs {
+		switch v := a.Value.(type) {
+		case string:
+			kvs = append(kvs, attribute.String(a.Key, v))
+		case int:
+			kvs = append(kvs, attribute.Int(a.Key, v))
+		case int64:
+			kvs = append(kvs, attribute.Int64(a.Key, v))
+		case float64:
+			kvs = append(kvs, attribute.Float64(a.Key, v))
+		case bool:
+			kvs = append(kvs, attribute.Bool(a.Key, v))
+		case []string:
+			kvs = append(kvs, attribute.StringSlice(a.Key, v))
+		default:
+			kvs = append(kvs, attribute.String(a.Key, fmt.Sprint(v)))
+		}
+	}
+	return kvs
+}
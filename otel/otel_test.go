@@ -0,0 +1,104 @@
+package otel_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sohaha/zlsgo"
+	"github.com/zlsgo/zllm"
+	"github.com/zlsgo/zllm/agent"
+	"github.com/zlsgo/zllm/agent/agenttest"
+	"github.com/zlsgo/zllm/message"
+	"github.com/zlsgo/zllm/otel"
+	"github.com/zlsgo/zllm/runtime"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type weatherRunner struct{}
+
+func (weatherRunner) Run(_ context.Context, name, args string) (string, error) {
+	return "晴", nil
+}
+
+func TestTracer(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+	defer runtime.SetTracer(nil)
+
+	run := func(tt *zlsgo.TestUtil, capture bool) map[string]tracetest.SpanStub {
+		exporter := tracetest.NewInMemoryExporter()
+		otel.Setup(func(o *otel.Options) {
+			o.TracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+			o.CaptureContent = capture
+		})
+
+		srv := agenttest.NewServer(agenttest.OpenAI,
+			agenttest.ToolCall("weather", `{"city":"北京"}`),
+			agenttest.Text("ok"),
+		)
+		defer srv.Close()
+
+		llm := agent.NewOpenAI(func(o *agent.OpenAIOptions) {
+			o.BaseURL = srv.URL + "/v1"
+			o.APIKey = "sk-test"
+			o.Model = "gpt-4o"
+		})
+		ctx := zllm.WithToolRunner(context.Background(), weatherRunner{})
+		_, err := zllm.CompleteLLM(ctx, llm, message.NewPrompt("北京天气如何"))
+		tt.NoError(err, true)
+
+		spans := exporter.GetSpans()
+		tt.Equal(4, len(spans))
+		byName := map[string]tracetest.SpanStub{}
+		for _, s := range spans {
+			byName[s.Name] = s
+		}
+		return byName
+	}
+
+	attrs := func(s tracetest.SpanStub) map[string]string {
+		m := map[string]string{}
+		for _, kv := range s.Attributes {
+			m[string(kv.Key)] = kv.Value.Emit()
+		}
+		return m
+	}
+
+	tt.Run("Spans", func(tt *zlsgo.TestUtil) {
+		spans := run(tt, false)
+
+		root, ok := spans["zllm.CompleteLLM"]
+		tt.EqualTrue(ok)
+		tt.Equal("openai", attrs(root)[runtime.AttrSystem])
+		tt.Equal("gpt-4o", attrs(root)[runtime.AttrRequestModel])
+
+		chat, ok := spans["chat gpt-4o"]
+		tt.EqualTrue(ok)
+		tt.Equal(trace.SpanKindClient, chat.SpanKind)
+		tt.Equal(root.SpanContext.SpanID(), chat.Parent.SpanID())
+		a := attrs(chat)
+		tt.Equal(runtime.OperationChat, a[runtime.AttrOperationName])
+		tt.Equal("openai", a[runtime.AttrSystem])
+		tt.Equal("200", a[runtime.AttrHTTPStatusCode])
+		_, ok = a[runtime.AttrPrompt]
+		tt.EqualTrue(!ok)
+
+		tool, ok := spans["execute_tool weather"]
+		tt.EqualTrue(ok)
+		tt.Equal(root.SpanContext.SpanID(), tool.Parent.SpanID())
+		tt.Equal("weather", attrs(tool)[runtime.AttrToolName])
+		_, ok = attrs(tool)[runtime.AttrToolCallArguments]
+		tt.EqualTrue(!ok)
+	})
+
+	tt.Run("CaptureContent", func(tt *zlsgo.TestUtil) {
+		spans := run(tt, true)
+
+		tt.EqualTrue(strings.Contains(attrs(spans["chat gpt-4o"])[runtime.AttrPrompt], "北京天气如何"))
+		tt.Equal(`{"city":"北京"}`, attrs(spans["execute_tool weather"])[runtime.AttrToolCallArguments])
+		tt.Equal("晴", attrs(spans["execute_tool weather"])[runtime.AttrToolCallResult])
+		tt.EqualTrue(attrs(spans["zllm.CompleteLLM"])[runtime.AttrCompletion] != "")
+	})
+}
@@ -0,0 +1,33 @@
+module github.com/zlsgo/zllm/prometheus
+
+// github.com/prometheus/client_golang v1.23 要求 go 1.23，因此独立为子模块，主模块仍保持 go 1.18
+go 1.23.0
+
+require (
+	github.com/sohaha/zlsgo v1.7.20
+	// 主模块尚未发布包含该子模块所需接口的版本，发布后改为对应 tag，见 README 子模块发布
+	github.com/zlsgo/zllm v0.0.0-00010101000000-000000000000
+)
+
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/kr/text v0.2.0 // indirect
+	github.com/kylelemons/godebug v1.1.0 // indirect
+	github.com/munnerz/goautoneg v0.0.0-20191010083416-a7dc8b61c822 // indirect
+	github.com/prometheus/client_golang v1.23.2
+	github.com/prometheus/client_model v0.6.2 // indirect
+	github.com/prometheus/common v0.66.1 // indirect
+	github.com/prometheus/procfs v0.16.1 // indirect
+	go.yaml.in/yaml/v2 v2.4.2 // indirect
+	golang.org/x/crypto v0.41.0 // indirect
+	golang.org/x/exp v0.0.0-20230213192124-5e25df0256eb // indirect
+	golang.org/x/net v0.43.0 // indirect
+	golang.org/x/sync v0.16.0 // indirect
+	golang.org/x/sys v0.35.0 // indirect
+	golang.org/x/text v0.28.0 // indirect
+	google.golang.org/protobuf v1.36.8 // indirect
+)
+
+// 本地开发时使用仓库内的主模块
+replace github.com/zlsgo/zllm => ../
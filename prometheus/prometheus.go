@@ -0,0 +1,109 @@
+// Package prometheus 提供基于 Prometheus 客户端的指标实现
+//
+//	prometheus.Setup(func(o *prometheus.Options) {
+//		o.Registerer = registry
+//	})
+package prometheus
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sohaha/zlsgo/zutil"
+	"github.com/zlsgo/zllm/runtime"
+)
+
+// DefaultBuckets 默认直方图分桶（秒），覆盖首个分片到长时间生成的耗时范围
+var DefaultBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+// Options 指标配置
+type Options struct {
+	// Registerer 指标注册器，默认使用 prometheus.DefaultRegisterer
+	Registerer prometheus.Registerer
+	// ConstLabels 附加到所有指标的固定标签
+	ConstLabels prometheus.Labels
+	// Namespace 指标名称前缀
+	Namespace string
+	// Buckets 直方图分桶，默认 DefaultBuckets
+	Buckets []float64
+}
+
+// Metrics Prometheus 指标
+type Metrics struct {
+	counters   map[string]*prometheus.CounterVec
+	histograms map[string]*prometheus.HistogramVec
+}
+
+var _ runtime.Metrics = &Metrics{}
+
+// New 创建 Prometheus 指标并注册 runtime.MetricDescs 中的全部指标
+func New(opt ...func(*Options)) (*Metrics, error) {
+	o := zutil.Optional(Options{
+		Registerer: prometheus.DefaultRegisterer,
+		Buckets:    DefaultBuckets,
+	}, opt...)
+
+	m := &Metrics{
+		counters:   map[string]*prometheus.CounterVec{},
+		histograms: map[string]*prometheus.HistogramVec{},
+	}
+	for _, desc := range runtime.MetricDescs() {
+		var c prometheus.Collector
+		switch desc.Kind {
+		case runtime.MetricHistogram:
+			h := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+				Namespace:   o.Namespace,
+				Name:        desc.Name,
+				Help:        desc.Help,
+				ConstLabels: o.ConstLabels,
+				Buckets:     o.Buckets,
+			}, desc.Labels)
+			m.histograms[desc.Name] = h
+			c = h
+		default:
+			v := prometheus.NewCounterVec(prometheus.CounterOpts{
+				Namespace:   o.Namespace,
+				Name:        desc.Name,
+				Help:        desc.Help,
+				ConstLabels: o.ConstLabels,
+			}, desc.Labels)
+			m.counters[desc.Name] = v
+			c = v
+		}
+		if err := o.Registerer.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// Setup 创建 Prometheus 指标并设置为全局指标记录器
+func Setup(opt ...func(*Options)) (*Metrics, error) {
+	m, err := New(opt...)
+	if err != nil {
+		return nil, err
+	}
+	runtime.SetMetrics(m)
+	return m, nil
+}
+
+// Add 计数器累加，未注册的指标或标签数量不匹配时忽略
+func (m *Metrics) Add(name string, value float64, labelValues ...string) {
+	v, ok := m.counters[name]
+	if !ok {
+		return
+	}
+	if c, err := v.GetMetricWithLabelValues(labelValues...); err == nil {
+		c.Add(value)
+	}
+}
+
+// Observe 直方图记录观测值，未注册的指标或标签数量不匹配时忽略
+func (m *Metrics) Observe(name string, value float64, labelValues ...string) {
+	v, ok := m.histograms[name]
+	if !ok {
+		return
+	}
+	if h, err := v.GetMetricWithLabelValues(labelValues...); err == nil {
+		h.Observe(value)
+	}
+}
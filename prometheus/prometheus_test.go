@@ -0,0 +1,56 @@
+package prometheus_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	client "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sohaha/zlsgo"
+	"github.com/zlsgo/zllm"
+	"github.com/zlsgo/zllm/agent"
+	"github.com/zlsgo/zllm/agent/agenttest"
+	"github.com/zlsgo/zllm/message"
+	"github.com/zlsgo/zllm/prometheus"
+	"github.com/zlsgo/zllm/runtime"
+)
+
+func TestMetrics(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+	defer runtime.SetMetrics(nil)
+
+	registry := client.NewRegistry()
+	_, err := prometheus.Setup(func(o *prometheus.Options) {
+		o.Registerer = registry
+	})
+	tt.NoError(err, true)
+
+	srv := agenttest.NewServer(agenttest.OpenAI, agenttest.Text("hi").WithUsage(10, 5, 0))
+	defer srv.Close()
+
+	llm := agent.NewOpenAI(func(o *agent.OpenAIOptions) {
+		o.BaseURL = srv.URL + "/v1"
+		o.APIKey = "sk-test"
+		o.Model = "gpt-4o"
+	})
+	_, err = zllm.CompleteLLM(context.Background(), llm, message.NewPrompt("hello"))
+	tt.NoError(err, true)
+
+	expected := `
+# HELP zllm_tokens_total token 用量
+# TYPE zllm_tokens_total counter
+zllm_tokens_total{model="gpt-4o",provider="openai",type="input"} 10
+zllm_tokens_total{model="gpt-4o",provider="openai",type="output"} 5
+`
+	tt.NoError(testutil.GatherAndCompare(registry, strings.NewReader(expected), "zllm_tokens_total"), true)
+
+	count, err := testutil.GatherAndCount(registry, "zllm_requests_total", "zllm_request_duration_seconds")
+	tt.NoError(err, true)
+	tt.Equal(2, count)
+
+	_, err = prometheus.New(func(o *prometheus.Options) {
+		o.Registerer = registry
+	})
+	tt.EqualTrue(err != nil)
+}
@@ -0,0 +1,371 @@
+package prompt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/zlsgo/zllm"
+	"github.com/zlsgo/zllm/agent"
+	"github.com/zlsgo/zllm/message"
+	"gopkg.in/yaml.v3"
+)
+
+// Example 提示词示例
+type Example struct {
+	Input  string `yaml:"input" json:"input"`
+	Output string `yaml:"output" json:"output"`
+}
+
+// ModelHints 提示词推荐的模型参数
+type ModelHints struct {
+	Model       string   `yaml:"model,omitempty" json:"model,omitempty"`
+	Temperature *float64 `yaml:"temperature,omitempty" json:"temperature,omitempty"`
+	MaxTokens   int      `yaml:"max_tokens,omitempty" json:"max_tokens,omitempty"`
+}
+
+// CallOptions 转换为单次调用参数
+func (h ModelHints) CallOptions() agent.CallOptions {
+	return agent.CallOptions{
+		Model:       h.Model,
+		Temperature: h.Temperature,
+		Sampling:    agent.Sampling{MaxTokens: h.MaxTokens},
+	}
+}
+
+// Definition 从文件加载的提示词定义
+type Definition struct {
+	Name         string            `yaml:"name" json:"name"`
+	Version      string            `yaml:"version" json:"version"`
+	Description  string            `yaml:"description,omitempty" json:"description,omitempty"`
+	Input        string            `yaml:"input,omitempty" json:"input,omitempty"`
+	SystemPrompt string            `yaml:"system,omitempty" json:"system,omitempty"`
+	Steps        []string          `yaml:"steps,omitempty" json:"steps,omitempty"`
+	Rules        []string          `yaml:"rules,omitempty" json:"rules,omitempty"`
+	Examples     []Example         `yaml:"examples,omitempty" json:"examples,omitempty"`
+	ExampleTurns bool              `yaml:"examples_as_messages,omitempty" json:"examples_as_messages,omitempty"` // 示例作为对话轮次发送
+	OutputFormat map[string]string `yaml:"output_format,omitempty" json:"output_format,omitempty"`
+	Placeholder  map[string]string `yaml:"placeholder,omitempty" json:"placeholder,omitempty"`
+	MaxLength    int               `yaml:"max_length,omitempty" json:"max_length,omitempty"`
+	Model        ModelHints        `yaml:"model_hints,omitempty" json:"model_hints,omitempty"`
+	Weight       int               `yaml:"weight,omitempty" json:"weight,omitempty"` // A/B 分流权重，默认 1
+	Path         string            `yaml:"-" json:"-"`
+}
+
+// Prompt 将定义转换为结构化提示词，input 为用户输入
+// 定义中设置了 input 模板时使用模板，模板中的变量通过 ConvertToMessages 的 Placeholder 传入
+func (d *Definition) Prompt(input ...string) *message.Prompt {
+	text := d.Input
+	if text == "" {
+		text = strings.Join(input, "\n")
+	}
+
+	return message.NewPrompt(text, func(po *message.PromptOptions) {
+		po.SystemPrompt = d.SystemPrompt
+		po.Steps = d.Steps
+		po.Rules = d.Rules
+		po.MaxLength = d.MaxLength
+		po.ExamplesAsMessages = d.ExampleTurns
+		if len(d.OutputFormat) > 0 {
+			po.OutputFormat = message.CustomOutputFormat(d.OutputFormat)
+		}
+		if len(d.Placeholder) > 0 {
+			po.Placeholder = d.Placeholder
+		}
+		for _, e := range d.Examples {
+			po.Examples = append(po.Examples, [2]string{e.Input, e.Output})
+		}
+	})
+}
+
+// WithCallOptions 在上下文中设置提示词推荐的模型参数，CompleteLLM 调用时覆盖提供商配置
+func (d *Definition) WithCallOptions(ctx context.Context) context.Context {
+	co := d.Model.CallOptions()
+	if co.IsZero() {
+		return ctx
+	}
+	return zllm.WithCallOptions(ctx, co)
+}
+
+// ErrPromptNotFound 提示词不存在
+var ErrPromptNotFound = errors.New("prompt not found")
+
+// Library 提示词库，按名称管理多个版本的提示词
+type Library struct {
+	mu      sync.RWMutex
+	prompts map[string][]*Definition
+}
+
+// NewLibrary 创建提示词库
+func NewLibrary() *Library {
+	return &Library{prompts: make(map[string][]*Definition)}
+}
+
+// LoadDir 递归加载目录下的 .md、.yaml、.yml 提示词文件
+func (l *Library) LoadDir(dir string) error {
+	var errs []string
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("error accessing %s: %v", path, err))
+			return nil
+		}
+		if d.IsDir() || !isPromptFile(path) {
+			return nil
+		}
+		if err := l.LoadFile(path); err != nil {
+			errs = append(errs, err.Error())
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to load %d prompt files: %s", len(errs), strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// LoadFile 加载单个提示词文件
+// Markdown 文件使用 YAML front matter 描述元数据，正文作为系统提示词；
+// 未指定名称时使用文件名，文件名形如 name@version.md 时同时解析版本
+func (l *Library) LoadFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read prompt file %s: %v", path, err)
+	}
+
+	def, err := ParseDefinition(string(content), strings.ToLower(filepath.Ext(path)) == ".md")
+	if err != nil {
+		return fmt.Errorf("failed to parse prompt file %s: %v", path, err)
+	}
+
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	name, version := base, ""
+	if i := strings.LastIndex(base, "@"); i > 0 {
+		name, version = base[:i], base[i+1:]
+	}
+	if def.Name == "" {
+		def.Name = name
+	}
+	if def.Version == "" {
+		def.Version = version
+	}
+	def.Path = path
+
+	return l.Add(def)
+}
+
+// ParseDefinition 解析提示词定义，markdown 为 true 时解析 front matter 与正文
+func ParseDefinition(content string, markdown bool) (*Definition, error) {
+	def := &Definition{}
+
+	if !markdown {
+		if err := yaml.Unmarshal([]byte(content), def); err != nil {
+			return nil, err
+		}
+		return def, nil
+	}
+
+	body := strings.TrimSpace(content)
+	if strings.HasPrefix(body, "---") {
+		parts := strings.SplitN(body, "---", 3)
+		if len(parts) < 3 {
+			return nil, errors.New("unterminated front matter")
+		}
+		if err := yaml.Unmarshal([]byte(parts[1]), def); err != nil {
+			return nil, fmt.Errorf("failed to parse YAML frontmatter: %v", err)
+		}
+		body = strings.TrimSpace(parts[2])
+	}
+
+	if def.SystemPrompt == "" {
+		def.SystemPrompt = body
+	}
+	return def, nil
+}
+
+// Add 添加提示词定义，同名同版本的定义会被覆盖
+func (l *Library) Add(def *Definition) error {
+	if def == nil || strings.TrimSpace(def.Name) == "" {
+		return errors.New("prompt name cannot be empty")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	versions := l.prompts[def.Name]
+	for i := range versions {
+		if versions[i].Version == def.Version {
+			versions[i] = def
+			return nil
+		}
+	}
+
+	versions = append(versions, def)
+	sort.SliceStable(versions, func(i, j int) bool {
+		return compareVersion(versions[i].Version, versions[j].Version) < 0
+	})
+	l.prompts[def.Name] = versions
+	return nil
+}
+
+// Names 返回所有提示词名称
+func (l *Library) Names() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	names := make([]string, 0, len(l.prompts))
+	for name := range l.prompts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Versions 返回提示词的所有版本，按版本号升序排列
+func (l *Library) Versions(name string) []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	versions := make([]string, 0, len(l.prompts[name]))
+	for _, def := range l.prompts[name] {
+		versions = append(versions, def.Version)
+	}
+	return versions
+}
+
+// Get 获取指定版本的提示词定义，未指定版本时返回最新版本
+func (l *Library) Get(name string, version ...string) (*Definition, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	versions := l.prompts[name]
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrPromptNotFound, name)
+	}
+
+	if len(version) == 0 || version[0] == "" {
+		return versions[len(versions)-1], nil
+	}
+
+	for _, def := range versions {
+		if def.Version == version[0] {
+			return def, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s@%s", ErrPromptNotFound, name, version[0])
+}
+
+// Prompt 获取指定版本的结构化提示词，用户输入需通过定义的 input 模板与 Placeholder 传入，
+// 未设置 input 模板时使用 Get 获取定义后调用 Definition.Prompt 传入用户输入
+func (l *Library) Prompt(name string, version ...string) (*message.Prompt, error) {
+	def, err := l.Get(name, version...)
+	if err != nil {
+		return nil, err
+	}
+	return def.Prompt(), nil
+}
+
+// Pick 按权重在提示词的多个版本之间进行 A/B 分流
+// key 不为空时同一个 key 始终命中同一版本，为空时随机选择
+func (l *Library) Pick(name, key string) (*Definition, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	versions := l.prompts[name]
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrPromptNotFound, name)
+	}
+
+	total := 0
+	for _, def := range versions {
+		total += weightOf(def)
+	}
+	if total == 0 {
+		return versions[len(versions)-1], nil
+	}
+
+	var n int
+	if key == "" {
+		n = rand.Intn(total)
+	} else {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(name + "/" + key))
+		n = int(h.Sum32() % uint32(total))
+	}
+
+	for _, def := range versions {
+		n -= weightOf(def)
+		if n < 0 {
+			return def, nil
+		}
+	}
+	return versions[len(versions)-1], nil
+}
+
+// weightOf 返回定义的分流权重，未设置时为 1，负数视为 0
+func weightOf(def *Definition) int {
+	if def.Weight == 0 {
+		return 1
+	}
+	if def.Weight < 0 {
+		return 0
+	}
+	return def.Weight
+}
+
+// isPromptFile 判断是否为支持的提示词文件
+func isPromptFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".yaml", ".yml":
+		return true
+	default:
+		return false
+	}
+}
+
+// compareVersion 比较版本号，按点分段优先数值比较
+func compareVersion(a, b string) int {
+	as := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bs := strings.Split(strings.TrimPrefix(b, "v"), ".")
+
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y string
+		if i < len(as) {
+			x = as[i]
+		}
+		if i < len(bs) {
+			y = bs[i]
+		}
+
+		xn, xerr := strconv.Atoi(x)
+		yn, yerr := strconv.Atoi(y)
+		switch {
+		case xerr == nil && yerr == nil:
+			if xn != yn {
+				if xn < yn {
+					return -1
+				}
+				return 1
+			}
+		case x != y:
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,138 @@
+package prompt_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sohaha/zlsgo"
+	"github.com/sohaha/zlsgo/zjson"
+	"github.com/zlsgo/zllm"
+	"github.com/zlsgo/zllm/agent"
+	"github.com/zlsgo/zllm/agent/agenttest"
+	"github.com/zlsgo/zllm/message"
+	"github.com/zlsgo/zllm/prompt"
+)
+
+const translateV1 = `---
+name: translate
+version: "1.0"
+steps:
+  - 翻译成 **{{language}}**
+placeholder:
+  language: 中文
+examples:
+  - input: Hello
+    output: 你好
+model_hints:
+  model: gpt-4o-mini
+  temperature: 0.2
+---
+你是一位翻译专家。`
+
+const translateV2 = `name: translate
+version: "1.10"
+system: 你是一位资深翻译专家。
+input: "{{text}}"
+rules:
+  - 保证准确性
+output_format:
+  result: "{}"
+weight: 3
+`
+
+func TestLibrary(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	dir := t.TempDir()
+	tt.NoError(os.WriteFile(filepath.Join(dir, "translate.md"), []byte(translateV1), 0o644), true)
+	tt.NoError(os.MkdirAll(filepath.Join(dir, "v2"), 0o755), true)
+	tt.NoError(os.WriteFile(filepath.Join(dir, "v2", "translate.yaml"), []byte(translateV2), 0o644), true)
+	tt.NoError(os.WriteFile(filepath.Join(dir, "summary@0.1.md"), []byte("请总结用户输入"), 0o644), true)
+	tt.NoError(os.WriteFile(filepath.Join(dir, "ignore.txt"), []byte("ignored"), 0o644), true)
+
+	lib := prompt.NewLibrary()
+	tt.NoError(lib.LoadDir(dir), true)
+
+	tt.Equal([]string{"summary", "translate"}, lib.Names())
+	tt.Equal([]string{"1.0", "1.10"}, lib.Versions("translate"))
+
+	tt.Run("Latest", func(tt *zlsgo.TestUtil) {
+		def, err := lib.Get("translate")
+		tt.NoError(err, true)
+		tt.Equal("1.10", def.Version)
+
+		p, err := lib.Prompt("translate")
+		tt.NoError(err, true)
+		msg, err := p.ConvertToMessages(message.PromptConvertOptions{Placeholder: map[string]string{"text": "Hi"}})
+		tt.NoError(err, true)
+		content := msg.History(true)[0][1]
+		tt.EqualTrue(strings.HasPrefix(content, "# System\n你是一位资深翻译专家。"))
+		tt.EqualTrue(strings.Contains(content, `{"result":"{}"}`))
+		tt.EqualTrue(strings.HasSuffix(content, "Hi"))
+	})
+
+	tt.Run("Version", func(tt *zlsgo.TestUtil) {
+		def, err := lib.Get("translate", "1.0")
+		tt.NoError(err, true)
+		tt.Equal("你是一位翻译专家。", def.SystemPrompt)
+		tt.Equal("gpt-4o-mini", def.Model.Model)
+		tt.Equal(0.2, *def.Model.Temperature)
+
+		p := def.Prompt("Hello")
+		msg, err := p.ConvertToMessages()
+		tt.NoError(err, true)
+		content := msg.History(true)[0][1]
+		tt.EqualTrue(strings.HasSuffix(content, "Hello"))
+		tt.EqualTrue(strings.Contains(content, "翻译成 **中文**"))
+		tt.EqualTrue(strings.Contains(content, "**Output**: 你好"))
+
+		srv := agenttest.NewServer(agenttest.OpenAI, agenttest.Text(`{"Assistant":"你好"}`))
+		defer srv.Close()
+		llm := agent.NewOpenAI(func(o *agent.OpenAIOptions) {
+			o.BaseURL = srv.URL
+			o.APIURL = "/chat/completions"
+			o.APIKey = "sk-test"
+			o.Model = "gpt-4o"
+		})
+		_, err = zllm.CompleteLLM(def.WithCallOptions(context.Background()), llm, p)
+		tt.NoError(err, true)
+		body := zjson.ParseBytes(srv.Requests()[0].Body)
+		tt.Equal("gpt-4o-mini", body.Get("model").String())
+		tt.Equal(0.2, body.Get("temperature").Float())
+
+		def, err = lib.Get("summary", "0.1")
+		tt.NoError(err, true)
+		tt.Equal("请总结用户输入", def.SystemPrompt)
+
+		_, err = lib.Get("translate", "9.9")
+		tt.EqualTrue(errors.Is(err, prompt.ErrPromptNotFound))
+		_, err = lib.Get("missing")
+		tt.EqualTrue(errors.Is(err, prompt.ErrPromptNotFound))
+	})
+
+	tt.Run("Pick", func(tt *zlsgo.TestUtil) {
+		first, err := lib.Pick("translate", "user-1")
+		tt.NoError(err, true)
+		for i := 0; i < 5; i++ {
+			again, _ := lib.Pick("translate", "user-1")
+			tt.Equal(first.Version, again.Version)
+		}
+
+		hits := map[string]int{}
+		for i := 0; i < 400; i++ {
+			def, _ := lib.Pick("translate", "")
+			hits[def.Version]++
+		}
+		tt.EqualTrue(hits["1.10"] > hits["1.0"])
+	})
+
+	tt.Run("Invalid", func(tt *zlsgo.TestUtil) {
+		bad := t.TempDir()
+		tt.NoError(os.WriteFile(filepath.Join(bad, "bad.yaml"), []byte("name: [x"), 0o644), true)
+		tt.EqualTrue(prompt.NewLibrary().LoadDir(bad) != nil)
+	})
+}
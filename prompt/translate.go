@@ -11,6 +11,21 @@
 // 生成产品描述和分析客户反馈：
 //
 //	desc, err := prompt.GenerateEcommerceProductDescription(ctx, llmAgent, productInfo)
+//
+// # 提示词库
+//
+// 从 Markdown/YAML 文件加载带版本的提示词，并支持版本间 A/B 分流：
+//
+//	lib := prompt.NewLibrary()
+//	err := lib.LoadDir("./prompts")
+//	def, err := lib.Pick("translate", userID)
+//	messages, err := def.Prompt(userText).ConvertToMessages()
+//
+// 定义中设置了 input 模板（如 input: "{{text}}"）时，通过 Placeholder 传入模板变量：
+//
+//	messages, err := def.Prompt().ConvertToMessages(message.PromptConvertOptions{
+//		Placeholder: map[string]string{"text": userText},
+//	})
 package prompt
 
 import (
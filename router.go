@@ -0,0 +1,457 @@
+package zllm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sohaha/zlsgo/zjson"
+	"github.com/sohaha/zlsgo/zstring"
+	"github.com/sohaha/zlsgo/ztype"
+	"github.com/sohaha/zlsgo/zutil"
+	"github.com/zlsgo/zllm/agent"
+	"github.com/zlsgo/zllm/message"
+	"github.com/zlsgo/zllm/runtime"
+	runtime_errors "github.com/zlsgo/zllm/runtime/errors"
+)
+
+// DefaultClassifierPrompt 默认的任务复杂度分类提示词
+const DefaultClassifierPrompt = "判断以下用户请求是简单任务（闲聊、简短问答、简单改写）还是复杂任务（多步推理、代码、长文写作、数据分析），只回答 simple 或 complex。\n\n用户请求：\n"
+
+// routeField 路由请求体与响应中记录路由名称的字段
+const routeField = "zllm_route"
+
+// LatencyClass 延迟等级，数值越小越快
+type LatencyClass int
+
+const (
+	LatencyAny    LatencyClass = iota // 不限制
+	LatencyLow                        // 低延迟
+	LatencyMedium                     // 中等延迟
+	LatencyHigh                       // 高延迟
+)
+
+// Complexity 任务复杂度
+type Complexity string
+
+const (
+	ComplexityAny     Complexity = ""        // 不区分
+	ComplexitySimple  Complexity = "simple"  // 简单任务
+	ComplexityComplex Complexity = "complex" // 复杂任务
+)
+
+// Route 路由目标
+type Route struct {
+	LLM          agent.LLM           // 目标 LLM
+	Capabilities *agent.Capabilities // 模型能力，为空时从模型目录查询
+	Name         string              // 名称，用于日志与规则匹配
+	Complexity   Complexity          // 适用的任务复杂度，为空表示不限
+	InputCost    float64             // 每百万输入 token 成本
+	OutputCost   float64             // 每百万输出 token 成本
+	Latency      LatencyClass        // 延迟等级
+	MinTokens    int                 // 适用的最小输入 token 数
+	MaxTokens    int                 // 适用的最大输入 token 数，0 表示不限
+}
+
+// RouteHints 单次请求的路由约束
+type RouteHints struct {
+	Capabilities agent.Capabilities // 额外要求的能力，如 Vision
+	Complexity   Complexity         // 指定任务复杂度，为空时由分类器判定
+	MaxLatency   LatencyClass       // 可接受的最高延迟等级
+	MaxCost      float64            // 单次请求的成本上限
+	OutputTokens int                // 预估输出 token 数，用于计算成本
+}
+
+// RouteRequest 路由规则可用的请求信息
+type RouteRequest struct {
+	Messages *message.Messages
+	Hints    RouteHints
+	Required agent.Capabilities // 请求所需能力，由请求参数与路由约束推断
+	Tokens   int                // 估算的输入 token 数
+}
+
+// RouteRule 自定义路由规则，返回路由名称与原因，ok 为 false 时交由后续规则处理
+type RouteRule func(ctx context.Context, req RouteRequest) (name, reason string, ok bool)
+
+// RouteDecision 路由决策
+type RouteDecision struct {
+	Route      string
+	Reason     string
+	Complexity Complexity
+	Tokens     int
+}
+
+// RouterOptions 路由器配置
+type RouterOptions struct {
+	Classifier       agent.LLM              // 判定简单/复杂任务的分类 LLM，为空时不分类
+	Estimator        message.TokenEstimator // token 估算器，默认 message.EstimateTokens
+	OnDecision       func(RouteDecision)    // 路由决策回调
+	ClassifierPrompt string                 // 分类提示词，默认 DefaultClassifierPrompt
+	Fallback         string                 // 无路由满足条件时使用的路由名称
+	Rules            []RouteRule            // 自定义规则，按顺序优先于内置筛选
+	PreferCheapest   bool                   // 在满足条件的路由中选择成本最低者，否则按声明顺序
+}
+
+// Router 按请求特征选择 LLM 的路由器，实现 agent.LLM
+type Router struct {
+	routes  []Route
+	options RouterOptions
+}
+
+var (
+	_ agent.LLM            = &Router{}
+	_ agent.CallOptionsLLM = &Router{}
+	_ agent.ResolverLLM    = &Router{}
+)
+
+type routeHintsKey struct{}
+
+// WithRouteHints 在上下文中设置单次请求的路由约束
+func WithRouteHints(ctx context.Context, h RouteHints) context.Context {
+	return context.WithValue(ctx, routeHintsKey{}, h)
+}
+
+// getRouteHints 从上下文中获取路由约束
+func getRouteHints(ctx context.Context) RouteHints {
+	h, _ := ctx.Value(routeHintsKey{}).(RouteHints)
+	return h
+}
+
+// NewRouter 创建路由器，routes 按声明顺序作为优先级
+func NewRouter(routes []Route, opt ...func(*RouterOptions)) *Router {
+	o := zutil.Optional(RouterOptions{
+		Estimator:        message.EstimateTokens,
+		ClassifierPrompt: DefaultClassifierPrompt,
+	}, opt...)
+
+	routes = append([]Route(nil), routes...)
+	for i := range routes {
+		if routes[i].Name == "" {
+			routes[i].Name = fmt.Sprintf("route-%d", i)
+		}
+	}
+	return &Router{routes: routes, options: o}
+}
+
+// Route 为请求选择路由
+func (r *Router) Route(ctx context.Context, messages *message.Messages, options ...func(ztype.Map) ztype.Map) (Route, RouteDecision, error) {
+	if len(r.routes) == 0 {
+		return Route{}, RouteDecision{}, runtime_errors.NewLLMError(runtime_errors.ErrInvalidRequest, "router has no routes")
+	}
+
+	req := r.request(ctx, messages, options...)
+	decision := RouteDecision{Tokens: req.Tokens}
+
+	for _, rule := range r.options.Rules {
+		name, reason, ok := rule(ctx, req)
+		if !ok {
+			continue
+		}
+		if route, found := r.find(name); found {
+			decision.Route, decision.Reason = route.Name, "rule: "+reason
+			return route, r.decide(ctx, decision), nil
+		}
+		runtime.Warn(ctx, "Router rule returned unknown route", runtime.Attr("route", name))
+	}
+
+	decision.Complexity = req.Hints.Complexity
+	if decision.Complexity == ComplexityAny && r.needComplexity() {
+		decision.Complexity = r.classify(ctx, messages)
+	}
+
+	var (
+		candidates []Route
+		rejected   []string
+	)
+	for _, route := range r.routes {
+		if reason := r.reject(route, req, decision.Complexity); reason != "" {
+			rejected = append(rejected, route.Name+": "+reason)
+			continue
+		}
+		candidates = append(candidates, route)
+	}
+
+	if len(candidates) == 0 {
+		if route, ok := r.find(r.options.Fallback); ok {
+			decision.Route, decision.Reason = route.Name, "fallback, "+strings.Join(rejected, "; ")
+			return route, r.decide(ctx, decision), nil
+		}
+		return Route{}, decision, runtime_errors.NewLLMErrorWithDetails(runtime_errors.ErrInvalidRequest,
+			"no route satisfies request: "+strings.Join(rejected, "; "),
+			map[string]interface{}{"rejected": rejected})
+	}
+
+	route := candidates[0]
+	reason := "first matching route"
+	if r.options.PreferCheapest && len(candidates) > 1 {
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return r.cost(candidates[i], req) < r.cost(candidates[j], req)
+		})
+		route = candidates[0]
+		reason = fmt.Sprintf("cheapest of %d candidates", len(candidates))
+	}
+
+	details := []string{reason, fmt.Sprintf("tokens=%d", req.Tokens)}
+	if decision.Complexity != ComplexityAny {
+		details = append(details, "complexity="+string(decision.Complexity))
+	}
+	if len(rejected) > 0 {
+		details = append(details, "rejected: "+strings.Join(rejected, "; "))
+	}
+	decision.Route, decision.Reason = route.Name, strings.Join(details, ", ")
+	return route, r.decide(ctx, decision), nil
+}
+
+// request 汇总路由所需的请求信息
+func (r *Router) request(ctx context.Context, messages *message.Messages, options ...func(ztype.Map) ztype.Map) RouteRequest {
+	req := RouteRequest{Messages: messages, Hints: getRouteHints(ctx)}
+	if messages != nil {
+		req.Tokens = messages.Tokens(r.options.Estimator)
+	}
+
+	params := ztype.Map{}
+	for _, v := range options {
+		params = v(params)
+	}
+	body, _ := json.Marshal(params)
+	req.Required = agent.RequiredCapabilities(body)
+
+	need := req.Hints.Capabilities
+	req.Required.Tools = req.Required.Tools || need.Tools
+	req.Required.JSONSchema = req.Required.JSONSchema || need.JSONSchema
+	req.Required.Vision = need.Vision
+	req.Required.Reasoning = need.Reasoning
+	req.Required.Streaming = need.Streaming
+	req.Required.StreamingTools = need.StreamingTools
+	req.Required.ContextWindow = req.Tokens
+	if need.ContextWindow > req.Tokens {
+		req.Required.ContextWindow = need.ContextWindow
+	}
+	req.Required.MaxOutputTokens = need.MaxOutputTokens
+	return req
+}
+
+// reject 返回路由不满足请求的原因，满足时返回空字符串
+func (r *Router) reject(route Route, req RouteRequest, complexity Complexity) string {
+	caps, known := agent.ModelCapabilities(route.LLM)
+	if route.Capabilities != nil {
+		caps, known = *route.Capabilities, true
+	}
+	if known {
+		if missing := caps.Missing(req.Required); len(missing) > 0 {
+			return "missing " + strings.Join(missing, ", ")
+		}
+	}
+
+	if req.Tokens < route.MinTokens {
+		return fmt.Sprintf("tokens %d < min %d", req.Tokens, route.MinTokens)
+	}
+	if route.MaxTokens > 0 && req.Tokens > route.MaxTokens {
+		return fmt.Sprintf("tokens %d > max %d", req.Tokens, route.MaxTokens)
+	}
+	if req.Hints.MaxLatency != LatencyAny && route.Latency > req.Hints.MaxLatency {
+		return "latency too high"
+	}
+	if req.Hints.MaxCost > 0 {
+		if cost := r.cost(route, req); cost > req.Hints.MaxCost {
+			return fmt.Sprintf("cost %.6f > max %.6f", cost, req.Hints.MaxCost)
+		}
+	}
+	if complexity != ComplexityAny && route.Complexity != ComplexityAny && route.Complexity != complexity {
+		return "for " + string(route.Complexity) + " tasks"
+	}
+	return ""
+}
+
+// cost 估算路由处理请求的成本
+func (r *Router) cost(route Route, req RouteRequest) float64 {
+	return (float64(req.Tokens)*route.InputCost + float64(req.Hints.OutputTokens)*route.OutputCost) / 1e6
+}
+
+// needComplexity 是否存在按复杂度区分的路由
+func (r *Router) needComplexity() bool {
+	if r.options.Classifier == nil {
+		return false
+	}
+	for i := range r.routes {
+		if r.routes[i].Complexity != ComplexityAny {
+			return true
+		}
+	}
+	return false
+}
+
+// classify 调用分类 LLM 判定任务复杂度，失败时返回 ComplexityAny
+func (r *Router) classify(ctx context.Context, messages *message.Messages) Complexity {
+	if messages == nil {
+		return ComplexityAny
+	}
+
+	msg := message.NewMessages()
+	_ = msg.AppendUser(r.options.ClassifierPrompt + messages.Input())
+	llm := r.options.Classifier
+	body, err := llm.PrepareRequest(msg)
+	if err != nil {
+		runtime.Warn(ctx, "Router classifier error", runtime.ErrAttr(err))
+		return ComplexityAny
+	}
+	res, err := llm.Generate(ctx, body)
+	if err != nil {
+		runtime.Warn(ctx, "Router classifier error", runtime.ErrAttr(err))
+		return ComplexityAny
+	}
+	resp, err := llm.ParseResponse(res)
+	if err != nil {
+		runtime.Warn(ctx, "Router classifier error", runtime.ErrAttr(err))
+		return ComplexityAny
+	}
+
+	answer := strings.ToLower(zstring.Bytes2String(resp.Content))
+	switch {
+	case strings.Contains(answer, string(ComplexityComplex)):
+		return ComplexityComplex
+	case strings.Contains(answer, string(ComplexitySimple)):
+		return ComplexitySimple
+	default:
+		return ComplexityAny
+	}
+}
+
+// decide 记录路由决策
+func (r *Router) decide(ctx context.Context, d RouteDecision) RouteDecision {
+	runtime.Info(ctx, "Router selected", runtime.Attr("route", d.Route), runtime.Attr("reason", d.Reason))
+	if r.options.OnDecision != nil {
+		r.options.OnDecision(d)
+	}
+	return d
+}
+
+// find 按名称查找路由
+func (r *Router) find(name string) (Route, bool) {
+	if name == "" {
+		return Route{}, false
+	}
+	for i := range r.routes {
+		if r.routes[i].Name == name {
+			return r.routes[i], true
+		}
+	}
+	return Route{}, false
+}
+
+// WithCallOptions 返回对全部路由目标应用单次调用参数后的路由器副本
+func (r *Router) WithCallOptions(co agent.CallOptions) agent.LLM {
+	routes := make([]Route, len(r.routes))
+	for i, route := range r.routes {
+		route.LLM = agent.ApplyCallOptions(route.LLM, co)
+		routes[i] = route
+	}
+	return &Router{routes: routes, options: r.options}
+}
+
+// Resolve 按上下文中的路由约束为请求选择目标 LLM，
+// CompleteLLM 会通过 agent.Resolve 解析，即使路由器被 Intercept 等包装也能读取上下文
+func (r *Router) Resolve(ctx context.Context, messages *message.Messages, options ...func(ztype.Map) ztype.Map) (agent.LLM, error) {
+	route, _, err := r.Route(ctx, messages, options...)
+	if err != nil {
+		return nil, err
+	}
+	return agent.Resolve(ctx, route.LLM, messages, options...)
+}
+
+// PrepareRequest 选择路由并由目标 LLM 构建请求，请求体中记录路由名称；
+// 没有上下文，不会读取 WithRouteHints 设置的约束，需要时先调用 Resolve
+func (r *Router) PrepareRequest(messages *message.Messages, options ...func(ztype.Map) ztype.Map) ([]byte, error) {
+	return r.prepare(context.Background(), messages, options...)
+}
+
+// prepare 选择路由并构建带路由名称的请求体
+func (r *Router) prepare(ctx context.Context, messages *message.Messages, options ...func(ztype.Map) ztype.Map) ([]byte, error) {
+	route, _, err := r.Route(ctx, messages, options...)
+	if err != nil {
+		return nil, err
+	}
+	body, err := route.LLM.PrepareRequest(messages, options...)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(map[string]any{routeField: route.Name, "body": json.RawMessage(body)})
+}
+
+// target 解析请求体对应的路由与原始请求体，非 JSON 内容视为用户输入
+func (r *Router) target(ctx context.Context, data []byte) (Route, []byte, error) {
+	if !zjson.ValidBytes(data) {
+		msg := message.NewMessages()
+		_ = msg.AppendUser(zstring.Bytes2String(data))
+		body, err := r.prepare(ctx, msg)
+		if err != nil {
+			return Route{}, nil, err
+		}
+		data = body
+	}
+
+	name := zjson.GetBytes(data, routeField)
+	if !name.Exists() {
+		return r.routes[0], data, nil
+	}
+	route, ok := r.find(name.String())
+	if !ok {
+		return Route{}, nil, fmt.Errorf("unknown route: %s", name.String())
+	}
+	return route, []byte(zjson.GetBytes(data, "body").Raw()), nil
+}
+
+func (r *Router) Generate(ctx context.Context, data []byte) (*zjson.Res, error) {
+	route, body, err := r.target(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	res, err := route.LLM.Generate(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	_ = res.Set(routeField, route.Name)
+	return res, nil
+}
+
+func (r *Router) Stream(ctx context.Context, data []byte, callback func(string, []byte)) (<-chan *zjson.Res, error) {
+	route, body, err := r.target(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	done, err := route.LLM.Stream(ctx, body, callback)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan *zjson.Res, 1)
+	go func() {
+		defer close(out)
+		for res := range done {
+			if res != nil {
+				_ = res.Set(routeField, route.Name)
+			}
+			out <- res
+		}
+	}()
+	return out, nil
+}
+
+// ParseResponse 由生成响应的路由解析响应
+func (r *Router) ParseResponse(body *zjson.Res) (*agent.Response, error) {
+	if len(r.routes) == 0 {
+		return nil, runtime_errors.NewLLMError(runtime_errors.ErrInvalidRequest, "router has no routes")
+	}
+	route := r.routes[0]
+	if name := body.Get(routeField); name.Exists() {
+		found, ok := r.find(name.String())
+		if !ok {
+			return nil, fmt.Errorf("unknown route: %s", name.String())
+		}
+		route = found
+		_ = body.Delete(routeField)
+	}
+	return route.LLM.ParseResponse(body)
+}
@@ -0,0 +1,157 @@
+package zllm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sohaha/zlsgo"
+	"github.com/sohaha/zlsgo/zjson"
+	"github.com/zlsgo/zllm/agent"
+	"github.com/zlsgo/zllm/agent/agenttest"
+	"github.com/zlsgo/zllm/message"
+	runtime_errors "github.com/zlsgo/zllm/runtime/errors"
+)
+
+func TestRouter(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	cheap := agenttest.NewMock(agenttest.Text(`{"Assistant":"cheap"}`)).Repeat()
+	smart := agenttest.NewMock(agenttest.Text(`{"Assistant":"smart"}`)).Repeat()
+	classifier := agenttest.NewMock(agenttest.Text("complex"), agenttest.Text("simple")).Repeat()
+
+	var decisions []RouteDecision
+	router := NewRouter([]Route{
+		{
+			Name:         "cheap",
+			LLM:          cheap,
+			Capabilities: &agent.Capabilities{ContextWindow: 32000},
+			Complexity:   ComplexitySimple,
+			Latency:      LatencyLow,
+			InputCost:    0.1,
+		},
+		{
+			Name:         "smart",
+			LLM:          smart,
+			Capabilities: &agent.Capabilities{Tools: true, Vision: true, ContextWindow: 128000},
+			Latency:      LatencyHigh,
+			InputCost:    5,
+		},
+	}, func(o *RouterOptions) {
+		o.Classifier = classifier
+		o.OnDecision = func(d RouteDecision) { decisions = append(decisions, d) }
+	})
+
+	_, err := CompleteLLM(context.Background(), router, message.NewPrompt("写一个编译器"))
+	tt.NoError(err, true)
+	tt.Equal("smart", decisions[0].Route)
+	tt.Equal(ComplexityComplex, decisions[0].Complexity)
+	tt.Equal(1, smart.Calls())
+
+	_, err = CompleteLLM(context.Background(), router, message.NewPrompt("你好"))
+	tt.NoError(err, true)
+	tt.Equal("cheap", decisions[1].Route)
+	tt.Equal(1, cheap.Calls())
+
+	tools := agent.WithToolCallHint([]map[string]any{{"type": "function", "function": map[string]any{"name": "weather"}}})
+	ctx := WithRouteHints(context.Background(), RouteHints{Complexity: ComplexitySimple})
+	_, err = CompleteLLM(ctx, router, message.NewPrompt("天气"), tools)
+	tt.NoError(err, true)
+	tt.Equal("smart", decisions[2].Route)
+	tt.EqualTrue(len(decisions[2].Reason) > 0)
+	tt.Equal(2, classifier.Calls())
+
+	ctx = WithRouteHints(context.Background(), RouteHints{
+		Complexity:   ComplexitySimple,
+		Capabilities: agent.Capabilities{Vision: true},
+		MaxLatency:   LatencyMedium,
+	})
+	_, err = CompleteLLM(ctx, router, message.NewPrompt("看图"))
+	llmErr, ok := err.(runtime_errors.LLMError)
+	tt.EqualTrue(ok)
+	tt.Equal(runtime_errors.ErrInvalidRequest, llmErr.Code)
+
+	msg := message.NewMessages()
+	_ = msg.AppendUser("hi")
+	_, decision, err := router.Route(WithRouteHints(context.Background(), RouteHints{MaxCost: 0.000001}), msg)
+	tt.NoError(err, true)
+	tt.Equal("cheap", decision.Route)
+
+	ctx = WithRouteHints(context.Background(), RouteHints{Complexity: ComplexitySimple, Capabilities: agent.Capabilities{Vision: true}})
+	_, err = CompleteLLM(ctx, agent.Intercept(router), message.NewPrompt("看图"))
+	tt.NoError(err, true)
+	tt.Equal("smart", decisions[len(decisions)-1].Route)
+	tt.Equal(3, smart.Calls())
+}
+
+func TestRouterLLM(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	a := agenttest.NewMock(agenttest.Text("from a")).Repeat()
+	b := agenttest.NewMock(agenttest.Text("from b")).Repeat()
+
+	router := NewRouter([]Route{
+		{Name: "a", LLM: a, InputCost: 2},
+		{Name: "b", LLM: b, InputCost: 1},
+	}, func(o *RouterOptions) {
+		o.PreferCheapest = true
+		o.Rules = []RouteRule{func(_ context.Context, req RouteRequest) (string, string, bool) {
+			return "a", "long input", req.Tokens > 100
+		}}
+	})
+
+	res, err := router.Generate(context.Background(), []byte("hi"))
+	tt.NoError(err, true)
+	resp, err := router.ParseResponse(res)
+	tt.NoError(err, true)
+	tt.Equal("from b", string(resp.Content))
+	tt.Equal(0, a.Calls())
+
+	msg := message.NewMessages()
+	long := make([]byte, 1000)
+	for i := range long {
+		long[i] = 'x'
+	}
+	_ = msg.AppendUser(string(long) + " " + string(long))
+	body, err := router.PrepareRequest(msg)
+	tt.NoError(err, true)
+
+	var chunks []string
+	done, err := router.Stream(context.Background(), body, func(s string, _ []byte) { chunks = append(chunks, s) })
+	tt.NoError(err, true)
+	resp, err = router.ParseResponse(<-done)
+	tt.NoError(err, true)
+	tt.Equal("from a", string(resp.Content))
+	tt.EqualTrue(len(chunks) > 0)
+	tt.Equal(1, a.Calls())
+
+	_, _, err = NewRouter(nil).Route(context.Background(), msg)
+	tt.EqualTrue(err != nil)
+}
+
+func TestRouterCallOptions(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	srv := agenttest.NewServer(agenttest.OpenAI, agenttest.Text("ok")).Repeat()
+	defer srv.Close()
+	newLLM := func(model string) agent.LLM {
+		return agent.NewOpenAI(func(o *agent.OpenAIOptions) {
+			o.BaseURL = srv.URL
+			o.APIURL = "/chat/completions"
+			o.APIKey = "sk-test"
+			o.Model = model
+		})
+	}
+
+	router := NewRouter([]Route{{Name: "a", LLM: newLLM("gpt-a")}, {Name: "b", LLM: newLLM("gpt-b")}})
+	llm := agent.ApplyCallOptions(agent.Intercept(router), agent.CallOptions{Model: "gpt-override", Temperature: agent.Ptr(0.1)})
+	_, err := llm.Generate(context.Background(), []byte("hi"))
+	tt.NoError(err, true)
+
+	body := zjson.ParseBytes(srv.Requests()[0].Body)
+	tt.Equal("gpt-override", body.Get("model").String())
+	tt.Equal(0.1, body.Get("temperature").Float())
+
+	_, err = router.Generate(context.Background(), []byte("hi"))
+	tt.NoError(err, true)
+	tt.Equal("gpt-a", zjson.GetBytes(srv.Requests()[1].Body, "model").String())
+}
@@ -0,0 +1,339 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/sohaha/zlsgo/zhttp"
+	"github.com/sohaha/zlsgo/zutil"
+)
+
+// CassetteMode 录制回放模式
+type CassetteMode int
+
+const (
+	// ModeReplay 仅回放，未匹配的请求返回错误
+	ModeReplay CassetteMode = iota
+	// ModeRecord 始终请求真实服务并录制
+	ModeRecord
+	// ModeAuto 优先回放，未匹配时请求真实服务并追加录制
+	ModeAuto
+)
+
+// ErrNoInteraction 回放时没有匹配的录制记录
+var ErrNoInteraction = errors.New("cassette: no matching interaction")
+
+// CassetteRequest 录制的请求
+type CassetteRequest struct {
+	Method  string              `json:"method"`
+	URL     string              `json:"url"`
+	Headers map[string][]string `json:"headers,omitempty"`
+	Body    string              `json:"body,omitempty"`
+}
+
+// CassetteResponse 录制的响应，SSE 流式响应以完整文本保存
+type CassetteResponse struct {
+	Status  int                 `json:"status"`
+	Headers map[string][]string `json:"headers,omitempty"`
+	Body    string              `json:"body"`
+}
+
+// Interaction 一次请求与响应
+type Interaction struct {
+	Request  CassetteRequest  `json:"request"`
+	Response CassetteResponse `json:"response"`
+}
+
+// CassetteOptions 录制回放选项
+type CassetteOptions struct {
+	// Mode 录制回放模式，默认 ModeReplay
+	Mode CassetteMode
+	// Transport 录制时使用的底层传输，默认 http.DefaultTransport
+	Transport http.RoundTripper
+	// Scrub 脱敏函数，默认 SanitizeSensitiveData
+	Scrub func(string) string
+	// Matcher 自定义匹配规则，默认按方法、URL 与归一化后的请求体匹配
+	Matcher func(req CassetteRequest, recorded CassetteRequest) bool
+	// SensitiveHeaders 需要脱敏的请求头
+	SensitiveHeaders []string
+	// SensitiveQuery 需要脱敏的查询参数
+	SensitiveQuery []string
+}
+
+// Cassette 录制回放 HTTP 传输，实现 http.RoundTripper
+type Cassette struct {
+	path         string
+	options      CassetteOptions
+	interactions []Interaction
+	used         []bool
+	mu           sync.Mutex
+}
+
+var (
+	defaultSensitiveHeaders = []string{"Authorization", "X-Api-Key", "Api-Key", "X-Goog-Api-Key", "Cookie", "Set-Cookie"}
+	defaultSensitiveQuery   = []string{"key", "api_key", "apikey", "token", "access_token"}
+)
+
+// NewCassette 创建录制回放传输，回放模式下会加载已有的录制文件
+func NewCassette(path string, opt ...func(*CassetteOptions)) (*Cassette, error) {
+	o := zutil.Optional(CassetteOptions{
+		Mode:             ModeReplay,
+		Scrub:            SanitizeSensitiveData,
+		SensitiveHeaders: defaultSensitiveHeaders,
+		SensitiveQuery:   defaultSensitiveQuery,
+	}, opt...)
+	if o.Transport == nil {
+		o.Transport = http.DefaultTransport
+	}
+	if o.Scrub == nil {
+		o.Scrub = func(s string) string { return s }
+	}
+
+	c := &Cassette{path: path, options: o}
+	if o.Mode == ModeRecord {
+		return c, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) && o.Mode == ModeAuto {
+			return c, nil
+		}
+		return nil, fmt.Errorf("cassette: failed to read %s: %w", path, err)
+	}
+
+	var file struct {
+		Interactions []Interaction `json:"interactions"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("cassette: failed to parse %s: %w", path, err)
+	}
+	c.interactions = file.Interactions
+	c.used = make([]bool, len(c.interactions))
+
+	return c, nil
+}
+
+// Interactions 返回已录制的交互
+func (c *Cassette) Interactions() []Interaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]Interaction(nil), c.interactions...)
+}
+
+// RoundTrip 执行请求
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	recorded := c.scrubRequest(req, body)
+
+	if c.options.Mode != ModeRecord {
+		if it, ok := c.match(recorded); ok {
+			return it.Response.toHTTP(req), nil
+		}
+		if c.options.Mode == ModeReplay {
+			return nil, fmt.Errorf("%w: %s %s", ErrNoInteraction, recorded.Method, recorded.URL)
+		}
+	}
+
+	resp, err := c.options.Transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	it := Interaction{
+		Request: recorded,
+		Response: CassetteResponse{
+			Status:  resp.StatusCode,
+			Headers: c.scrubHeaders(resp.Header),
+			Body:    c.options.Scrub(string(respBody)),
+		},
+	}
+
+	c.mu.Lock()
+	c.interactions = append(c.interactions, it)
+	c.used = append(c.used, true)
+	c.mu.Unlock()
+
+	if err := c.Save(); err != nil {
+		Log("cassette save failed:", err)
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	return resp, nil
+}
+
+// Save 将录制内容写入文件
+func (c *Cassette) Save() error {
+	c.mu.Lock()
+	data, err := json.MarshalIndent(struct {
+		Interactions []Interaction `json:"interactions"`
+	}{c.interactions}, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if dir := filepath.Dir(c.path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(c.path, data, 0o644)
+}
+
+// Client 返回使用该传输的 HTTP 客户端
+func (c *Cassette) Client() *zhttp.Engine {
+	engine := zhttp.New()
+	engine.SetClient(&http.Client{Transport: c})
+	return engine
+}
+
+// UseCassette 将全局 HTTP 客户端替换为录制回放客户端，返回恢复函数
+func UseCassette(path string, opt ...func(*CassetteOptions)) (restore func(), err error) {
+	c, err := NewCassette(path, opt...)
+	if err != nil {
+		return nil, err
+	}
+
+	old := GetClient()
+	SetClient(c.Client())
+	return func() {
+		SetClient(old)
+	}, nil
+}
+
+// match 查找匹配的录制记录，优先使用未回放过的记录
+func (c *Cassette) match(req CassetteRequest) (Interaction, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	last := -1
+	for i := range c.interactions {
+		if !c.matches(req, c.interactions[i].Request) {
+			continue
+		}
+		if !c.used[i] {
+			c.used[i] = true
+			return c.interactions[i], true
+		}
+		last = i
+	}
+
+	if last >= 0 {
+		return c.interactions[last], true
+	}
+	return Interaction{}, false
+}
+
+// matches 判断请求是否与录制记录匹配
+func (c *Cassette) matches(req, recorded CassetteRequest) bool {
+	if c.options.Matcher != nil {
+		return c.options.Matcher(req, recorded)
+	}
+	return req.Method == recorded.Method && req.URL == recorded.URL &&
+		NormalizeBody(req.Body) == NormalizeBody(recorded.Body)
+}
+
+// scrubRequest 生成脱敏后的请求记录
+func (c *Cassette) scrubRequest(req *http.Request, body []byte) CassetteRequest {
+	u := *req.URL
+	if q := u.Query(); len(q) > 0 {
+		for _, k := range c.options.SensitiveQuery {
+			for name := range q {
+				if strings.EqualFold(name, k) {
+					q[name] = []string{Redacted}
+				}
+			}
+		}
+		u.RawQuery = q.Encode()
+	}
+	u.User = nil
+
+	return CassetteRequest{
+		Method:  req.Method,
+		URL:     u.String(),
+		Headers: c.scrubHeaders(req.Header),
+		Body:    c.options.Scrub(string(body)),
+	}
+}
+
+// scrubHeaders 脱敏请求头
+func (c *Cassette) scrubHeaders(h http.Header) map[string][]string {
+	if len(h) == 0 {
+		return nil
+	}
+
+	headers := make(map[string][]string, len(h))
+	for k, v := range h {
+		sensitive := false
+		for _, s := range c.options.SensitiveHeaders {
+			if strings.EqualFold(k, s) {
+				sensitive = true
+				break
+			}
+		}
+		if sensitive {
+			headers[k] = []string{Redacted}
+		} else {
+			headers[k] = append([]string(nil), v...)
+		}
+	}
+	return headers
+}
+
+// toHTTP 转换为 HTTP 响应
+func (r CassetteResponse) toHTTP(req *http.Request) *http.Response {
+	header := http.Header{}
+	for k, v := range r.Headers {
+		header[k] = append([]string(nil), v...)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.Status, http.StatusText(r.Status)),
+		StatusCode:    r.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
+
+// NormalizeBody 归一化请求体，JSON 按键排序并去除空白，便于匹配
+func NormalizeBody(body string) string {
+	var v any
+	if err := json.Unmarshal([]byte(body), &v); err != nil {
+		return strings.TrimSpace(body)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+	return string(b)
+}
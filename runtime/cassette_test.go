@@ -0,0 +1,96 @@
+package runtime_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sohaha/zlsgo"
+	"github.com/sohaha/zlsgo/zhttp"
+	"github.com/zlsgo/zllm/runtime"
+)
+
+func TestCassette(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	hits := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), `"stream":true`) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = fmt.Fprint(w, "data: {\"n\":1}\n\ndata: {\"n\":2}\n\ndata: [DONE]\n\n")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"echo":%q,"token":"abcdefghijklmnop"}`, r.URL.Path)
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "cassettes", "chat.json")
+	headers := zhttp.Header{"Authorization": "Bearer sk-secret-value", "Content-Type": "application/json"}
+
+	restore, err := runtime.UseCassette(path, func(o *runtime.CassetteOptions) {
+		o.Mode = runtime.ModeRecord
+	})
+	tt.NoError(err, true)
+
+	res, err := runtime.GetClient().Post(srv.URL+"/v1/chat?key=my-secret-key", headers, zhttp.BodyJSON(map[string]any{"model": "m", "api_key": "sk-1234567890abc"}))
+	tt.NoError(err, true)
+	tt.Equal(`{"echo":"/v1/chat","token":"abcdefghijklmnop"}`, res.String())
+
+	res, err = runtime.GetClient().Post(srv.URL+"/v1/chat", headers, zhttp.BodyJSON(map[string]any{"model": "m", "stream": true}))
+	tt.NoError(err, true)
+	tt.EqualTrue(strings.HasSuffix(res.String(), "data: [DONE]\n\n"))
+	restore()
+	tt.Equal(2, hits)
+
+	data, err := os.ReadFile(path)
+	tt.NoError(err, true)
+	s := string(data)
+	tt.EqualTrue(!strings.Contains(s, "sk-secret-value"))
+	tt.EqualTrue(!strings.Contains(s, "my-secret-key"))
+	tt.EqualTrue(!strings.Contains(s, "sk-1234567890abc"))
+	tt.EqualTrue(!strings.Contains(s, "abcdefghijklmnop"))
+	tt.EqualTrue(strings.Contains(s, runtime.Redacted))
+
+	tt.Run("Replay", func(tt *zlsgo.TestUtil) {
+		c, err := runtime.NewCassette(path)
+		tt.NoError(err, true)
+		client := c.Client()
+
+		res, err := client.Post(srv.URL+"/v1/chat", headers, zhttp.BodyJSON(map[string]any{"stream": true, "model": "m"}))
+		tt.NoError(err, true)
+		tt.Equal("text/event-stream", res.Response().Header.Get("Content-Type"))
+		tt.EqualTrue(strings.Contains(res.String(), `data: {"n":2}`))
+
+		res, err = client.Post(srv.URL+"/v1/chat?key=other-key", headers, zhttp.BodyJSON(map[string]any{"api_key": "sk-0987654321xyz", "model": "m"}))
+		tt.NoError(err, true)
+		tt.Equal(200, res.StatusCode())
+		tt.EqualTrue(strings.Contains(res.String(), `"echo":"/v1/chat"`))
+
+		_, err = client.Post(srv.URL+"/v1/other", headers, zhttp.BodyJSON(map[string]any{"model": "m"}))
+		tt.EqualTrue(errors.Is(err, runtime.ErrNoInteraction))
+		tt.Equal(2, hits)
+	})
+
+	tt.Run("Auto", func(tt *zlsgo.TestUtil) {
+		c, err := runtime.NewCassette(path, func(o *runtime.CassetteOptions) {
+			o.Mode = runtime.ModeAuto
+		})
+		tt.NoError(err, true)
+
+		_, err = c.Client().Post(srv.URL+"/v1/other", headers, zhttp.BodyJSON(map[string]any{"model": "m"}))
+		tt.NoError(err, true)
+		tt.Equal(3, hits)
+		tt.Equal(3, len(c.Interactions()))
+	})
+
+	tt.Equal(`{"a":1,"b":[2]}`, runtime.NormalizeBody("{ \"b\": [2], \"a\": 1 }"))
+}
@@ -7,11 +7,14 @@ import (
 
 var isDebug = zutil.NewBool(false)
 
-// SetDebug 设置调试模式
+// SetDebug 设置调试模式，未设置日志时使用 zlog 输出，调试模式下输出 Debug 级别日志，否则仅输出警告与错误
 func SetDebug(debug bool) {
 	isDebug.Store(debug)
 	if log == nil {
 		log = zlog.New("[LLMX] ")
+		if l, _ := getLogger(); l == nil {
+			SetLogger(NewZlogLogger(log))
+		}
 	}
 	if debug {
 		log.SetLogLevel(zlog.LogDebug)
@@ -25,7 +28,12 @@ func IsDebug() bool {
 	return isDebug.Load()
 }
 
-// SetLog 设置自定义日志记录器
+// SetLog 设置自定义 zlog 日志记录器，传入 nil 关闭日志，需要结构化日志时使用 SetLogger
 func SetLog(l *zlog.Logger) {
 	log = l
+	if l == nil {
+		SetLogger(nil)
+		return
+	}
+	SetLogger(NewZlogLogger(l))
 }
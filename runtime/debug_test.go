@@ -1,6 +1,7 @@
 package runtime
 
 import (
+	"context"
 	"testing"
 
 	"github.com/sohaha/zlsgo/zlog"
@@ -40,3 +41,20 @@ func TestSetLog(t *testing.T) {
 		t.Error("Expected debug to work with custom logger")
 	}
 }
+
+func TestSetLogNil(t *testing.T) {
+	SetLog(nil)
+	if LogEnabled(context.Background(), LevelError) {
+		t.Error("Expected logging to be disabled after SetLog(nil)")
+	}
+	Error(context.Background(), "discarded")
+
+	if (&zlogLogger{}).Enabled(context.Background(), LevelError) {
+		t.Error("Expected zlogLogger without zlog to be disabled")
+	}
+
+	SetDebug(false)
+	if !LogEnabled(context.Background(), LevelError) {
+		t.Error("Expected SetDebug to restore the default logger")
+	}
+}
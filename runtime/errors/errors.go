@@ -23,8 +23,35 @@ const (
 	ErrInvalidRequest
 	ErrTokenLimit
 	ErrOutputFormatNotFound
+	ErrContentFiltered // 内容被安全策略拦截
 )
 
+var errorCodeNames = [...]string{
+	ErrUnknown:              "unknown",
+	ErrUnauthorized:         "unauthorized",
+	ErrRateLimited:          "rate_limited",
+	ErrBadRequest:           "bad_request",
+	ErrServer:               "server",
+	ErrTimeout:              "timeout",
+	ErrContextCanceled:      "context_canceled",
+	ErrInvalidResponse:      "invalid_response",
+	ErrProviderUnavailable:  "provider_unavailable",
+	ErrQuotaExceeded:        "quota_exceeded",
+	ErrModelNotFound:        "model_not_found",
+	ErrInvalidRequest:       "invalid_request",
+	ErrTokenLimit:           "token_limit",
+	ErrOutputFormatNotFound: "output_format_not_found",
+	ErrContentFiltered:      "content_filtered",
+}
+
+// String 返回错误码名称，用于日志与指标标签
+func (c ErrorCode) String() string {
+	if c >= 0 && int(c) < len(errorCodeNames) {
+		return errorCodeNames[c]
+	}
+	return errorCodeNames[ErrUnknown]
+}
+
 // LLMError LLM错误结构
 type LLMError struct {
 	Code    ErrorCode
@@ -71,7 +98,7 @@ func (e LLMError) GetSeverity() string {
 	switch e.Code {
 	case ErrUnauthorized, ErrInvalidRequest, ErrBadRequest:
 		return "high"
-	case ErrRateLimited, ErrQuotaExceeded:
+	case ErrRateLimited, ErrQuotaExceeded, ErrContentFiltered:
 		return "medium"
 	case ErrServer, ErrTimeout, ErrProviderUnavailable:
 		return "low"
@@ -35,6 +35,25 @@ func TestErrorCode_Values(t *testing.T) {
 	}
 }
 
+func TestErrorCode_String(t *testing.T) {
+	tests := []struct {
+		want string
+		code ErrorCode
+	}{
+		{"unknown", ErrUnknown},
+		{"rate_limited", ErrRateLimited},
+		{"content_filtered", ErrContentFiltered},
+		{"unknown", ErrorCode(-1)},
+		{"unknown", ErrorCode(100)},
+	}
+
+	for _, tt := range tests {
+		if got := tt.code.String(); got != tt.want {
+			t.Errorf("ErrorCode(%d).String() = %s, want %s", int(tt.code), got, tt.want)
+		}
+	}
+}
+
 func TestLLMError_Error(t *testing.T) {
 	err := LLMError{
 		Code:    ErrUnauthorized,
@@ -1,15 +1,257 @@
 package runtime
 
 import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
 	"github.com/sohaha/zlsgo/zlog"
+	"github.com/sohaha/zlsgo/zutil"
+)
+
+// Level 日志级别，取值与 log/slog 一致
+type Level int
+
+const (
+	LevelDebug Level = -4
+	LevelInfo  Level = 0
+	LevelWarn  Level = 4
+	LevelError Level = 8
+)
+
+// String 返回日志级别名称
+func (l Level) String() string {
+	switch {
+	case l >= LevelError:
+		return "ERROR"
+	case l >= LevelWarn:
+		return "WARN"
+	case l >= LevelInfo:
+		return "INFO"
+	default:
+		return "DEBUG"
+	}
+}
+
+// 日志字段名称
+const (
+	KeyProvider  = "provider"
+	KeyModel     = "model"
+	KeyRequestID = "request_id"
+	KeyLatency   = "latency"
+	KeyStatus    = "status"
+	KeyError     = "error"
+	KeyErrorCode = "error_code"
+	KeyPrompt    = "prompt"
+	KeyResponse  = "response"
+	KeyHeaders   = "headers"
 )
 
-var log *zlog.Logger
+// Logger 结构化日志接口，可通过 NewSlogLogger 对接 log/slog
+type Logger interface {
+	// Enabled 指定级别的日志是否会输出，用于跳过代价较高的字段计算
+	Enabled(ctx context.Context, level Level) bool
+	Log(ctx context.Context, level Level, msg string, attrs ...Attribute)
+}
+
+// LogOptions 日志脱敏配置
+type LogOptions struct {
+	// Redact 提示词与响应内容的脱敏函数，默认 SanitizeSensitiveData
+	Redact func(string) string
+	// SensitiveHeaders 值需要脱敏的请求头，不区分大小写
+	SensitiveHeaders []string
+	// ContentLimit 提示词与响应内容的最大记录长度，超出部分截断，0 表示不限制
+	ContentLimit int
+	// OmitContent 不记录提示词与响应内容，仅记录长度
+	OmitContent bool
+}
+
+var (
+	logger     Logger
+	logOptions = defaultLogOptions()
+	loggerMu   sync.RWMutex
+
+	log *zlog.Logger
+)
+
+func defaultLogOptions() LogOptions {
+	return LogOptions{
+		Redact:           SanitizeSensitiveData,
+		SensitiveHeaders: defaultSensitiveHeaders,
+		ContentLimit:     2048,
+	}
+}
+
+// SetLogger 设置结构化日志，传入 nil 关闭日志
+func SetLogger(l Logger) {
+	loggerMu.Lock()
+	logger = l
+	loggerMu.Unlock()
+}
+
+// SetLogOptions 设置日志脱敏配置，未修改的项使用默认值
+func SetLogOptions(opt ...func(*LogOptions)) {
+	o := zutil.Optional(defaultLogOptions(), opt...)
+	if o.Redact == nil {
+		o.Redact = func(s string) string { return s }
+	}
+
+	loggerMu.Lock()
+	logOptions = o
+	loggerMu.Unlock()
+}
+
+// getLogger 获取结构化日志与脱敏配置
+func getLogger() (Logger, LogOptions) {
+	loggerMu.RLock()
+	defer loggerMu.RUnlock()
+
+	return logger, logOptions
+}
+
+// LogEnabled 指定级别的日志是否会输出
+func LogEnabled(ctx context.Context, level Level) bool {
+	l, _ := getLogger()
+	return l != nil && l.Enabled(ctx, level)
+}
+
+// Debug 记录调试日志
+func Debug(ctx context.Context, msg string, attrs ...Attribute) {
+	logAttrs(ctx, LevelDebug, msg, attrs)
+}
+
+// Info 记录信息日志
+func Info(ctx context.Context, msg string, attrs ...Attribute) {
+	logAttrs(ctx, LevelInfo, msg, attrs)
+}
+
+// Warn 记录警告日志
+func Warn(ctx context.Context, msg string, attrs ...Attribute) {
+	logAttrs(ctx, LevelWarn, msg, attrs)
+}
+
+// Error 记录错误日志
+func Error(ctx context.Context, msg string, attrs ...Attribute) {
+	logAttrs(ctx, LevelError, msg, attrs)
+}
+
+func logAttrs(ctx context.Context, level Level, msg string, attrs []Attribute) {
+	l, _ := getLogger()
+	if l == nil || !l.Enabled(ctx, level) {
+		return
+	}
+	l.Log(ctx, level, msg, attrs...)
+}
 
 // Log 记录调试消息
+//
+// Deprecated: 使用 Debug 等结构化日志函数
 func Log(v ...any) {
-	if log == nil {
+	if !LogEnabled(context.Background(), LevelDebug) {
 		return
 	}
-	log.Debug(v...)
+	Debug(context.Background(), strings.TrimSuffix(fmt.Sprintln(v...), "\n"))
+}
+
+// ErrAttr 错误字段
+func ErrAttr(err error) Attribute {
+	if err == nil {
+		return Attr(KeyError, nil)
+	}
+	return Attr(KeyError, err.Error())
+}
+
+// ContentAttr 提示词或响应内容字段，按 LogOptions 脱敏、截断或只记录长度
+func ContentAttr(key, content string) Attribute {
+	_, o := getLogger()
+	if o.OmitContent {
+		return Attr(key, "["+strconv.Itoa(len(content))+" bytes]")
+	}
+
+	content = o.Redact(content)
+	if o.ContentLimit > 0 && len(content) > o.ContentLimit {
+		// 回退到字符边界，避免截断多字节字符
+		end := o.ContentLimit
+		for end > 0 && !utf8.RuneStart(content[end]) {
+			end--
+		}
+		content = content[:end] + "...[" + strconv.Itoa(len(content)) + " bytes]"
+	}
+	return Attr(key, content)
+}
+
+// HeadersAttr 请求头字段，敏感请求头的值会被替换为 Redacted
+func HeadersAttr(headers map[string]string) Attribute {
+	_, o := getLogger()
+	redacted := make(map[string]string, len(headers))
+	for k, v := range headers {
+		redacted[k] = v
+		for _, s := range o.SensitiveHeaders {
+			if strings.EqualFold(k, s) {
+				redacted[k] = Redacted
+				break
+			}
+		}
+	}
+	return Attr(KeyHeaders, redacted)
+}
+
+// NewZlogLogger 使用 zlog 输出日志，字段以 key=value 追加在消息之后
+func NewZlogLogger(l *zlog.Logger) Logger {
+	return &zlogLogger{log: l}
+}
+
+// zlogLogger 基于 zlog 的日志实现
+type zlogLogger struct {
+	log *zlog.Logger
+}
+
+func (z *zlogLogger) Enabled(_ context.Context, level Level) bool {
+	if z.log == nil {
+		return false
+	}
+	return z.log.GetLogLevel() >= zlogLevel(level)
+}
+
+func (z *zlogLogger) Log(_ context.Context, level Level, msg string, attrs ...Attribute) {
+	var b strings.Builder
+	b.WriteString(msg)
+	for _, a := range attrs {
+		b.WriteByte(' ')
+		b.WriteString(a.Key)
+		b.WriteByte('=')
+		s := fmt.Sprint(a.Value)
+		if strings.ContainsAny(s, " \t\n\"=") {
+			s = strconv.Quote(s)
+		}
+		b.WriteString(s)
+	}
+
+	switch zlogLevel(level) {
+	case zlog.LogError:
+		z.log.Error(b.String())
+	case zlog.LogWarn:
+		z.log.Warn(b.String())
+	case zlog.LogInfo:
+		z.log.Info(b.String())
+	default:
+		z.log.Debug(b.String())
+	}
+}
+
+// zlogLevel 转换为 zlog 日志级别
+func zlogLevel(level Level) int {
+	switch {
+	case level >= LevelError:
+		return zlog.LogError
+	case level >= LevelWarn:
+		return zlog.LogWarn
+	case level >= LevelInfo:
+		return zlog.LogInfo
+	default:
+		return zlog.LogDebug
+	}
 }
@@ -0,0 +1,107 @@
+package runtime_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/sohaha/zlsgo"
+	"github.com/sohaha/zlsgo/zlog"
+	"github.com/zlsgo/zllm/runtime"
+)
+
+type record struct {
+	attrs map[string]any
+	msg   string
+	level runtime.Level
+}
+
+type captureLogger struct {
+	records []record
+	level   runtime.Level
+	mu      sync.Mutex
+}
+
+func (c *captureLogger) Enabled(_ context.Context, level runtime.Level) bool {
+	return level >= c.level
+}
+
+func (c *captureLogger) Log(_ context.Context, level runtime.Level, msg string, attrs ...runtime.Attribute) {
+	r := record{level: level, msg: msg, attrs: map[string]any{}}
+	for _, a := range attrs {
+		r.attrs[a.Key] = a.Value
+	}
+	c.mu.Lock()
+	c.records = append(c.records, r)
+	c.mu.Unlock()
+}
+
+func TestLogger(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	l := &captureLogger{level: runtime.LevelInfo}
+	runtime.SetLogger(l)
+	defer runtime.SetLogger(nil)
+	defer runtime.SetLogOptions()
+
+	ctx := context.Background()
+	runtime.Debug(ctx, "skipped")
+	runtime.Info(ctx, "info", runtime.Attr(runtime.KeyProvider, "openai"))
+	runtime.Error(ctx, "failed", runtime.ErrAttr(errors.New("boom")))
+	tt.EqualTrue(!runtime.LogEnabled(ctx, runtime.LevelDebug))
+	tt.Equal(2, len(l.records))
+	tt.Equal("openai", l.records[0].attrs[runtime.KeyProvider])
+	tt.Equal(runtime.LevelError, l.records[1].level)
+	tt.Equal("boom", l.records[1].attrs[runtime.KeyError])
+	tt.Equal("WARN", runtime.LevelWarn.String())
+
+	l.level = runtime.LevelDebug
+	runtime.Log("legacy", 1)
+	tt.Equal("legacy 1", l.records[2].msg)
+
+	tt.Run("Redaction", func(tt *zlsgo.TestUtil) {
+		prompt := `{"api_key":"sk-1234567890abcdef","content":"hello"}`
+		v := runtime.ContentAttr(runtime.KeyPrompt, prompt).Value.(string)
+		tt.EqualTrue(strings.Contains(v, runtime.Redacted))
+		tt.EqualTrue(!strings.Contains(v, "sk-1234567890abcdef"))
+
+		headers := runtime.HeadersAttr(map[string]string{"authorization": "Bearer sk-test", "Content-Type": "application/json"})
+		tt.Equal(map[string]string{"authorization": runtime.Redacted, "Content-Type": "application/json"}, headers.Value)
+
+		runtime.SetLogOptions(func(o *runtime.LogOptions) {
+			o.ContentLimit = 5
+			o.SensitiveHeaders = []string{"X-Secret"}
+		})
+		tt.Equal("hello...[11 bytes]", runtime.ContentAttr(runtime.KeyResponse, "hello world").Value)
+		cjk := runtime.ContentAttr(runtime.KeyResponse, "你好世界").Value.(string)
+		tt.EqualTrue(utf8.ValidString(cjk))
+		tt.Equal("你...[12 bytes]", cjk)
+		tt.Equal(map[string]string{"X-Secret": runtime.Redacted}, runtime.HeadersAttr(map[string]string{"X-Secret": "1"}).Value)
+
+		runtime.SetLogOptions(func(o *runtime.LogOptions) { o.OmitContent = true })
+		tt.Equal("[11 bytes]", runtime.ContentAttr(runtime.KeyResponse, "hello world").Value)
+
+		runtime.SetLogOptions(func(o *runtime.LogOptions) { o.Redact = nil })
+		tt.Equal(prompt, runtime.ContentAttr(runtime.KeyPrompt, prompt).Value)
+	})
+
+	tt.Run("Zlog", func(tt *zlsgo.TestUtil) {
+		var out []string
+		zl := zlog.New("")
+		zl.WriteBefore(func(_ int, log string) bool {
+			out = append(out, log)
+			return false
+		})
+		zl.SetLogLevel(zlog.LogWarn)
+		logger := runtime.NewZlogLogger(zl)
+		runtime.SetLogger(logger)
+
+		tt.EqualTrue(!logger.Enabled(ctx, runtime.LevelInfo))
+		runtime.Warn(ctx, "slow", runtime.Attr(runtime.KeyModel, "gpt-4o"), runtime.Attr(runtime.KeyError, "time out"))
+		tt.Equal(1, len(out))
+		tt.EqualTrue(strings.Contains(out[0], `slow model=gpt-4o error="time out"`))
+	})
+}
@@ -0,0 +1,158 @@
+package runtime
+
+import (
+	"strings"
+	"sync"
+)
+
+// 指标名称
+const (
+	MetricRequests         = "zllm_requests_total"              // 请求次数
+	MetricRequestDuration  = "zllm_request_duration_seconds"    // 请求耗时
+	MetricTimeToFirstToken = "zllm_time_to_first_token_seconds" // 流式请求首个分片耗时
+	MetricTokens           = "zllm_tokens_total"                // token 用量
+	MetricErrors           = "zllm_errors_total"                // 错误次数
+	MetricToolCalls        = "zllm_tool_calls_total"            // 工具调用次数
+	MetricCacheHits        = "zllm_cache_hits_total"            // 提示词缓存命中次数
+)
+
+// 指标标签名称
+const (
+	LabelProvider = "provider"
+	LabelModel    = "model"
+	LabelStream   = "stream"
+	LabelStatus   = "status" // success 或 error
+	LabelType     = "type"   // input、output 或 cached
+	LabelCode     = "code"   // runtime/errors.ErrorCode 名称
+	LabelTool     = "tool"
+)
+
+// MetricKind 指标类型
+type MetricKind int
+
+const (
+	MetricCounter   MetricKind = iota // 计数器
+	MetricHistogram                   // 直方图
+)
+
+// MetricDesc 指标描述
+type MetricDesc struct {
+	Name   string
+	Help   string
+	Labels []string
+	Kind   MetricKind
+}
+
+var metricDescs = []MetricDesc{
+	{Name: MetricRequests, Help: "LLM 请求次数", Kind: MetricCounter, Labels: []string{LabelProvider, LabelModel, LabelStream, LabelStatus}},
+	{Name: MetricRequestDuration, Help: "LLM 请求耗时（秒）", Kind: MetricHistogram, Labels: []string{LabelProvider, LabelModel, LabelStream}},
+	{Name: MetricTimeToFirstToken, Help: "流式请求首个分片耗时（秒）", Kind: MetricHistogram, Labels: []string{LabelProvider, LabelModel}},
+	{Name: MetricTokens, Help: "token 用量", Kind: MetricCounter, Labels: []string{LabelProvider, LabelModel, LabelType}},
+	{Name: MetricErrors, Help: "LLM 错误次数", Kind: MetricCounter, Labels: []string{LabelProvider, LabelModel, LabelCode}},
+	{Name: MetricToolCalls, Help: "模型发起的工具调用次数", Kind: MetricCounter, Labels: []string{LabelProvider, LabelModel, LabelTool}},
+	{Name: MetricCacheHits, Help: "提示词缓存命中次数", Kind: MetricCounter, Labels: []string{LabelProvider, LabelModel}},
+}
+
+// MetricDescs 返回内置指标描述，用于在适配器中预先注册
+func MetricDescs() []MetricDesc {
+	return append([]MetricDesc(nil), metricDescs...)
+}
+
+// Metrics 指标记录接口，labelValues 与 MetricDesc.Labels 顺序一致
+type Metrics interface {
+	// Add 计数器累加
+	Add(name string, value float64, labelValues ...string)
+	// Observe 直方图记录观测值
+	Observe(name string, value float64, labelValues ...string)
+}
+
+var (
+	metrics   Metrics
+	metricsMu sync.RWMutex
+)
+
+// SetMetrics 设置全局指标记录器，传入 nil 关闭指标
+func SetMetrics(m Metrics) {
+	metricsMu.Lock()
+	metrics = m
+	metricsMu.Unlock()
+}
+
+// getMetrics 获取全局指标记录器
+func getMetrics() Metrics {
+	metricsMu.RLock()
+	defer metricsMu.RUnlock()
+
+	return metrics
+}
+
+// Metering 是否已启用指标
+func Metering() bool {
+	return getMetrics() != nil
+}
+
+// AddMetric 计数器累加，未启用指标时忽略
+func AddMetric(name string, value float64, labelValues ...string) {
+	if m := getMetrics(); m != nil {
+		m.Add(name, value, labelValues...)
+	}
+}
+
+// ObserveMetric 直方图记录观测值，未启用指标时忽略
+func ObserveMetric(name string, value float64, labelValues ...string) {
+	if m := getMetrics(); m != nil {
+		m.Observe(name, value, labelValues...)
+	}
+}
+
+// MemoryMetrics 内存指标记录器，适用于测试与调试
+type MemoryMetrics struct {
+	values map[string]float64
+	counts map[string]int
+	mu     sync.Mutex
+}
+
+var _ Metrics = &MemoryMetrics{}
+
+// NewMemoryMetrics 创建内存指标记录器
+func NewMemoryMetrics() *MemoryMetrics {
+	return &MemoryMetrics{
+		values: map[string]float64{},
+		counts: map[string]int{},
+	}
+}
+
+// Add 计数器累加
+func (m *MemoryMetrics) Add(name string, value float64, labelValues ...string) {
+	key := metricKey(name, labelValues)
+	m.mu.Lock()
+	m.values[key] += value
+	m.counts[key]++
+	m.mu.Unlock()
+}
+
+// Observe 直方图记录观测值
+func (m *MemoryMetrics) Observe(name string, value float64, labelValues ...string) {
+	m.Add(name, value, labelValues...)
+}
+
+// Value 返回计数器的值或直方图观测值之和
+func (m *MemoryMetrics) Value(name string, labelValues ...string) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.values[metricKey(name, labelValues)]
+}
+
+// Count 返回记录次数
+func (m *MemoryMetrics) Count(name string, labelValues ...string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.counts[metricKey(name, labelValues)]
+}
+
+// metricKey 生成指标与标签值组合的键
+func metricKey(name string, labelValues []string) string {
+	return name + "{" + strings.Join(labelValues, ",") + "}"
+}
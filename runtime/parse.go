@@ -3,19 +3,31 @@ package runtime
 import "bytes"
 
 var (
-	thinkStart = []byte("</think>")
+	thinkStart = []byte("<think>")
 	thinkEnd   = []byte("</think>")
 	codeWrap   = []byte("```")
 )
 
+// SplitThinking 拆分响应开头的 <think> 推理块，返回推理内容与剩余内容
+func SplitThinking(resp []byte) (thinking, content []byte) {
+	trimmed := bytes.TrimLeft(resp, " \t\r\n")
+	if !bytes.HasPrefix(trimmed, thinkStart) {
+		return nil, resp
+	}
+
+	thinkEndIndex := bytes.Index(trimmed, thinkEnd)
+	if thinkEndIndex < 0 {
+		return nil, resp
+	}
+
+	thinking = bytes.TrimSpace(trimmed[len(thinkStart):thinkEndIndex])
+	content = bytes.TrimLeft(trimmed[thinkEndIndex+len(thinkEnd):], " \t\r\n")
+	return thinking, content
+}
+
 // ParseContent 解析响应内容
 func ParseContent(resp []byte) []byte {
-	if bytes.HasPrefix(resp, thinkStart) {
-		thinkEndIndex := bytes.Index(resp, thinkEnd)
-		if thinkEndIndex > 0 {
-			resp = resp[thinkEndIndex+len(thinkEnd):]
-		}
-	}
+	_, resp = SplitThinking(resp)
 
 	if bytes.HasPrefix(resp, codeWrap) {
 		firstNewline := bytes.Index(resp, []byte("\n"))
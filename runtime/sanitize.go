@@ -0,0 +1,31 @@
+package runtime
+
+import "regexp"
+
+// 用于检测日志中敏感数据的正则表达式模式
+var (
+	apiKeyPattern   = regexp.MustCompile(`(?i)(api[_-]?key["\s]*[:=]["\s]*)[a-zA-Z0-9_-]{10,}`)
+	bearerPattern   = regexp.MustCompile(`(?i)(authorization["\s]*[:=]["\s]*bearer\s+)[a-zA-Z0-9._-]+`)
+	tokenPattern    = regexp.MustCompile(`(?i)(token["\s]*[:=]["\s]*)[a-zA-Z0-9._-]{10,}`)
+	passwordPattern = regexp.MustCompile(`(?i)(password["\s]*[:=]["\s]*)[^\s"']{4,}`)
+)
+
+// Redacted 敏感数据脱敏后的占位内容
+const Redacted = "***REDACTED***"
+
+// SanitizeSensitiveData 清理敏感数据
+func SanitizeSensitiveData(input string) string {
+	result := input
+	for _, pattern := range []*regexp.Regexp{apiKeyPattern, bearerPattern, tokenPattern, passwordPattern} {
+		p := pattern
+		result = p.ReplaceAllStringFunc(result, func(match string) string {
+			parts := p.FindStringSubmatch(match)
+			if len(parts) >= 2 {
+				return parts[1] + Redacted
+			}
+			return match
+		})
+	}
+
+	return result
+}
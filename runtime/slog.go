@@ -0,0 +1,40 @@
+//go:build go1.21
+
+package runtime
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Level 实现 slog.Leveler
+func (l Level) Level() slog.Level {
+	return slog.Level(l)
+}
+
+// NewSlogLogger 使用 log/slog 输出结构化日志，l 为 nil 时使用 slog.Default()
+//
+//	runtime.SetLogger(runtime.NewSlogLogger(slog.New(slog.NewJSONHandler(os.Stderr, nil))))
+func NewSlogLogger(l *slog.Logger) Logger {
+	if l == nil {
+		l = slog.Default()
+	}
+	return &slogLogger{log: l}
+}
+
+// slogLogger 基于 log/slog 的日志实现
+type slogLogger struct {
+	log *slog.Logger
+}
+
+func (s *slogLogger) Enabled(ctx context.Context, level Level) bool {
+	return s.log.Enabled(ctx, slog.Level(level))
+}
+
+func (s *slogLogger) Log(ctx context.Context, level Level, msg string, attrs ...Attribute) {
+	args := make([]slog.Attr, 0, len(attrs))
+	for _, a := range attrs {
+		args = append(args, slog.Any(a.Key, a.Value))
+	}
+	s.log.LogAttrs(ctx, slog.Level(level), msg, args...)
+}
@@ -0,0 +1,32 @@
+//go:build go1.21
+
+package runtime_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/sohaha/zlsgo"
+	"github.com/sohaha/zlsgo/zjson"
+	"github.com/zlsgo/zllm/runtime"
+)
+
+func TestSlogLogger(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	var buf bytes.Buffer
+	runtime.SetLogger(runtime.NewSlogLogger(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: runtime.LevelInfo}))))
+	defer runtime.SetLogger(nil)
+
+	ctx := context.Background()
+	runtime.Debug(ctx, "skipped")
+	runtime.Warn(ctx, "LLM request failed", runtime.Attr(runtime.KeyModel, "gpt-4o"), runtime.Attr(runtime.KeyStatus, 429))
+
+	res := zjson.ParseBytes(bytes.TrimSpace(buf.Bytes()))
+	tt.Equal("WARN", res.Get("level").String())
+	tt.Equal("LLM request failed", res.Get("msg").String())
+	tt.Equal("gpt-4o", res.Get(runtime.KeyModel).String())
+	tt.Equal(429, res.Get(runtime.KeyStatus).Int())
+}
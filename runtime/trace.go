@@ -0,0 +1,126 @@
+package runtime
+
+import (
+	"context"
+	"sync"
+)
+
+// GenAI 语义约定属性名
+const (
+	AttrOperationName      = "gen_ai.operation.name"
+	AttrSystem             = "gen_ai.system"
+	AttrRequestModel       = "gen_ai.request.model"
+	AttrRequestTemperature = "gen_ai.request.temperature"
+	AttrRequestMaxTokens   = "gen_ai.request.max_tokens"
+	AttrResponseModel      = "gen_ai.response.model"
+	AttrResponseID         = "gen_ai.response.id"
+	AttrResponseFinish     = "gen_ai.response.finish_reasons"
+	AttrUsageInputTokens   = "gen_ai.usage.input_tokens"
+	AttrUsageOutputTokens  = "gen_ai.usage.output_tokens"
+	AttrToolName           = "gen_ai.tool.name"
+	AttrToolCallArguments  = "gen_ai.tool.call.arguments"
+	AttrToolCallResult     = "gen_ai.tool.call.result"
+	AttrPrompt             = "gen_ai.prompt"     // 请求内容，仅在 Tracer.CaptureContent 时记录
+	AttrCompletion         = "gen_ai.completion" // 响应内容，仅在 Tracer.CaptureContent 时记录
+	AttrServerAddress      = "server.address"
+	AttrHTTPStatusCode     = "http.response.status_code"
+	AttrErrorType          = "error.type"
+	AttrRetryAttempt       = "zllm.retry.attempt"
+	AttrStream             = "zllm.stream"
+)
+
+// GenAI 操作名称
+const (
+	OperationChat        = "chat"
+	OperationExecuteTool = "execute_tool"
+)
+
+// SpanKind 追踪片段类型
+type SpanKind int
+
+const (
+	SpanKindInternal SpanKind = iota // 内部操作
+	SpanKindClient                   // 对外请求
+)
+
+// Attribute 追踪属性
+type Attribute struct {
+	Value any
+	Key   string
+}
+
+// Attr 创建追踪属性
+func Attr(key string, value any) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Span 追踪片段
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	RecordError(err error)
+	End()
+}
+
+// Tracer 链路追踪接口，未设置时不产生任何追踪数据
+type Tracer interface {
+	Start(ctx context.Context, name string, kind SpanKind, attrs ...Attribute) (context.Context, Span)
+	// CaptureContent 是否记录提示词与响应内容
+	CaptureContent() bool
+}
+
+var (
+	tracer   Tracer
+	tracerMu sync.RWMutex
+)
+
+// SetTracer 设置全局链路追踪，传入 nil 关闭追踪
+func SetTracer(t Tracer) {
+	tracerMu.Lock()
+	tracer = t
+	tracerMu.Unlock()
+}
+
+// getTracer 获取全局链路追踪
+func getTracer() Tracer {
+	tracerMu.RLock()
+	defer tracerMu.RUnlock()
+
+	return tracer
+}
+
+// Tracing 是否已启用链路追踪
+func Tracing() bool {
+	return getTracer() != nil
+}
+
+// CaptureContent 是否需要在追踪中记录提示词与响应内容
+func CaptureContent() bool {
+	t := getTracer()
+	return t != nil && t.CaptureContent()
+}
+
+// StartSpan 开始一个追踪片段，未启用追踪时返回空实现
+func StartSpan(ctx context.Context, name string, kind SpanKind, attrs ...Attribute) (context.Context, Span) {
+	t := getTracer()
+	if t == nil {
+		return ctx, noopSpan{}
+	}
+	return t.Start(ctx, name, kind, attrs...)
+}
+
+// EndSpan 记录错误（如有）并结束追踪片段
+func EndSpan(span Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+// noopSpan 未启用追踪时的空实现
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...Attribute) {}
+
+func (noopSpan) RecordError(error) {}
+
+func (noopSpan) End() {}
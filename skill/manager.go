@@ -1,6 +1,7 @@
 package skill
 
 import (
+	"context"
 	"fmt"
 	"sort"
 	"sync"
@@ -50,7 +51,7 @@ func (m *DefaultSkillManager) LoadSkills(paths []string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	runtime.Log("Loading skills from paths:", paths)
+	runtime.Debug(context.Background(), "Loading skills", runtime.Attr("paths", paths))
 
 	m.stats.LoadedPaths = append(m.stats.LoadedPaths, paths...)
 
@@ -60,16 +61,16 @@ func (m *DefaultSkillManager) LoadSkills(paths []string) error {
 
 	for _, skill := range skills {
 		m.skills[skill.Name()] = skill
-		runtime.Log("Loaded skill:", skill.Name())
+		runtime.Debug(context.Background(), "Loaded skill", runtime.Attr("skill", skill.Name()))
 	}
 
 	m.stats.TotalSkills = len(m.skills)
 	m.stats.LoadErrors = errors
 
-	runtime.Log("Skills loading completed:", len(m.skills), "skills loaded,", len(errors), "errors")
+	runtime.Debug(context.Background(), "Skills loading completed", runtime.Attr("skills", len(m.skills)), runtime.Attr("errors", len(errors)))
 
 	if len(errors) > 0 {
-		runtime.Log("Skill loading errors:", errors)
+		runtime.Warn(context.Background(), "Skill loading errors", runtime.Attr(runtime.KeyError, errors))
 		return fmt.Errorf("loaded %d skills with %d errors", len(m.skills), len(errors))
 	}
 
@@ -80,13 +81,13 @@ func (m *DefaultSkillManager) FindRelevantSkills(query string, limit int) []Skil
 	m.mu.RLock()
 	if cached, exists := m.cache[query]; exists {
 		m.mu.RUnlock()
-		runtime.Log("Cache hit for query:", query, "found", len(cached), "matches")
+		runtime.Debug(context.Background(), "Skill match cache hit", runtime.ContentAttr("query", query), runtime.Attr("matches", len(cached)))
 		return cached[:min(limit, len(cached))]
 	}
 
 	m.mu.RUnlock()
 
-	runtime.Log("Finding relevant skills for query:", query, "limit:", limit)
+	runtime.Debug(context.Background(), "Finding relevant skills", runtime.ContentAttr("query", query), runtime.Attr("limit", limit))
 
 	m.mu.RLock()
 	skillsCopy := make([]Skill, 0, len(m.skills))
@@ -104,7 +105,7 @@ func (m *DefaultSkillManager) FindRelevantSkills(query string, limit int) []Skil
 				Score:  score,
 				Reason: m.getMatchReason(skill, query),
 			})
-			runtime.Log("Skill match found:", skill.Name(), "score:", score)
+			runtime.Debug(context.Background(), "Skill match found", runtime.Attr("skill", skill.Name()), runtime.Attr("score", score))
 		}
 	}
 
@@ -123,7 +124,7 @@ func (m *DefaultSkillManager) FindRelevantSkills(query string, limit int) []Skil
 	m.cache[query] = matches
 	m.mu.Unlock()
 
-	runtime.Log("Query processed:", query, "found", len(matches), "relevant skills")
+	runtime.Debug(context.Background(), "Skill query processed", runtime.ContentAttr("query", query), runtime.Attr("matches", len(matches)))
 	return matches
 }
 
@@ -57,21 +57,44 @@ func NewSkillsProvider(baseAgent agent.LLM, manager SkillManager, config SkillsC
 	}
 }
 
+// WithCallOptions 对底层代理应用单次调用参数
+func (p *SkillsProvider) WithCallOptions(co agent.CallOptions) agent.LLM {
+	c := *p
+	c.agent = agent.ApplyCallOptions(p.agent, co)
+	return &c
+}
+
+// Resolve 解析底层代理实际使用的 LLM，并保留技能注入
+func (p *SkillsProvider) Resolve(ctx context.Context, messages *message.Messages, options ...func(ztype.Map) ztype.Map) (agent.LLM, error) {
+	llm, err := agent.Resolve(ctx, p.agent, messages, options...)
+	if err != nil || llm == p.agent {
+		return p, err
+	}
+	c := *p
+	c.agent = llm
+	return &c, nil
+}
+
+// Capabilities 返回被包装 LLM 的模型能力
+func (p *SkillsProvider) Capabilities() (agent.Capabilities, bool) {
+	return agent.ModelCapabilities(p.agent)
+}
+
 func (p *SkillsProvider) Generate(ctx context.Context, data []byte) (*zjson.Res, error) {
 	if !p.config.Enabled {
-		runtime.Log("Skills provider disabled, delegating to base agent")
+		runtime.Debug(ctx, "Skills provider disabled, delegating to base agent")
 		return p.agent.Generate(ctx, data)
 	}
 
-	runtime.Log("Skills provider processing request")
+	runtime.Debug(ctx, "Skills provider processing request")
 
 	query, err := p.extractQueryFromRequest(data)
 	if err != nil {
-		runtime.Log("Failed to extract query from request:", err, "falling back to base agent")
+		runtime.Warn(ctx, "Failed to extract query from request, falling back to base agent", runtime.ErrAttr(err))
 		return p.agent.Generate(ctx, data)
 	}
 
-	runtime.Log("Extracted query:", query)
+	runtime.Debug(ctx, "Extracted skill query", runtime.ContentAttr("query", query))
 
 	skills := p.manager.FindRelevantSkills(query, p.config.MaxSkills)
 
@@ -79,20 +102,20 @@ func (p *SkillsProvider) Generate(ctx context.Context, data []byte) (*zjson.Res,
 	for _, match := range skills {
 		if match.Score >= p.config.MinScore {
 			relevantSkills = append(relevantSkills, match)
-			runtime.Log("Selected relevant skill:", match.Skill.Name(), "score:", match.Score)
+			runtime.Debug(ctx, "Selected relevant skill", runtime.Attr("skill", match.Skill.Name()), runtime.Attr("score", match.Score))
 		}
 	}
 
 	if len(relevantSkills) == 0 {
-		runtime.Log("No relevant skills found with minimum score:", p.config.MinScore, "falling back to base agent")
+		runtime.Debug(ctx, "No relevant skills found, falling back to base agent", runtime.Attr("min_score", p.config.MinScore))
 		return p.agent.Generate(ctx, data)
 	}
 
-	runtime.Log("Injecting", len(relevantSkills), "skills into request")
+	runtime.Debug(ctx, "Injecting skills into request", runtime.Attr("skills", len(relevantSkills)))
 
 	modifiedData, err := p.injectSkills(data, relevantSkills)
 	if err != nil {
-		runtime.Log("Failed to inject skills:", err, "falling back to base agent")
+		runtime.Warn(ctx, "Failed to inject skills, falling back to base agent", runtime.ErrAttr(err))
 		return p.agent.Generate(ctx, data)
 	}
 
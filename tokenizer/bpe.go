@@ -0,0 +1,214 @@
+package tokenizer
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sohaha/zlsgo/zutil"
+)
+
+// 支持的 BPE 编码名称
+const (
+	Cl100kBase = "cl100k_base"
+	O200kBase  = "o200k_base"
+)
+
+var (
+	encodingMu sync.RWMutex
+	encodings  = map[string]*BPE{}
+	attempted  = map[string]bool{}
+	vocabDir   = zutil.Getenv("ZLLM_TOKENIZER_DIR", "")
+)
+
+// BPE 基于 tiktoken 词表的字节对编码计数器
+type BPE struct {
+	name  string
+	ranks map[string]int
+}
+
+// SetVocabDir 设置词表目录，目录中的 {name}.tiktoken 文件会在首次使用时自动加载
+func SetVocabDir(dir string) {
+	encodingMu.Lock()
+	defer encodingMu.Unlock()
+
+	vocabDir = dir
+	attempted = map[string]bool{}
+}
+
+// Register 注册 BPE 编码，覆盖同名编码
+func Register(bpe *BPE) {
+	encodingMu.Lock()
+	defer encodingMu.Unlock()
+
+	encodings[bpe.name] = bpe
+}
+
+// Encoding 获取指定名称的编码，词表不可用时返回 OpenAIHeuristic
+func Encoding(name string) Tokenizer {
+	encodingMu.RLock()
+	bpe, ok := encodings[name]
+	tried := attempted[name]
+	dir := vocabDir
+	encodingMu.RUnlock()
+
+	if ok {
+		return bpe
+	}
+
+	if !tried && dir != "" {
+		encodingMu.Lock()
+		if _, ok := encodings[name]; !ok && !attempted[name] {
+			attempted[name] = true
+			if bpe, err := LoadFile(name, filepath.Join(dir, name+".tiktoken")); err == nil {
+				encodings[name] = bpe
+			}
+		}
+		bpe = encodings[name]
+		encodingMu.Unlock()
+
+		if bpe != nil {
+			return bpe
+		}
+	}
+
+	return OpenAIHeuristic
+}
+
+// LoadFile 从磁盘读取 tiktoken 格式的词表
+func LoadFile(name, path string) (*BPE, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Load(name, f)
+}
+
+// LoadFS 从文件系统（如 embed.FS）读取 tiktoken 格式的词表
+func LoadFS(fsys fs.FS, name, path string) (*BPE, error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Load(name, f)
+}
+
+// Load 解析 tiktoken 格式的词表，每行为 "base64(token) rank"
+func Load(name string, r io.Reader) (*BPE, error) {
+	ranks := make(map[string]int, 1<<17)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid vocab line %d", line)
+		}
+
+		token, err := base64.StdEncoding.DecodeString(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid token on line %d: %w", line, err)
+		}
+
+		rank, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid rank on line %d: %w", line, err)
+		}
+		ranks[string(token)] = rank
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(ranks) == 0 {
+		return nil, fmt.Errorf("empty vocab: %s", name)
+	}
+
+	return NewBPE(name, ranks), nil
+}
+
+// NewBPE 使用给定的词表创建 BPE 编码
+func NewBPE(name string, ranks map[string]int) *BPE {
+	return &BPE{name: name, ranks: ranks}
+}
+
+// Name 返回编码名称
+func (b *BPE) Name() string {
+	return b.name
+}
+
+// Count 计算文本的 token 数
+func (b *BPE) Count(text string) int {
+	n := 0
+	for _, piece := range b.split(text) {
+		if _, ok := b.ranks[piece]; ok {
+			n++
+			continue
+		}
+		n += len(b.merge(piece))
+	}
+	return n
+}
+
+// Encode 将文本编码为 token 序列
+func (b *BPE) Encode(text string) []int {
+	tokens := make([]int, 0, len(text)/3+1)
+	for _, piece := range b.split(text) {
+		if rank, ok := b.ranks[piece]; ok {
+			tokens = append(tokens, rank)
+			continue
+		}
+		for _, part := range b.merge(piece) {
+			tokens = append(tokens, b.ranks[part])
+		}
+	}
+	return tokens
+}
+
+// split 按编码对应的预分词规则切分文本
+func (b *BPE) split(text string) []string {
+	return splitPieces(text, b.name == O200kBase)
+}
+
+// merge 对单个片段执行字节对合并，每次合并词表中排名最小的相邻片段
+func (b *BPE) merge(piece string) []string {
+	parts := make([]string, len(piece))
+	for i := 0; i < len(piece); i++ {
+		parts[i] = piece[i : i+1]
+	}
+
+	for len(parts) > 1 {
+		best, at := -1, -1
+		for i := 0; i < len(parts)-1; i++ {
+			if rank, ok := b.ranks[parts[i]+parts[i+1]]; ok && (best < 0 || rank < best) {
+				best, at = rank, i
+			}
+		}
+		if at < 0 {
+			break
+		}
+		parts[at] += parts[at+1]
+		parts = append(parts[:at+1], parts[at+2:]...)
+	}
+
+	return parts
+}
@@ -0,0 +1,162 @@
+package tokenizer
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// splitPieces 按 tiktoken 的预分词正则切分文本
+// Go 的 regexp 不支持 (?!\S) 前瞻，这里用手写扫描器等价实现 cl100k_base 的规则：
+//
+//	(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+
+//
+// o200k_base 在此基础上将缩写并入前面的单词，并允许标点后跟随 "/"
+func splitPieces(text string, o200k bool) []string {
+	pieces := make([]string, 0, len(text)/4+1)
+	for i := 0; i < len(text); {
+		n := matchPiece(text[i:], o200k)
+		pieces = append(pieces, text[i:i+n])
+		i += n
+	}
+	return pieces
+}
+
+// matchPiece 返回文本开头第一个片段的字节长度
+func matchPiece(s string, o200k bool) int {
+	if !o200k {
+		if n := matchContraction(s); n > 0 {
+			return n
+		}
+	}
+
+	r, size := utf8.DecodeRuneInString(s)
+
+	if n := matchWord(s, o200k); n > 0 {
+		return n
+	}
+
+	if unicode.IsNumber(r) {
+		n, count := 0, 0
+		for n < len(s) && count < 3 {
+			r, size := utf8.DecodeRuneInString(s[n:])
+			if !unicode.IsNumber(r) {
+				break
+			}
+			n += size
+			count++
+		}
+		return n
+	}
+
+	if n := matchPunct(s, o200k); n > 0 {
+		return n
+	}
+
+	if unicode.IsSpace(r) {
+		end := 0
+		lastNewline := -1
+		for end < len(s) {
+			r, size := utf8.DecodeRuneInString(s[end:])
+			if !unicode.IsSpace(r) {
+				break
+			}
+			if r == '\r' || r == '\n' {
+				lastNewline = end + size
+			}
+			end += size
+		}
+
+		if lastNewline > 0 {
+			return lastNewline
+		}
+		if end == len(s) {
+			return end
+		}
+
+		_, lastSize := utf8.DecodeLastRuneInString(s[:end])
+		if end-lastSize > 0 {
+			return end - lastSize
+		}
+		return end
+	}
+
+	return size
+}
+
+// matchContraction 匹配英文缩写 's 't 're 've 'm 'll 'd
+func matchContraction(s string) int {
+	if len(s) < 2 || s[0] != '\'' {
+		return 0
+	}
+
+	lower := strings.ToLower(s[1:min(len(s), 3)])
+	switch {
+	case strings.HasPrefix(lower, "re"), strings.HasPrefix(lower, "ve"), strings.HasPrefix(lower, "ll"):
+		return 3
+	case lower[0] == 's', lower[0] == 't', lower[0] == 'm', lower[0] == 'd':
+		return 2
+	}
+	return 0
+}
+
+// matchWord 匹配 [^\r\n\p{L}\p{N}]?\p{L}+，o200k 模式下附带尾随缩写
+func matchWord(s string, o200k bool) int {
+	n := 0
+	r, size := utf8.DecodeRuneInString(s)
+	if !unicode.IsLetter(r) {
+		if r == '\r' || r == '\n' || unicode.IsNumber(r) || size >= len(s) {
+			return 0
+		}
+		next, _ := utf8.DecodeRuneInString(s[size:])
+		if !unicode.IsLetter(next) {
+			return 0
+		}
+		n = size
+	}
+
+	for n < len(s) {
+		r, size := utf8.DecodeRuneInString(s[n:])
+		if !unicode.IsLetter(r) && !(o200k && unicode.Is(unicode.M, r)) {
+			break
+		}
+		n += size
+	}
+
+	if o200k {
+		n += matchContraction(s[n:])
+	}
+	return n
+}
+
+// matchPunct 匹配 " ?[^\s\p{L}\p{N}]+[\r\n]*"
+func matchPunct(s string, o200k bool) int {
+	n := 0
+	if s[0] == ' ' {
+		n = 1
+	}
+
+	start := n
+	for n < len(s) {
+		r, size := utf8.DecodeRuneInString(s[n:])
+		if unicode.IsSpace(r) || unicode.IsLetter(r) || unicode.IsNumber(r) {
+			break
+		}
+		n += size
+	}
+	if n == start {
+		return 0
+	}
+
+	for n < len(s) && (s[n] == '\r' || s[n] == '\n' || (o200k && s[n] == '/')) {
+		n++
+	}
+	return n
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,101 @@
+package tokenizer
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/sohaha/zlsgo"
+)
+
+func TestSplitPieces(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	tt.Equal([]string{"Hello", " world"}, splitPieces("Hello world", false))
+	tt.Equal([]string{"I", "'m", " ", "123", "45"}, splitPieces("I'm 12345", false))
+	tt.Equal([]string{"a", "  ", " b"}, splitPieces("a   b", false))
+	tt.Equal([]string{"x", "\n\n", "y"}, splitPieces("x\n\ny", false))
+	tt.Equal([]string{"end", "!!!\n"}, splitPieces("end!!!\n", false))
+	tt.Equal([]string{"你好", "，世界"}, splitPieces("你好，世界", false))
+	tt.Equal([]string{"I'm", " fine"}, splitPieces("I'm fine", true))
+}
+
+func testVocab() string {
+	tokens := []string{}
+	for i := 0; i < 256; i++ {
+		tokens = append(tokens, string([]byte{byte(i)}))
+	}
+	tokens = append(tokens, "he", "ll", "hell", "hello", " w", " wo", " wor", "ld")
+
+	var b strings.Builder
+	for i, token := range tokens {
+		b.WriteString(base64.StdEncoding.EncodeToString([]byte(token)))
+		b.WriteString(" ")
+		b.WriteString(strconv.Itoa(i))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func TestBPE(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	bpe, err := Load("test", strings.NewReader(testVocab()))
+	tt.NoError(err, true)
+	tt.Equal("test", bpe.Name())
+
+	tt.Equal([]int{259}, bpe.Encode("hello"))
+	tt.Equal(3, bpe.Count("hello world"))
+	tt.Equal([]int{259, 262, 263}, bpe.Encode("hello world"))
+
+	_, err = Load("bad", strings.NewReader("abc"))
+	tt.EqualTrue(err != nil)
+}
+
+func TestEncoding(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	tt.Equal("openai", Encoding("missing_base").Name())
+
+	dir := t.TempDir()
+	tt.NoError(os.WriteFile(filepath.Join(dir, "fake_base.tiktoken"), []byte(testVocab()), 0o644), true)
+
+	SetVocabDir(dir)
+	defer SetVocabDir("")
+
+	tk := Encoding("fake_base")
+	tt.Equal("fake_base", tk.Name())
+	tt.Equal(1, tk.Count("hello"))
+}
+
+func TestForModel(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	tt.Equal("anthropic", ForModel("claude-3-5-sonnet-latest").Name())
+	tt.Equal("gemini", ForModel("gemini-2.0-flash").Name())
+	tt.Equal("ollama", ForModel("qwen2.5:3b").Name())
+	tt.Equal("deepseek", ForModel("deepseek-chat").Name())
+	tt.Equal("openai", ForModel("gpt-4o-mini").Name())
+
+	Register(NewBPE(O200kBase, map[string]int{"a": 0}))
+	defer func() {
+		encodingMu.Lock()
+		delete(encodings, O200kBase)
+		encodingMu.Unlock()
+	}()
+	tt.Equal(O200kBase, ForModel("openai/gpt-4o").Name())
+}
+
+func TestHeuristic(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	h := NewHeuristic("test", 4, 1)
+	tt.Equal(0, h.Count(""))
+	tt.Equal(1, h.Count("abcd"))
+	tt.Equal(2, h.Count("abcde"))
+	tt.Equal(4, h.Count("你好世界"))
+	tt.Equal(5, AnthropicHeuristic.Count("你好世界"))
+}
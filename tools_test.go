@@ -2,12 +2,16 @@ package zllm
 
 import (
 	"context"
+	"strings"
 	"testing"
 
+	"github.com/sohaha/zlsgo"
 	"github.com/sohaha/zlsgo/zjson"
 	"github.com/sohaha/zlsgo/ztype"
 	"github.com/zlsgo/zllm/agent"
+	"github.com/zlsgo/zllm/agent/agenttest"
 	"github.com/zlsgo/zllm/message"
+	runtime_errors "github.com/zlsgo/zllm/runtime/errors"
 )
 
 type mockToolRunner struct{}
@@ -69,3 +73,110 @@ func TestToolRunnerLoop(t *testing.T) {
 		t.Fatalf("unexpected resp: %q", resp)
 	}
 }
+
+func TestScriptedToolLoop(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	llm := agenttest.NewMock(
+		agenttest.Error(runtime_errors.NewLLMError(runtime_errors.ErrServer, "overloaded")),
+		agenttest.ToolCall("echo", `{"text":"hi"}`),
+		agenttest.Text(`{"Assistant":"final: hi"}`),
+	)
+
+	ctx := WithToolRunner(context.Background(), mockToolRunner{})
+	resp, err := CompleteLLM(ctx, llm, message.NewPrompt("say hi via tool"))
+	tt.NoError(err, true)
+	tt.Equal(`{"Assistant":"final: hi"}`, resp)
+	tt.Equal(3, llm.Calls())
+
+	last := llm.LastMessages()
+	tt.EqualTrue(strings.Contains(last[len(last)-1][1], `"result":"hi"`))
+
+	llm = agenttest.NewMock(agenttest.Error(runtime_errors.NewLLMError(runtime_errors.ErrUnauthorized, "bad key")))
+	_, err = CompleteLLM(context.Background(), llm, message.NewPrompt("hi"))
+	tt.EqualTrue(err != nil)
+	tt.Equal(1, llm.Calls())
+}
+
+func TestToolCallRoundTrip(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+	ctx := WithToolRunner(context.Background(), mockToolRunner{})
+
+	srv := agenttest.NewServer(agenttest.Anthropic,
+		agenttest.ToolCall("echo", `{"text":"hi"}`).WithReasoning("need echo"),
+		agenttest.Text(`{"Assistant":"final: hi"}`),
+	)
+	defer srv.Close()
+
+	claude := agent.NewAnthropic(func(o *agent.AnthropicOptions) {
+		o.BaseURL = srv.URL
+		o.ThinkingBudget = 1024
+	})
+	resp, err := CompleteLLM(ctx, claude, message.NewPrompt("say hi via tool"))
+	tt.NoError(err, true)
+	tt.Equal(`{"Assistant":"final: hi"}`, resp)
+
+	sent := zjson.ParseBytes(srv.Requests()[1].Body).Get("messages")
+	tt.Equal(3, len(sent.Array()))
+	assistant := sent.Get("1")
+	tt.Equal("assistant", assistant.Get("role").String())
+	tt.Equal("thinking", assistant.Get("content.0.type").String())
+	tt.Equal(agenttest.MockSignature, assistant.Get("content.0.signature").String())
+	tt.Equal("tool_use", assistant.Get("content.1.type").String())
+	tt.Equal("toolu_0", assistant.Get("content.1.id").String())
+	tt.Equal("hi", assistant.Get("content.1.input.text").String())
+	result := sent.Get("2")
+	tt.Equal("user", result.Get("role").String())
+	tt.Equal("tool_result", result.Get("content.0.type").String())
+	tt.Equal("toolu_0", result.Get("content.0.tool_use_id").String())
+	tt.Equal("hi", result.Get("content.0.content").String())
+
+	srv = agenttest.NewServer(agenttest.OpenAI,
+		agenttest.ToolCall("echo", `{"text":"hi"}`),
+		agenttest.Text(`{"Assistant":"final: hi"}`),
+	)
+	defer srv.Close()
+
+	gpt := agent.NewOpenAI(func(o *agent.OpenAIOptions) {
+		o.BaseURL = srv.URL
+		o.APIURL = "/chat/completions"
+	})
+	_, err = CompleteLLM(ctx, gpt, message.NewPrompt("say hi via tool"))
+	tt.NoError(err, true)
+
+	sent = zjson.ParseBytes(srv.Requests()[1].Body).Get("messages")
+	assistant = sent.Get("1")
+	tt.Equal("assistant", assistant.Get("role").String())
+	tt.Equal("call_0", assistant.Get("tool_calls.0.id").String())
+	tt.Equal("echo", assistant.Get("tool_calls.0.function.name").String())
+	tt.Equal(`{"text":"hi"}`, assistant.Get("tool_calls.0.function.arguments").String())
+	result = sent.Get("2")
+	tt.Equal("tool", result.Get("role").String())
+	tt.Equal("call_0", result.Get("tool_call_id").String())
+	tt.Equal("hi", result.Get("content").String())
+
+	srv = agenttest.NewServer(agenttest.OpenAIResponses,
+		agenttest.ToolCall("echo", `{"text":"hi"}`).WithReasoning("need echo"),
+		agenttest.Text(`{"Assistant":"final: hi"}`),
+	)
+	defer srv.Close()
+
+	responses := agent.NewOpenAIResponses(func(o *agent.OpenAIResponsesOptions) {
+		o.BaseURL = srv.URL
+	})
+	_, err = CompleteLLM(ctx, responses, message.NewPrompt("say hi via tool"))
+	tt.NoError(err, true)
+
+	sent = zjson.ParseBytes(srv.Requests()[1].Body).Get("input")
+	tt.Equal(4, len(sent.Array()))
+	tt.Equal("reasoning", sent.Get("1.type").String())
+	tt.Equal("rs_mock", sent.Get("1.id").String())
+	tt.Equal("need echo", sent.Get("1.summary.0.text").String())
+	tt.Equal("function_call", sent.Get("2.type").String())
+	tt.Equal("call_0", sent.Get("2.call_id").String())
+	tt.Equal("echo", sent.Get("2.name").String())
+	tt.Equal(`{"text":"hi"}`, sent.Get("2.arguments").String())
+	tt.Equal("function_call_output", sent.Get("3.type").String())
+	tt.Equal("call_0", sent.Get("3.call_id").String())
+	tt.Equal("hi", sent.Get("3.output").String())
+}
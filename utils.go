@@ -10,6 +10,7 @@ import (
 	"github.com/sohaha/zlsgo/ztype"
 	"github.com/zlsgo/zllm/agent"
 	"github.com/zlsgo/zllm/message"
+	"github.com/zlsgo/zllm/runtime"
 	runtime_errors "github.com/zlsgo/zllm/runtime/errors"
 )
 
@@ -27,16 +28,18 @@ type llmInteractionProcessor struct {
 	ctx      context.Context             // 请求上下文
 	llm      agent.LLM                   // LLM 代理
 	messages *message.Messages           // 消息历史
+	window   *contextWindowState         // 上下文窗口状态
 	body     []byte                      // 请求体
 	options  []func(ztype.Map) ztype.Map // 请求选项
 }
 
 // newLLMInteractionProcessor 创建 LLM 交互处理器
-func newLLMInteractionProcessor(ctx context.Context, llm agent.LLM, messages *message.Messages, body []byte, options ...func(ztype.Map) ztype.Map) *llmInteractionProcessor {
+func newLLMInteractionProcessor(ctx context.Context, llm agent.LLM, messages *message.Messages, window *contextWindowState, body []byte, options ...func(ztype.Map) ztype.Map) *llmInteractionProcessor {
 	return &llmInteractionProcessor{
 		ctx:      ctx,
 		llm:      llm,
 		messages: messages,
+		window:   window,
 		body:     body,
 		options:  options,
 	}
@@ -72,17 +75,33 @@ func (p *llmInteractionProcessor) shouldContinueIteration(state *ToolIterationSt
 // processSingleIteration 处理单次 LLM 交互迭代，包含重试逻辑
 func (p *llmInteractionProcessor) processSingleIteration(state *ToolIterationState) error {
 	maxRetries := 2
-	var consecutiveErrors int
+	var (
+		consecutiveErrors int
+		lastErr           error
+	)
 
 	for i := 0; i <= maxRetries; i++ {
+		ctx, span := p.ctx, runtime.Span(nil)
 		if i > 0 {
+			if lastErr != nil {
+				agent.NotifyRetry(p.ctx, p.llm, p.body, i, lastErr)
+			}
 			interval := calculateRetryInterval(i, consecutiveErrors)
 			time.Sleep(interval)
+			// 重试 span 只覆盖重试请求本身，不包含退避等待
+			if lastErr != nil {
+				ctx, span = runtime.StartSpan(p.ctx, "zllm.retry", runtime.SpanKindInternal,
+					runtime.Attr(runtime.AttrRetryAttempt, i), runtime.Attr(runtime.AttrErrorType, agent.ErrorCode(lastErr).String()))
+			}
 		}
 
-		response, err := p.generateLLMResponse()
+		response, err := p.generateLLMResponse(ctx)
+		if span != nil {
+			runtime.EndSpan(span, err)
+		}
 		if err != nil {
 			consecutiveErrors++
+			lastErr = err
 
 			if !shouldRetryError(err, i, maxRetries, consecutiveErrors) {
 				return err
@@ -91,6 +110,7 @@ func (p *llmInteractionProcessor) processSingleIteration(state *ToolIterationSta
 		}
 
 		consecutiveErrors = 0
+		lastErr = nil
 
 		if p.hasToolCalls(response) {
 			if _, err := p.handleToolCalls(response, state); err != nil {
@@ -156,8 +176,8 @@ func shouldRetryError(err error, currentAttempt, maxAttempts, consecutiveErrors
 
 // generateLLMResponse 从 LLM 生成响应
 // 返回 解析后的 LLM 响应和任何错误
-func (p *llmInteractionProcessor) generateLLMResponse() (*agent.Response, error) {
-	resp, err := p.llm.Generate(p.ctx, p.body)
+func (p *llmInteractionProcessor) generateLLMResponse(ctx context.Context) (*agent.Response, error) {
+	resp, err := p.llm.Generate(ctx, p.body)
 	if err != nil {
 		return nil, err
 	}
@@ -191,11 +211,28 @@ func (p *llmInteractionProcessor) handleToolCalls(response *agent.Response, stat
 		return false, fmt.Errorf("tool runner not configured: use WithToolRunner() to set up tool execution for %d tool(s)", len(response.Tools))
 	}
 
+	// 未返回调用 ID 的提供商生成本地 ID，保证工具结果能与调用对应
+	calls := make([]message.ToolCall, len(response.Tools))
+	for i := range response.Tools {
+		if response.Tools[i].ID == "" {
+			response.Tools[i].ID = fmt.Sprintf("call_%d_%d", p.messages.Len(), i)
+		}
+		calls[i] = message.ToolCall{ID: response.Tools[i].ID, Name: response.Tools[i].Name, Args: response.Tools[i].Args}
+	}
+
 	toolResults, err := p.executeToolCalls(response.Tools, runner, state)
 	if err != nil {
 		return false, err
 	}
 
+	// 助手的工具调用轮次需完整回传，带签名的推理块缺失时后续请求会被拒绝
+	_ = p.messages.Append(message.Message{
+		Role:      message.RoleAssistant,
+		Content:   zstring.Bytes2String(response.Content),
+		Reasoning: response.ReasoningBlocks,
+		ToolCalls: calls,
+	})
+
 	if err := p.updateMessagesForNextIteration(toolResults); err != nil {
 		return false, err
 	}
@@ -229,15 +266,25 @@ func (p *llmInteractionProcessor) executeToolCalls(tools []agent.Tool, runner To
 // 返回 工具执行结果
 func (p *llmInteractionProcessor) executeSingleTool(tool agent.Tool, runner ToolRunner) ToolResult {
 	result := ToolResult{
+		ID:   tool.ID,
 		Name: tool.Name,
 		Args: tool.Args,
 	}
 
-	out, ferr := runner.Run(p.ctx, tool.Name, tool.Args)
+	ctx, span := runtime.StartSpan(p.ctx, runtime.OperationExecuteTool+" "+tool.Name, runtime.SpanKindInternal,
+		runtime.Attr(runtime.AttrOperationName, runtime.OperationExecuteTool), runtime.Attr(runtime.AttrToolName, tool.Name))
+	if runtime.CaptureContent() {
+		span.SetAttributes(runtime.Attr(runtime.AttrToolCallArguments, tool.Args))
+	}
+
+	out, ferr := runner.Run(ctx, tool.Name, tool.Args)
 	result.Result = out
 	if ferr != nil {
 		result.Err = ferr.Error()
+	} else if runtime.CaptureContent() {
+		span.SetAttributes(runtime.Attr(runtime.AttrToolCallResult, out))
 	}
+	runtime.EndSpan(span, ferr)
 
 	return result
 }
@@ -247,10 +294,19 @@ func (p *llmInteractionProcessor) executeSingleTool(tool agent.Tool, runner Tool
 // 返回 如果更新消息失败则返回错误
 func (p *llmInteractionProcessor) updateMessagesForNextIteration(toolResults []ToolResult) error {
 	content := getToolResultFormatter(p.ctx)(toolResults)
-	p.messages.AppendUser(content)
+	outputs := make([]message.ToolOutput, len(toolResults))
+	for i, r := range toolResults {
+		outputs[i] = message.ToolOutput{ID: r.ID, Name: r.Name, Content: r.Result, IsError: r.Err != ""}
+		if r.Err != "" {
+			outputs[i].Content = r.Err
+		}
+	}
+	if err := p.messages.AppendToolOutputs(content, outputs); err != nil {
+		return err
+	}
 
 	var err error
-	p.body, err = p.llm.PrepareRequest(p.messages, p.options...)
+	p.body, err = p.window.prepareRequest(p.ctx, p.llm, p.messages, p.options...)
 	if err != nil {
 		return fmt.Errorf("failed to prepare request after tool execution: %w", err)
 	}
@@ -314,11 +370,12 @@ func (p *llmInteractionProcessor) validateInteraction() error {
 // 参数 ctx 请求上下文
 // 参数 llm 要使用的 LLM 代理
 // 参数 messages 消息历史
+// 参数 window 上下文窗口状态
 // 参数 body 请求体
 // 参数 options 可选的请求修改器
 // 返回 响应内容、原始体和任何错误
-func processLLMInteractionWithValidation(ctx context.Context, llm agent.LLM, messages *message.Messages, body []byte, options ...func(ztype.Map) ztype.Map) (string, []byte, error) {
-	processor := newLLMInteractionProcessor(ctx, llm, messages, body, options...)
+func processLLMInteractionWithValidation(ctx context.Context, llm agent.LLM, messages *message.Messages, window *contextWindowState, body []byte, options ...func(ztype.Map) ztype.Map) (string, []byte, error) {
+	processor := newLLMInteractionProcessor(ctx, llm, messages, window, body, options...)
 
 	if err := processor.validateInteraction(); err != nil {
 		return "", nil, fmt.Errorf("invalid interaction parameters: %w", err)
@@ -0,0 +1,197 @@
+package zllm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sohaha/zlsgo/zstring"
+	"github.com/sohaha/zlsgo/ztype"
+	"github.com/zlsgo/zllm/agent"
+	"github.com/zlsgo/zllm/message"
+	"github.com/zlsgo/zllm/runtime"
+)
+
+// summaryNote 摘要系统提示的前缀
+const summaryNote = "以下是之前对话的摘要：\n"
+
+// DefaultSummaryPrompt 默认的历史摘要提示词
+const DefaultSummaryPrompt = "请将以下对话历史压缩为简洁的摘要，保留关键事实、结论、用户偏好和未完成的事项，只输出摘要内容。"
+
+// ContextWindow 上下文窗口管理配置，在每次构建请求前裁剪历史消息
+type ContextWindow struct {
+	MaxTokens     int                    // 请求允许的最大 token 数，0 表示不限制
+	KeepTurns     int                    // 最多保留最近的对话轮次，0 表示不限制
+	Estimator     message.TokenEstimator // 自定义 token 估算器，为空时优先使用提供商的估算
+	Summarize     bool                   // 是否将被丢弃的历史摘要为系统提示
+	Summarizer    agent.LLM              // 用于生成摘要的 LLM，为空时使用当前 LLM
+	SummaryPrompt string                 // 摘要提示词，为空时使用 DefaultSummaryPrompt
+	SummaryTokens int                    // 首次摘要前为摘要预留的 token 数，为空时预留 MaxTokens 的 1/4
+}
+
+// WithContextWindow 在上下文中设置上下文窗口管理策略
+func WithContextWindow(ctx context.Context, w ContextWindow) context.Context {
+	return context.WithValue(ctx, contextWindowKey{}, w)
+}
+
+// getContextWindow 从上下文中获取上下文窗口管理策略
+func getContextWindow(ctx context.Context) (ContextWindow, bool) {
+	w, ok := ctx.Value(contextWindowKey{}).(ContextWindow)
+	return w, ok
+}
+
+// contextWindowState 单次调用内的窗口状态，用于在工具迭代之间复用摘要
+type contextWindowState struct {
+	window    ContextWindow
+	enabled   bool
+	dropped   int
+	summary   string
+	estimator message.TokenEstimator
+}
+
+// newContextWindowState 根据上下文创建窗口状态
+func newContextWindowState(ctx context.Context, llm agent.LLM) *contextWindowState {
+	w, ok := getContextWindow(ctx)
+	state := &contextWindowState{window: w, enabled: ok && (w.MaxTokens > 0 || w.KeepTurns > 0)}
+	if !state.enabled {
+		return state
+	}
+
+	state.estimator = w.Estimator
+	if state.estimator == nil {
+		if counter, ok := llm.(agent.TokenCounter); ok {
+			state.estimator = counter.CountTokens
+		} else {
+			state.estimator = message.EstimateTokens
+		}
+	}
+	return state
+}
+
+// apply 返回裁剪后的消息集合，原始消息历史不会被修改
+func (s *contextWindowState) apply(ctx context.Context, llm agent.LLM, messages *message.Messages) (*message.Messages, error) {
+	if s == nil || !s.enabled {
+		return messages, nil
+	}
+
+	opt := message.WindowOptions{
+		MaxTokens: s.window.MaxTokens,
+		KeepTurns: s.window.KeepTurns,
+		Estimator: s.estimator,
+	}
+
+	// 摘要占用的 token 需计入预算，首次摘要前按预留值计算，
+	// 摘要超出预留时按实际大小重新裁剪
+	reserve := 0
+	if s.window.Summarize && s.window.MaxTokens > 0 {
+		reserve = s.window.SummaryTokens
+		if reserve <= 0 {
+			reserve = s.window.MaxTokens / 4
+		}
+		if s.summary != "" {
+			reserve = s.summaryTokens()
+		}
+	}
+
+	for {
+		if s.window.MaxTokens > 0 {
+			opt.MaxTokens = s.window.MaxTokens - reserve
+			if opt.MaxTokens < 1 {
+				opt.MaxTokens = 1
+			}
+		}
+		window, dropped := messages.Window(opt)
+		if len(dropped) == 0 {
+			return window, nil
+		}
+
+		runtime.Info(ctx, "Context window dropped messages", runtime.Attr("dropped", len(dropped)))
+
+		if !s.window.Summarize {
+			return window, nil
+		}
+
+		if err := s.summarize(ctx, llm, dropped); err != nil {
+			return nil, err
+		}
+
+		if used := s.summaryTokens(); s.window.MaxTokens > 0 && used > reserve {
+			reserve = used
+			continue
+		}
+		return window.PrependSystem(summaryNote + s.summary), nil
+	}
+}
+
+// summaryTokens 摘要系统提示占用的 token 数
+func (s *contextWindowState) summaryTokens() int {
+	return message.NewMessages().PrependSystem(summaryNote + s.summary).Tokens(s.estimator)
+}
+
+// summarize 摘要被丢弃的消息，丢弃范围不变时复用已有摘要
+func (s *contextWindowState) summarize(ctx context.Context, llm agent.LLM, dropped []message.Message) error {
+	if len(dropped) == s.dropped && s.summary != "" {
+		return nil
+	}
+
+	summarizer := s.window.Summarizer
+	if summarizer == nil {
+		summarizer = llm
+	}
+	summary, err := summarizeMessages(ctx, summarizer, dropped, s.window.SummaryPrompt)
+	if err != nil {
+		return fmt.Errorf("failed to summarize dropped history: %w", err)
+	}
+	s.summary = summary
+	s.dropped = len(dropped)
+	return nil
+}
+
+// prepareRequest 应用上下文窗口后构建请求体
+func (s *contextWindowState) prepareRequest(ctx context.Context, llm agent.LLM, messages *message.Messages, options ...func(ztype.Map) ztype.Map) ([]byte, error) {
+	window, err := s.apply(ctx, llm, messages)
+	if err != nil {
+		return nil, err
+	}
+	return llm.PrepareRequest(window, options...)
+}
+
+// summarizeMessages 调用 LLM 将消息摘要为一段文本
+func summarizeMessages(ctx context.Context, llm agent.LLM, dropped []message.Message, prompt string) (string, error) {
+	if prompt == "" {
+		prompt = DefaultSummaryPrompt
+	}
+
+	history := zstring.Buffer(len(dropped) * 2)
+	for i := range dropped {
+		history.WriteString(dropped[i].Role)
+		history.WriteString(": ")
+		history.WriteString(dropped[i].Content)
+		history.WriteString("\n")
+	}
+
+	msg := message.NewMessages()
+	_ = msg.Append(message.Message{Role: message.RoleSystem, Content: prompt})
+	_ = msg.Append(message.Message{Role: message.RoleUser, Content: history.String()})
+
+	body, err := llm.PrepareRequest(msg)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := llm.Generate(ctx, body)
+	if err != nil {
+		return "", err
+	}
+
+	parsed, err := llm.ParseResponse(resp)
+	if err != nil {
+		return "", err
+	}
+
+	summary := strings.TrimSpace(zstring.Bytes2String(runtime.ParseContent(parsed.Content)))
+	if summary == "" {
+		return "", fmt.Errorf("empty summary")
+	}
+	return summary, nil
+}
@@ -0,0 +1,114 @@
+package zllm
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sohaha/zlsgo"
+	"github.com/sohaha/zlsgo/zjson"
+	"github.com/sohaha/zlsgo/ztype"
+	"github.com/zlsgo/zllm/agent"
+	"github.com/zlsgo/zllm/message"
+)
+
+type windowRecordLLM struct {
+	agent.LLM
+	requests [][][]string
+	summary  int
+}
+
+func (m *windowRecordLLM) PrepareRequest(messages *message.Messages, options ...func(ztype.Map) ztype.Map) ([]byte, error) {
+	history := messages.History(true)
+	m.requests = append(m.requests, history)
+	if history[0][0] == message.RoleSystem && history[0][1] == DefaultSummaryPrompt {
+		return []byte(`{"summary":true}`), nil
+	}
+	return []byte(`{}`), nil
+}
+
+func (m *windowRecordLLM) Generate(ctx context.Context, data []byte) (*zjson.Res, error) {
+	if zjson.GetBytes(data, "summary").Bool() {
+		m.summary++
+		return zjson.Parse(`{"content":"用户问过两个问题"}`), nil
+	}
+	return zjson.Parse(`{"content":"{\"Assistant\":\"好的\"}"}`), nil
+}
+
+func (m *windowRecordLLM) ParseResponse(resp *zjson.Res) (*agent.Response, error) {
+	return &agent.Response{Content: []byte(resp.Get("content").String())}, nil
+}
+
+func TestContextWindow(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	newHistory := func() *message.Messages {
+		msg := message.NewMessages()
+		_ = msg.AppendUser("第一个问题")
+		_ = msg.AppendAssistant("第一个回答")
+		_ = msg.AppendUser("第二个问题")
+		_ = msg.AppendAssistant("第二个回答")
+		_ = msg.AppendUser("第三个问题")
+		return msg
+	}
+
+	tt.Run("KeepTurns", func(tt *zlsgo.TestUtil) {
+		llm := &windowRecordLLM{}
+		msg := newHistory()
+		ctx := WithContextWindow(context.Background(), ContextWindow{KeepTurns: 1})
+
+		resp, err := CompleteLLM(ctx, llm, msg)
+		tt.NoError(err, true)
+		tt.Equal(`{"Assistant":"好的"}`, resp)
+		tt.Equal(1, len(llm.requests))
+		tt.Equal(1, len(llm.requests[0]))
+		tt.Equal(true, strings.HasSuffix(llm.requests[0][0][1], "第三个问题"))
+		tt.Equal(6, msg.Len())
+	})
+
+	tt.Run("Summarize", func(tt *zlsgo.TestUtil) {
+		llm := &windowRecordLLM{}
+		msg := newHistory()
+		ctx := WithContextWindow(context.Background(), ContextWindow{KeepTurns: 1, Summarize: true})
+
+		_, err := CompleteLLM(ctx, llm, msg)
+		tt.NoError(err, true)
+		tt.Equal(1, llm.summary)
+		tt.Equal(2, len(llm.requests))
+		tt.Equal(true, strings.Contains(llm.requests[0][1][1], "第一个问题"))
+
+		last := llm.requests[1]
+		tt.Equal(2, len(last))
+		tt.Equal(message.RoleSystem, last[0][0])
+		tt.Equal(true, strings.HasSuffix(last[0][1], "用户问过两个问题"))
+	})
+
+	tt.Run("SummaryBudget", func(tt *zlsgo.TestUtil) {
+		llm := &windowRecordLLM{}
+		msg := newHistory()
+		counter := func(text string) int { return len([]rune(text)) }
+		maxTokens := msg.Tokens(counter) - 1
+		ctx := WithContextWindow(context.Background(), ContextWindow{MaxTokens: maxTokens, Summarize: true, Estimator: counter})
+
+		_, err := CompleteLLM(ctx, llm, msg)
+		tt.NoError(err, true)
+		tt.Equal(1, llm.summary)
+
+		last := llm.requests[len(llm.requests)-1]
+		tt.Equal(message.RoleSystem, last[0][0])
+		total := 0
+		for _, v := range last {
+			total += counter(v[1]) + 4
+		}
+		tt.EqualTrue(total <= maxTokens)
+	})
+
+	tt.Run("Disabled", func(tt *zlsgo.TestUtil) {
+		llm := &windowRecordLLM{}
+		msg := newHistory()
+
+		_, err := CompleteLLM(context.Background(), llm, msg)
+		tt.NoError(err, true)
+		tt.Equal(5, len(llm.requests[0]))
+	})
+}
@@ -40,6 +40,8 @@ type (
 	toolResultFormatterKey struct{} // 工具结果格式化器键
 	timeoutKey             struct{} // 超时时间键
 	toolIterKey            struct{} // 工具迭代次数键
+	contextWindowKey       struct{} // 上下文窗口键
+	callOptionsKey         struct{} // 单次调用参数键
 )
 
 // WithAllowTools 在上下文中设置是否允许使用工具
@@ -55,6 +57,19 @@ func isAllowTools(ctx context.Context) bool {
 	return true // 默认允许使用工具
 }
 
+// WithCallOptions 在上下文中设置单次调用参数（模型、温度、生成参数），覆盖提供商构造时的配置
+func WithCallOptions(ctx context.Context, co agent.CallOptions) context.Context {
+	return context.WithValue(ctx, callOptionsKey{}, co)
+}
+
+// applyCallOptions 对 LLM 应用上下文中的单次调用参数
+func applyCallOptions(ctx context.Context, llm agent.LLM) agent.LLM {
+	if co, ok := ctx.Value(callOptionsKey{}).(agent.CallOptions); ok {
+		return agent.ApplyCallOptions(llm, co)
+	}
+	return llm
+}
+
 // ToolRunner 定义工具执行接口，用于处理 LLM 工具调用
 type ToolRunner interface {
 	Run(ctx context.Context, name, args string) (string, error)
@@ -75,6 +90,7 @@ func getToolRunner(ctx context.Context) ToolRunner {
 
 // ToolResult 定义工具执行的返回结果
 type ToolResult struct {
+	ID     string // 工具调用 ID
 	Name   string // 工具名称
 	Args   string // 工具调用参数
 	Result string // 工具执行结果
@@ -143,15 +159,25 @@ func tryJSON(s string) any {
 }
 
 // CompleteLLM 向 LLM 发送提示并返回完整响应，支持工具执行、重试机制和超时处理
-func CompleteLLM[T promptMsg](ctx context.Context, llm agent.LLM, msg T, options ...func(ztype.Map) ztype.Map) (string, error) {
+func CompleteLLM[T promptMsg](ctx context.Context, llm agent.LLM, msg T, options ...func(ztype.Map) ztype.Map) (parse string, err error) {
 	timeout := getTimeout(ctx)
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	var (
-		messages *message.Messages
-		err      error
-	)
+	if runtime.Tracing() {
+		provider, model := agent.DescribeModel(llm)
+		var span runtime.Span
+		ctx, span = runtime.StartSpan(ctx, "zllm.CompleteLLM", runtime.SpanKindInternal,
+			runtime.Attr(runtime.AttrSystem, provider), runtime.Attr(runtime.AttrRequestModel, model))
+		defer func() {
+			if err == nil && runtime.CaptureContent() {
+				span.SetAttributes(runtime.Attr(runtime.AttrCompletion, parse))
+			}
+			runtime.EndSpan(span, err)
+		}()
+	}
+
+	var messages *message.Messages
 
 	switch v := any(msg).(type) {
 	case *message.Prompt:
@@ -165,15 +191,24 @@ func CompleteLLM[T promptMsg](ctx context.Context, llm agent.LLM, msg T, options
 		return "", fmt.Errorf("invalid prompt type: %T", msg)
 	}
 
-	content, err := llm.PrepareRequest(messages, options...)
+	if llm, err = agent.Resolve(ctx, llm, messages, options...); err != nil {
+		return "", err
+	}
+	llm = agent.Intercept(applyCallOptions(ctx, llm))
+
+	window := newContextWindowState(ctx, llm)
+	content, err := window.prepareRequest(ctx, llm, messages, options...)
 	if err != nil {
 		return "", err
 	}
+	if err = agent.CheckCapabilities(llm, content); err != nil {
+		return "", err
+	}
 
-	parse, _, err := processLLMInteraction(ctx, llm, messages, bytes.TrimSpace(content), options...)
+	parse, _, err = processLLMInteraction(ctx, llm, messages, window, bytes.TrimSpace(content), options...)
 	if err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
-			runtime.Log("LLM request timeout after", timeout)
+			runtime.Warn(ctx, "LLM request timeout", runtime.Attr("timeout", timeout.String()))
 			return "", fmt.Errorf("LLM request timeout after %v", timeout)
 		}
 		return "", err
@@ -195,8 +230,8 @@ func CompleteLLMJSON[T promptMsg](ctx context.Context, llm agent.LLM, msg T, opt
 }
 
 // processLLMInteraction 处理与 LLM 的交互，处理工具调用和重试
-func processLLMInteraction(ctx context.Context, llm agent.LLM, messages *message.Messages, body []byte, options ...func(ztype.Map) ztype.Map) (parse string, rawContext []byte, err error) {
-	return processLLMInteractionWithValidation(ctx, llm, messages, body, options...)
+func processLLMInteraction(ctx context.Context, llm agent.LLM, messages *message.Messages, window *contextWindowState, body []byte, options ...func(ztype.Map) ztype.Map) (parse string, rawContext []byte, err error) {
+	return processLLMInteractionWithValidation(ctx, llm, messages, window, body, options...)
 }
 
 // WithMaxToolIterations 在上下文中设置工具迭代的最大次数
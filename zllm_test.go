@@ -10,6 +10,7 @@ import (
 	"github.com/sohaha/zlsgo/zjson"
 	"github.com/sohaha/zlsgo/ztype"
 	"github.com/zlsgo/zllm/agent"
+	"github.com/zlsgo/zllm/agent/agenttest"
 	"github.com/zlsgo/zllm/message"
 	"github.com/zlsgo/zllm/runtime"
 )
@@ -434,3 +435,90 @@ func TestLLM(t *testing.T) {
 	tt.NoError(err, true)
 	tt.Log(resp)
 }
+
+func TestWithCallOptions(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	srv := agenttest.NewServer(agenttest.OpenAI).Push(agenttest.Text(`{"Assistant":"ok"}`)).Repeat()
+	defer srv.Close()
+
+	llm := agent.NewOpenAI(func(o *agent.OpenAIOptions) {
+		o.BaseURL = srv.URL
+		o.APIURL = "/chat/completions"
+		o.Model = "gpt-4.1"
+	})
+
+	ctx := WithCallOptions(context.Background(), agent.CallOptions{
+		Model:       "gpt-4.1-mini",
+		Temperature: agent.Ptr(0.0),
+		Sampling:    agent.Sampling{MaxTokens: 64},
+	})
+	_, err := CompleteLLM(ctx, llm, message.NewPrompt("hi"))
+	tt.NoError(err, true)
+
+	_, err = CompleteLLM(context.Background(), llm, message.NewPrompt("hi"))
+	tt.NoError(err, true)
+
+	cheap := zjson.ParseBytes(srv.Requests()[0].Body)
+	tt.Equal("gpt-4.1-mini", cheap.Get("model").String())
+	tt.Equal(0.0, cheap.Get("temperature").Float())
+	tt.Equal(64, cheap.Get("max_tokens").Int())
+
+	normal := zjson.ParseBytes(srv.Requests()[1].Body)
+	tt.Equal("gpt-4.1", normal.Get("model").String())
+	tt.Equal(0.5, normal.Get("temperature").Float())
+	tt.EqualTrue(!normal.Get("max_tokens").Exists())
+
+	gemini := agent.ApplyCallOptions(agent.NewGemini(func(o *agent.GeminiOptions) {
+		o.BaseURL = srv.URL
+	}), agent.CallOptions{Model: "gemini-2.5-pro"})
+	tt.Equal("gemini-2.5-pro", gemini.(*agent.GeminiProvider).GetConfig().Model)
+	_, _ = gemini.Generate(context.Background(), []byte("hi"))
+	tt.Equal("/v1beta/models/gemini-2.5-pro:generateContent", srv.Requests()[2].Path)
+}
+
+func TestCompleteLLMCapabilities(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	srv := agenttest.NewServer(agenttest.Ollama, agenttest.Text(`{"Assistant":"ok"}`)).Repeat()
+	defer srv.Close()
+
+	llm := agent.NewOllama(func(o *agent.OllamaOptions) {
+		o.BaseURL = srv.URL
+		o.Model = "deepseek-r1:7b"
+	})
+	tools := agent.WithToolCallHint([]map[string]any{{"type": "function", "function": map[string]any{"name": "weather"}}})
+
+	_, err := CompleteLLM(context.Background(), llm, message.NewPrompt("hi"), tools)
+	tt.EqualTrue(err != nil)
+	tt.Equal(0, len(srv.Requests()))
+
+	ctx := WithCallOptions(context.Background(), agent.CallOptions{Model: "qwen2.5:7b"})
+	_, err = CompleteLLM(ctx, llm, message.NewPrompt("hi"), tools)
+	tt.NoError(err, true)
+	tt.Equal(1, len(srv.Requests()))
+}
+
+func TestCompleteLLMInterceptor(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	var calls []*agent.Call
+	unregister := agent.RegisterInterceptor(agent.Interceptor{
+		AfterResponse: func(_ context.Context, call *agent.Call, _ *zjson.Res) { calls = append(calls, call) },
+	})
+	defer unregister()
+
+	srv := agenttest.NewServer(agenttest.Ollama, agenttest.Text(`{"Assistant":"ok"}`))
+	defer srv.Close()
+
+	llm := agent.NewOllama(func(o *agent.OllamaOptions) {
+		o.BaseURL = srv.URL
+		o.Model = "qwen3:8b"
+	})
+	ctx := WithCallOptions(context.Background(), agent.CallOptions{Model: "qwen2.5:7b"})
+	_, err := CompleteLLM(ctx, llm, message.NewPrompt("hi"))
+	tt.NoError(err, true)
+	tt.Equal(1, len(calls))
+	tt.Equal("ollama", calls[0].Provider)
+	tt.Equal("qwen2.5:7b", calls[0].Model)
+}